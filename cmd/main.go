@@ -9,8 +9,10 @@ import (
 
 	"example/AI/internal/handlers"
 	"example/AI/internal/middleware"
+	"example/AI/internal/models"
 	"example/AI/internal/services"
 	"example/AI/internal/store"
+	"example/AI/internal/utils"
 )
 
 const dbName = "AI_Expense"
@@ -63,7 +65,7 @@ No natural text outside JSON. No markdown. No explanations.
 
 Your job:
 - Interpret ANY natural-language request about purchases.
-- Classify the type of request (add / query / analyze).
+- Classify the type of request (add / add_income / add_refund / add_installment / pay_installment / transfer / query / analyze / set_budget / add_recurring / export).
 - Extract ALL relevant parameters, even if user didn’t explicitly mention them.
 - Support arbitrary filtering, comparison, user-level analysis, multi-user admin analysis, and any custom insight.
 - All fields must be fully filled. No null. No missing keys. No empty strings except when logically needed.
@@ -71,7 +73,7 @@ Your job:
 MANDATORY JSON SCHEMA:
 
 {
-  "action": "add | query | analyze",
+  "action": "add | add_income | add_refund | add_installment | pay_installment | transfer | query | analyze | set_budget | add_recurring | export",
 
   "request_context": {
     "user_role": "user | admin",
@@ -89,7 +91,11 @@ MANDATORY JSON SCHEMA:
     "emotional_tone": "happy | stressed | neutral | excited | sad | angry",
     "reason_guess": "",
     "confidence": 0,
-    "purchase_time": "YYYY-MM-DD"
+    "purchase_time": "YYYY-MM-DD",
+    "account": "",         // account/wallet paid from ("کارت ملت", "نقدی"), "" if not mentioned
+    "items": [             // receipt lines, [] for a single-item purchase
+      {"name": "", "quantity": 0, "unit": "", "unit_price": 0, "amount": 0, "category": "", "subcategory": ""}
+    ]
   },
 
   "filters": {
@@ -98,12 +104,15 @@ MANDATORY JSON SCHEMA:
     "categories": [],
     "min_amount": 0,
     "max_amount": 0,
+    "currency": "",
+    "include_unconfirmed": false,
+    "basis": "accrual | cash",   // installment purchases: full price (accrual) or the paid installments (cash)
     "keywords": []        // for text-based or fuzzy filtering
   },
 
   "analysis": {
     "intent": "",
-    "dimensions": [],      // e.g. ["time", "category", "user", "amount"]
+    "dimensions": [],      // e.g. ["time", "category", "vendor", "user", "amount", "income"]
     "metrics": [],         // e.g. ["sum", "max", "min", "average"]
     "compare": {
       "targets": [],       // list of things being compared (dates, users, categories, etc.)
@@ -114,6 +123,77 @@ MANDATORY JSON SCHEMA:
     "details": ""
   },
 
+  "budget": {
+    "category": "",        // empty = overall budget
+    "amount": 0,
+    "currency": "",
+    "period": "monthly | weekly | jalali_monthly",
+    "rollover": "none | surplus | full"
+  },
+
+  "schedule": {
+    "title": "",
+    "amount": 0,
+    "currency": "",
+    "category": "",
+    "subcategory": "",
+    "vendor": "",
+    "rrule": "",           // e.g. "FREQ=JMONTHLY;BYMONTHDAY=1"
+    "start_date": "YYYY-MM-DD",
+    "end_date": ""         // "" = no end
+  },
+
+  "income": {
+    "title": "",
+    "amount": 0,
+    "currency": "",
+    "kind": "salary | transfer | refund | gift | interest | other",
+    "source": "",          // employer / payer, "" if unknown
+    "received_at": "YYYY-MM-DD",
+    "account": ""          // account it was paid into, "" if not mentioned
+  },
+
+  "refund": {
+    "purchase": "",        // what was returned / the original purchase ("کفش", "سفارش دیجی‌کالا")
+    "amount": 0,           // 0 = the whole purchase
+    "currency": "",
+    "purchase_date": "",   // YYYY-MM-DD of the original purchase if mentioned, else ""
+    "refunded_at": "YYYY-MM-DD",
+    "reason": ""
+  },
+
+  "installment": {
+    "title": "",
+    "total": 0,            // full price
+    "down_payment": 0,
+    "currency": "",
+    "count": 0,            // number of installments after the down payment
+    "rrule": "",           // schedule of the installments, e.g. "FREQ=JMONTHLY"
+    "first_due": "",       // YYYY-MM-DD of the first installment, "" = one month after the purchase
+    "purchase_time": "YYYY-MM-DD",
+    "category": "",
+    "subcategory": "",
+    "vendor": "",
+    "account": "",
+    "auto_pay": false,     // paid automatically (direct debit, cheques)
+    "paid_at": ""          // pay_installment only: YYYY-MM-DD, "" = today
+  },
+
+  "transfer": {
+    "from_account": "",
+    "to_account": "",
+    "amount": 0,
+    "currency": "",
+    "fee": 0,
+    "date": "YYYY-MM-DD",
+    "note": ""
+  },
+
+  "export": {
+    "format": "csv | xlsx | ndjson | pdf | beancount | hledger",
+    "period": "monthly | jalali_monthly | weekly"   // only for pdf reports
+  },
+
   "assistant_reply": ""
 }
 
@@ -122,8 +202,16 @@ RULES:
 
 1) ACTION
    - Purchase description → "add".
+   - Moving money between the user's own accounts ("۵ میلیون از کارت ملت به کیف پول ریختم") → "transfer" (NOT add_income).
+   - Money back for something the user bought ("پول کفش رو پس دادن", "سفارشم رو کنسل کردم پولش برگشت") → "add_refund" (NOT add_income).
+   - Money received (salary, transfer from someone, gift, interest: "حقوقم ۴۰ میلیون واریز شد") → "add_income".
    - Listing/filtering/search → "query".
    - Any insight, comparison, reasoning, or evaluation → "analyze".
+   - Setting/changing a spending limit ("بودجه‌ی خوراک ماهی ۵ میلیون") → "set_budget".
+   - Buying something in installments ("گوشی رو ۱۲ قسطه خریدم، ۱۰ میلیون پیش") → "add_installment" (NOT add or add_recurring).
+   - Paying an installment of an existing plan ("قسط گوشی رو دادم") → "pay_installment".
+   - A purchase that repeats on a schedule ("هر ماه اول ماه اجاره ۱۵ میلیون") → "add_recurring".
+   - Asking for a file/report of purchases ("خریدهای ماه قبل رو اکسل بده", "send me last month as Excel") → "export".
 
 2) USER ROLE & TARGET USERS
    - Always fill user_role from input.
@@ -132,16 +220,24 @@ RULES:
 
 3) ADD MODE
    - Infer title, category, vendor.
+   - category/subcategory MUST be keys from the ALLOWED CATEGORIES list (when given).
    - Convert Persian numbers to digits.
    - Infer currency (default IRR).
+   - amount is a plain number in the main unit of the currency (no thousands separators, no exponent).
    - necessity/emotional_tone MUST be chosen.
    - reason_guess MUST be meaningful.
    - confidence MUST be 0–1.
    - purchase_time: convert any relative or fuzzy dates to exact YYYY-MM-DD; default = today.
+   - items: when the message or a pasted receipt lists several things (a supermarket trip), one entry per line:
+     name, quantity (default 1), unit ("kg", "l", "pcs", ...), unit_price and/or amount (line total), and the line's own category/subcategory.
+     amount of the purchase = the receipt total (after discounts/tax); title = the store or a short summary. Otherwise items = [].
+   - account: the card/wallet/cash the user paid with ("با کارت ملت" → the matching USER ACCOUNTS name); "" if not mentioned.
 
 4) QUERY MODE
    - Extract any date, category, amount, keyword filters.
    - keywords supports arbitrary search inputs.
+   - include_unconfirmed = true only if the user asks to include unreviewed/guessed purchases.
+   - basis = "cash" when the user means money actually paid (installments as they are paid), default "accrual".
    - If something not provided → fill with default ("" or 0 or []).
 
 5) ANALYZE MODE (VERY IMPORTANT)
@@ -162,19 +258,60 @@ RULES:
      - min purchase
      - user-to-user comparison
      - time-series trend
-     - overspending pattern
+     - overspending pattern (use intent "overspending-detection" / "anomaly-detection")
+     - end-of-period projection ("at this rate, how much will I spend this month?" → intent "spending-forecast", aggregation_level = the period: "monthly", "jalali_monthly" or "weekly")
      - necessity distribution
-     - emotional spending analysis
+     - income vs spending, net cash flow, savings rate ("چقدر پس‌انداز کردم؟" → intent "cash-flow" or "savings-rate", dimensions include "income")
+     - remaining installment debt ("چقدر قسط مونده؟" → intent "installment-debt")
+     - personal inflation / price changes of what I buy ("نون چقدر گرون شده؟" → intent "price-tracking" with the item in filters.keywords; "تورم شخصی من چقدره؟" → intent "personal-inflation")
+     - emotional spending analysis / impulse buying (intent "emotional-spending" or "impulse-analysis", dimensions include "emotion" and "necessity")
      - category ranking
      - custom insight based on user question
 
    Model MUST choose the best fitting pattern, not rely on fixed examples.
 
-6) ASSISTANT_REPLY
+6) SET_BUDGET MODE
+   - category: key from ALLOWED CATEGORIES, or "" for an overall budget.
+   - amount: the limit per period, plain number in the main unit of the currency.
+   - period: "jalali_monthly" when the user speaks of Persian months (ماه شمسی، فروردین، ...), default "monthly".
+   - rollover: "surplus" if unused money carries over, "full" if overspending also carries over, default "none".
+
+7) ADD_RECURRING MODE
+   - Fill "schedule" like ADD MODE fields, plus an RRULE-like "rrule":
+     FREQ=DAILY|WEEKLY|MONTHLY|JMONTHLY|YEARLY; INTERVAL=n; BYMONTHDAY=1,15,-1 (-1 = last day); BYDAY=SA,SU,MO,TU,WE,TH,FR; COUNT=n; UNTIL=YYYYMMDD
+   - Use JMONTHLY (Jalali months, BYMONTHDAY is a Jalali day) when the user writes in Persian or names Persian months; MONTHLY only for Gregorian months.
+   - start_date: first day the schedule applies (default today); end_date only if the user gives an end.
+
+8) ADD_INCOME / TRANSFER MODE
+   - Fill "income": title, amount (plain number in the main unit of the currency), currency (default IRR).
+   - kind: "salary" for wages/حقوق, "transfer" for money sent by someone, "refund" only for returned money not tied to a purchase, "gift", "interest" for bank profit/سود, else "other".
+   - source: who paid (employer, person, bank), "" if not mentioned.
+   - received_at: exact YYYY-MM-DD, default today.
+   - account: like ADD MODE, the account the money went into.
+   - transfer: fill "transfer" with both account names from USER ACCOUNTS, amount, fee (0 if none) and date (default today).
+
+9) ADD_REFUND MODE
+   - purchase: the item or vendor of the original purchase, as the user named it.
+   - amount: the refunded amount (plain number in the main unit of the currency); 0 when the whole purchase was refunded.
+   - refunded_at: exact YYYY-MM-DD, default today. purchase_date only if the user says when they bought it.
+   - reason: short reason (returned, cancelled, defective, ...), "" if unknown.
+
+10) ADD_INSTALLMENT / PAY_INSTALLMENT MODE
+   - Fill "installment": title, total (the full price), down_payment (0 if none), count, currency like ADD MODE.
+   - rrule like ADD_RECURRING MODE without COUNT; default "FREQ=JMONTHLY" in Persian, "FREQ=MONTHLY" otherwise.
+   - If only the installment amount is given, total = down_payment + count × installment.
+   - pay_installment: only title (the purchase the installment belongs to) and paid_at.
+
+11) EXPORT MODE
+   - Fill "filters" like QUERY MODE (date range, categories, ...).
+   - format: "xlsx" for Excel, "pdf" for a formatted/printable report, "ndjson" for JSON, "beancount"/"hledger" for accounting journals, default "csv".
+   - period (pdf only): the report period; "jalali_monthly" for Persian months, default "monthly". filters.from_date = a day inside that period.
+
+12) ASSISTANT_REPLY
    - Short friendly Persian response (1–2 sentences).
    - No emoji. No markdown.
 
-7) Strictness
+13) Strictness
    - NO nulls.
    - NO missing fields.
    - NO text outside JSON.
@@ -185,11 +322,218 @@ RULES:
 	aiService := services.NewAIServiceFromEnv(prompt) // systemPrompt = همان سیستم پرامپت
 	purchaseRepo := store.NewPurchaseRepo(store.DB)
 	purchaseSvc := services.NewPurchaseService(purchaseRepo)
+	purchaseSvc.ReviewThreshold = utils.EnvFloat("REVIEW_CONFIDENCE_THRESHOLD", services.DefaultReviewThreshold)
+
+	taxonomySvc := services.NewTaxonomyService(store.DB)
+	purchaseSvc.Taxonomy = taxonomySvc
+	aiService.AddPromptContext(taxonomySvc.PromptContext) // لیست دسته‌های مجاز داخل پرامپت
+
+	vendorSvc := services.NewVendorService(store.DB)
+	purchaseSvc.Vendors = vendorSvc
+	correctionSvc := services.NewCorrectionService(store.DB)
+	purchaseSvc.Corrections = correctionSvc
+	aiService.AddPromptContext(correctionSvc.PromptContext) // few-shot از اصلاحات قبلی کاربر
+
+	classifierSvc := services.NewClassifierService(store.DB)
+	purchaseSvc.Classifier = classifierSvc
+	go func() {
+		if err := classifierSvc.TrainGlobal(); err != nil {
+			log.Printf("classifier training error: %v", err)
+		}
+	}()
+
+	recurringSvc := services.NewRecurringService(store.DB)
+	purchaseSvc.Recurring = recurringSvc
+	duplicateSvc := services.NewDuplicateService(store.DB, utils.EnvDuration("DUPLICATE_WINDOW", services.DefaultDuplicateWindow))
+	purchaseSvc.Duplicates = duplicateSvc
+
+	go func() {
+		// link purchases saved before the vendors table existed
+		if n, err := vendorSvc.BackfillPurchases(); err != nil {
+			log.Printf("vendor backfill error: %v", err)
+		} else if n > 0 {
+			log.Printf("vendor backfill: %d purchases linked", n)
+		}
+	}()
 
 	aiHandler := handlers.NewAiHandler(aiService, purchaseSvc, store.DB) // یا مستقیم db
 
+	// incomplete purchases wait here for the follow-up answer
+	draftSvc := services.NewDraftService(store.DB, utils.EnvDuration("DRAFT_TTL", services.DefaultDraftTTL))
+	aiHandler.Drafts = draftSvc
+	aiService.AddPromptContext(draftSvc.PromptContext)
+
+	budgetSvc := services.NewBudgetService(store.DB, purchaseSvc)
+	purchaseSvc.Budgets = budgetSvc
+	aiHandler.Budgets = budgetSvc
+
+	// scheduled purchases: catches up missed runs at startup, then runs periodically
+	scheduleSvc := services.NewScheduleService(store.DB, purchaseSvc)
+	aiHandler.Schedules = scheduleSvc
+
+	anomalySvc := services.NewAnomalyService(store.DB, taxonomySvc)
+	aiHandler.Anomalies = anomalySvc
+	forecastSvc := services.NewForecastService(store.DB, recurringSvc, scheduleSvc)
+	aiHandler.Forecasts = forecastSvc
+	emotionSvc := services.NewEmotionService(purchaseSvc)
+	aiHandler.Emotions = emotionSvc
+	incomeSvc := services.NewIncomeService(store.DB, purchaseSvc)
+	aiHandler.Incomes = incomeSvc
+	accountSvc := services.NewAccountService(store.DB)
+	purchaseSvc.Accounts = accountSvc
+	incomeSvc.Accounts = accountSvc
+	aiHandler.Accounts = accountSvc
+	// started once purchases get their accounts
+	scheduleSvc.Start(utils.EnvDuration("SCHEDULER_INTERVAL", services.DefaultSchedulerInterval))
+	refundSvc := services.NewRefundService(store.DB, purchaseSvc)
+	aiHandler.Refunds = refundSvc
+	installmentSvc := services.NewInstallmentService(store.DB, purchaseSvc)
+	aiHandler.Installments = installmentSvc
+	forecastSvc.Installments = installmentSvc
+	installmentSvc.Start(utils.EnvDuration("SCHEDULER_INTERVAL", services.DefaultSchedulerInterval))
+	priceSvc := services.NewPriceService(store.DB, purchaseSvc)
+	aiHandler.Prices = priceSvc
+	aiService.AddPromptContext(accountSvc.PromptContext) // لیست حساب‌ها و کیف پول‌های کاربر
+
+	importSvc := services.NewImportService(store.DB, purchaseSvc, aiService)
+	ledgerSvc := services.NewLedgerService(store.DB, purchaseSvc)
+	statementSvc := services.NewStatementService(store.DB, purchaseSvc, utils.EnvDuration("STATEMENT_MATCH_WINDOW", services.DefaultStatementWindow))
+	bankSMSSvc := services.NewBankSMSService(store.DB, purchaseSvc, aiService)
+	bankSMSSvc.Incomes = incomeSvc
+
+	// PDF reports need a TTF with Persian glyphs (e.g. Vazirmatn), otherwise they are in English
+	exportSvc := services.NewExportService(purchaseSvc, os.Getenv("REPORT_FONT_PATH"))
+	aiHandler.Exports = exportSvc
+
 	r.POST("/ai/message", middleware.AuthRequired(), aiHandler.HandleMessage())
 
+	categoryHandler := handlers.NewCategoryHandler(taxonomySvc)
+	api.GET("/categories", categoryHandler.List())
+	api.POST("/categories", categoryHandler.Create())
+	api.POST("/categories/:id/synonyms", categoryHandler.AddSynonym())
+	api.POST("/categories/merge", categoryHandler.Merge())
+	api.POST("/categories/recategorize", categoryHandler.Recategorize())
+
+	vendorHandler := handlers.NewVendorHandler(vendorSvc, purchaseSvc)
+	api.GET("/vendors", vendorHandler.List())
+	api.GET("/vendors/report", vendorHandler.Report())
+	api.PUT("/vendors/:id", vendorHandler.Update())
+	api.POST("/vendors/:id/aliases", vendorHandler.AddAlias())
+	api.POST("/vendors/merge", vendorHandler.Merge())
+
+	purchaseHandler := handlers.NewPurchaseHandler(purchaseSvc)
+	purchaseHandler.Refunds = refundSvc
+	api.GET("/purchases/:id", purchaseHandler.Details())
+	api.PATCH("/purchases/:id", purchaseHandler.Correct())
+	api.PUT("/purchases/:id/items", purchaseHandler.SetItems())
+
+	refundHandler := handlers.NewRefundHandler(refundSvc)
+	api.POST("/purchases/:id/refunds", refundHandler.Create())
+	api.GET("/refunds", refundHandler.List())
+	api.DELETE("/refunds/:id", refundHandler.Delete())
+
+	duplicateHandler := handlers.NewDuplicateHandler(duplicateSvc)
+	api.GET("/duplicates", duplicateHandler.List())
+	api.POST("/duplicates/scan", duplicateHandler.Scan())
+	api.POST("/purchases/:id/keep", duplicateHandler.Keep())
+	api.POST("/purchases/:id/merge", duplicateHandler.Merge())
+
+	incomeHandler := handlers.NewIncomeHandler(incomeSvc)
+	api.GET("/incomes", incomeHandler.List())
+	api.POST("/incomes", incomeHandler.Create())
+	api.POST("/incomes/:id/confirm", incomeHandler.SetStatus(models.StatusConfirmed))
+	api.POST("/incomes/:id/reject", incomeHandler.SetStatus(models.StatusRejected))
+	api.DELETE("/incomes/:id", incomeHandler.Delete())
+
+	accountHandler := handlers.NewAccountHandler(accountSvc)
+	api.GET("/accounts", accountHandler.List())
+	api.POST("/accounts", accountHandler.Create())
+	api.GET("/accounts/balances", accountHandler.Balances())
+	api.PUT("/accounts/:id", accountHandler.Update())
+	api.DELETE("/accounts/:id", accountHandler.Archive())
+	api.GET("/accounts/:id/ledger", accountHandler.Ledger())
+	api.POST("/accounts/:id/reconcile", accountHandler.Reconcile())
+	api.GET("/accounts/:id/reconciliations", accountHandler.Reconciliations())
+	api.GET("/transfers", accountHandler.Transfers())
+	api.POST("/transfers", accountHandler.CreateTransfer())
+	api.DELETE("/transfers/:id", accountHandler.DeleteTransfer())
+
+	installmentHandler := handlers.NewInstallmentHandler(installmentSvc)
+	api.GET("/installments", installmentHandler.List())
+	api.POST("/installments", installmentHandler.Create())
+	api.GET("/installments/debt", installmentHandler.Debt())
+	api.GET("/installments/:id", installmentHandler.Get())
+	api.POST("/installments/:id/pay", installmentHandler.Pay())
+
+	ruleHandler := handlers.NewRuleHandler(correctionSvc, purchaseSvc)
+	api.GET("/rules", ruleHandler.List())
+	api.POST("/rules", ruleHandler.Create())
+	api.DELETE("/rules/:id", ruleHandler.Delete())
+
+	reviewHandler := handlers.NewReviewHandler(services.NewReviewService(purchaseSvc))
+	api.GET("/review", reviewHandler.Queue())
+	api.POST("/review", reviewHandler.Apply())
+
+	budgetHandler := handlers.NewBudgetHandler(budgetSvc)
+	api.GET("/budgets", budgetHandler.List())
+	api.GET("/budgets/status", budgetHandler.Status())
+	api.POST("/budgets", budgetHandler.Create())
+	api.PUT("/budgets/:id", budgetHandler.Update())
+	api.DELETE("/budgets/:id", budgetHandler.Delete())
+
+	recurringHandler := handlers.NewRecurringHandler(recurringSvc)
+	api.GET("/recurring", recurringHandler.List())
+	api.GET("/recurring/upcoming", recurringHandler.Upcoming())
+	api.POST("/recurring/detect", recurringHandler.Detect())
+	api.POST("/recurring/:id/confirm", recurringHandler.SetStatus(true))
+	api.POST("/recurring/:id/dismiss", recurringHandler.SetStatus(false))
+	api.GET("/subscriptions", recurringHandler.Subscriptions())
+
+	scheduleHandler := handlers.NewScheduleHandler(scheduleSvc)
+	api.GET("/scheduled", scheduleHandler.List())
+	api.POST("/scheduled", scheduleHandler.Create())
+	api.GET("/scheduled/:id/occurrences", scheduleHandler.Occurrences())
+	api.POST("/scheduled/:id/pause", scheduleHandler.Pause())
+	api.POST("/scheduled/:id/resume", scheduleHandler.Resume())
+	api.POST("/scheduled/:id/skip", scheduleHandler.Skip())
+	api.POST("/scheduled/:id/end", scheduleHandler.End())
+
+	insightsHandler := handlers.NewInsightsHandler(anomalySvc, forecastSvc, emotionSvc)
+	insightsHandler.Incomes = incomeSvc
+	insightsHandler.Prices = priceSvc
+	api.GET("/insights/anomalies", insightsHandler.AnomalyList())
+	api.POST("/insights/anomalies/:id/dismiss", insightsHandler.DismissAnomaly())
+	api.GET("/insights/forecast", insightsHandler.Forecast())
+	api.GET("/insights/emotions", insightsHandler.EmotionReport())
+	api.GET("/insights/cash-flow", insightsHandler.CashFlow())
+	api.GET("/insights/prices", insightsHandler.PriceList())
+	api.GET("/insights/inflation", insightsHandler.Inflation())
+
+	importHandler := handlers.NewImportHandler(importSvc)
+	api.POST("/imports", importHandler.Upload())
+	api.GET("/imports/:id", importHandler.Preview())
+	api.PUT("/imports/:id/mapping", importHandler.Remap())
+	api.POST("/imports/:id/commit", importHandler.Commit())
+	api.POST("/imports/ledger", handlers.NewLedgerHandler(ledgerSvc).Import())
+
+	statementHandler := handlers.NewStatementHandler(statementSvc)
+	api.POST("/imports/:id/reconcile", statementHandler.FromImport())
+	api.GET("/imports/:id/reconciliation", statementHandler.Report())
+	api.POST("/imports/:id/reconciliation/auto-match", statementHandler.AutoMatch())
+	api.POST("/statement-lines/:id/match", statementHandler.Match())
+	api.DELETE("/statement-lines/:id/match", statementHandler.Unmatch())
+	api.POST("/statement-lines/:id/ignore", statementHandler.Ignore())
+	api.POST("/statement-lines/:id/purchase", statementHandler.CreatePurchase())
+
+	bankSMSHandler := handlers.NewBankSMSHandler(bankSMSSvc)
+	api.POST("/bank-sms", bankSMSHandler.Import())
+	api.GET("/bank-sms", bankSMSHandler.List())
+	api.GET("/bank-sms/balances", bankSMSHandler.Balances())
+	api.GET("/bank-sms/formats", bankSMSHandler.Formats())
+
+	exportHandler := handlers.NewExportHandler(exportSvc)
+	api.GET("/exports", exportHandler.Download())
+
 	log.Println("server running on :8080")
 	if err := r.Run(":8080"); err != nil {
 		log.Fatalf("server error: %v", err)
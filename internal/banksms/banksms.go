@@ -0,0 +1,346 @@
+// Package banksms parses the transaction SMS of Iranian banks, e.g.
+//
+//	بانک ملت
+//	برداشت:1,250,000
+//	حساب:12345678
+//	مانده:5,430,000
+//	1403/01/12-14:25
+//
+// Formats are kept in a registry; Parse tries every registered format that
+// recognizes the text. Amounts are in rials unless the SMS says تومان.
+package banksms
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+)
+
+const (
+	Debit  = "debit"  // برداشت / خرید
+	Credit = "credit" // واریز
+)
+
+var ErrUnrecognized = errors.New("unrecognized bank sms")
+
+type Transaction struct {
+	Bank        string        `json:"bank"`
+	Kind        string        `json:"kind"` // debit | credit
+	Amount      models.Money  `json:"amount"`
+	Balance     *models.Money `json:"balance,omitempty"` // مانده after the transaction
+	Account     string        `json:"account,omitempty"` // as printed (often masked)
+	Time        *time.Time    `json:"time,omitempty"`    // wall clock of the sms, stored as UTC like other purchase dates
+	Description string        `json:"description,omitempty"`
+	Raw         string        `json:"raw"`
+}
+
+// Format parses the SMS of one bank.
+type Format interface {
+	Name() string
+	// Match reports whether the (normalized) text looks like this bank's SMS.
+	Match(text string) bool
+	Parse(text string) (*Transaction, error)
+}
+
+var (
+	mu      sync.RWMutex
+	formats []Format
+)
+
+// Register adds a format; later registrations are tried first, so a custom
+// format can override a built-in one for the same bank.
+func Register(f Format) {
+	mu.Lock()
+	defer mu.Unlock()
+	formats = append([]Format{f}, formats...)
+}
+
+// Formats lists the registered format names.
+func Formats() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, len(formats))
+	for i, f := range formats {
+		names[i] = f.Name()
+	}
+	return names
+}
+
+// Detect returns the bank a text mentions ("کارت ملت" -> mellat), "" if none.
+func Detect(text string) string {
+	norm := normalize(text)
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, f := range formats {
+		if f.Match(norm) {
+			return f.Name()
+		}
+	}
+	return ""
+}
+
+// Parse finds the format of an SMS and parses it. Unknown banks fall back to
+// the generic keyword parser.
+func Parse(text string) (*Transaction, error) {
+	norm := normalize(text)
+	if norm == "" {
+		return nil, errors.New("empty sms")
+	}
+	mu.RLock()
+	list := append([]Format(nil), formats...)
+	mu.RUnlock()
+
+	for _, f := range list {
+		if !f.Match(norm) {
+			continue
+		}
+		if tx, err := f.Parse(norm); err == nil {
+			tx.Raw = strings.TrimSpace(text)
+			return tx, nil
+		}
+	}
+	tx, err := parseGeneric(norm, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	tx.Raw = strings.TrimSpace(text)
+	return tx, nil
+}
+
+// Split cuts a pasted block of several SMS apart (one per blank-line separated chunk).
+func Split(text string) []string {
+	var out []string
+	for _, chunk := range regexp.MustCompile(`\r?\n\s*\r?\n`).Split(text, -1) {
+		if c := strings.TrimSpace(chunk); c != "" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// normalize unifies digits, Arabic letters and separators but keeps the lines.
+func normalize(s string) string {
+	s = utils.NormalizeDigits(s)
+	s = strings.NewReplacer("ي", "ی", "ك", "ک", "‌", " ", "‏", "", "‎", "", "：", ":", "\r", "").Replace(s)
+	lines := strings.Split(s, "\n")
+	out := lines[:0]
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// KeywordFormat recognizes a bank by its name in the text and parses the body
+// with the generic label-based rules (برداشت / واریز / مبلغ / مانده / حساب).
+type KeywordFormat struct {
+	Bank     string
+	Keywords []string
+}
+
+func (f KeywordFormat) Name() string { return f.Bank }
+
+func (f KeywordFormat) Match(text string) bool {
+	text = strings.ToLower(text)
+	for _, k := range f.Keywords {
+		if strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f KeywordFormat) Parse(text string) (*Transaction, error) {
+	return parseGeneric(text, f.Bank, f.Keywords)
+}
+
+var (
+	amountPart = `([+-]?\d{1,3}(?:[,.]\d{3})+|[+-]?\d+)`
+	reAmount   = regexp.MustCompile(`(مبلغ|برداشت|واریز|خرید|کسر|بستانکار|بدهکار)[\s:]*(?:مبلغ)?[\s:]*` + amountPart + `\s*(ریال|تومان)?`)
+	reSigned   = regexp.MustCompile(`(?m)^` + `([+-])\s*(\d{1,3}(?:[,.]\d{3})+|\d+)\s*(ریال|تومان)?\s*$`)
+	reBalance  = regexp.MustCompile(`(مانده|موجودی|مانده حساب)[^\d\n+-]{0,15}` + amountPart)
+	reAccount  = regexp.MustCompile(`(?:حساب|کارت|سپرده|از|به)\s*:?\s*([\d*]{2,}(?:[.\-/*][\d*]+)*)`)
+	reAcctOnly = regexp.MustCompile(`(?m)^(\d{2,}(?:[.\-][\d*]+){2,}|[\d*]{8,})$`)
+	reDate     = regexp.MustCompile(`(\d{2,4})/(\d{1,2})/(\d{1,2})`)
+	reClock    = regexp.MustCompile(`(\d{1,2}):(\d{2})(?::\d{2})?`)
+
+	debitWords  = []string{"برداشت", "خرید", "کسر", "بدهکار", "انتقال از", "پرداخت"}
+	creditWords = []string{"واریز", "بستانکار", "انتقال به", "سود"}
+)
+
+func parseNumber(s string) (int64, bool) {
+	s = strings.TrimLeft(s, "+-")
+	s = strings.NewReplacer(",", "", ".", "").Replace(s)
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil && n > 0
+}
+
+// parseGeneric reads the labelled fields common to (almost) every Iranian bank SMS.
+func parseGeneric(text, bank string, keywords []string) (*Transaction, error) {
+	tx := &Transaction{Bank: bank}
+	currency := "IRR"
+	var amount int64
+	sign := ""
+
+	// "مبلغ: ..." wins over "برداشت از حساب ..."-style lines
+	matches := reAmount.FindAllStringSubmatch(text, -1)
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i][1] == "مبلغ" && matches[j][1] != "مبلغ" })
+	for _, m := range matches {
+		n, ok := parseNumber(m[2])
+		if ok {
+			amount = n
+			if strings.HasPrefix(m[2], "-") || strings.HasPrefix(m[2], "+") {
+				sign = m[2][:1]
+			}
+			if m[3] == "تومان" {
+				currency = "IRT"
+			}
+			switch m[1] {
+			case "واریز", "بستانکار":
+				tx.Kind = Credit
+			case "برداشت", "خرید", "کسر", "بدهکار":
+				tx.Kind = Debit
+			}
+			break
+		}
+	}
+	if amount == 0 {
+		// Pasargad, Saman, ...: the amount is a signed line of its own
+		if m := reSigned.FindStringSubmatch(text); m != nil {
+			if n, ok := parseNumber(m[2]); ok {
+				amount, sign = n, m[1]
+				if m[3] == "تومان" {
+					currency = "IRT"
+				}
+			}
+		}
+	}
+	if amount == 0 {
+		return nil, ErrUnrecognized
+	}
+
+	if tx.Kind == "" {
+		switch {
+		case sign == "-":
+			tx.Kind = Debit
+		case sign == "+":
+			tx.Kind = Credit
+		case containsAny(text, creditWords):
+			tx.Kind = Credit
+		case containsAny(text, debitWords):
+			tx.Kind = Debit
+		default:
+			return nil, errors.New("cannot tell debit from credit")
+		}
+	}
+	tx.Amount = models.NewMoney(amount, currency)
+
+	if m := reBalance.FindStringSubmatch(text); m != nil {
+		if n, ok := parseNumber(m[2]); ok || strings.Trim(m[2], "+-0,.") == "" {
+			if strings.HasPrefix(m[2], "-") {
+				n = -n
+			}
+			b := models.NewMoney(n, currency)
+			tx.Balance = &b
+		}
+	}
+
+	if m := reAccount.FindStringSubmatch(text); m != nil {
+		tx.Account = m[1]
+	} else if m := reAcctOnly.FindStringSubmatch(text); m != nil {
+		tx.Account = m[1]
+	}
+	tx.Time = parseTime(text)
+	tx.Description = description(text, keywords)
+	return tx, nil
+}
+
+func containsAny(s string, words []string) bool {
+	for _, w := range words {
+		if strings.Contains(s, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTime reads "1403/01/12-14:25", "03/01/12 14:25" or "2024/04/01".
+func parseTime(text string) *time.Time {
+	m := reDate.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+	y, _ := strconv.Atoi(m[1])
+	mo, _ := strconv.Atoi(m[2])
+	d, _ := strconv.Atoi(m[3])
+	if y < 100 {
+		y += 1400 // two-digit Jalali year
+	}
+	var t time.Time
+	var err error
+	if y < 1700 {
+		t, err = utils.FromJalali(y, mo, d, time.UTC)
+	} else {
+		t = time.Date(y, time.Month(mo), d, 0, 0, 0, 0, time.UTC)
+		if t.Month() != time.Month(mo) {
+			err = errors.New("invalid date")
+		}
+	}
+	if err != nil {
+		return nil
+	}
+	if c := reClock.FindStringSubmatch(text[strings.Index(text, m[0])+len(m[0]):]); c != nil {
+		h, _ := strconv.Atoi(c[1])
+		min, _ := strconv.Atoi(c[2])
+		if h < 24 && min < 60 {
+			t = t.Add(time.Duration(h)*time.Hour + time.Duration(min)*time.Minute)
+		}
+	}
+	return &t
+}
+
+// description keeps the lines that are not a known field: usually the merchant
+// or the transfer counterparty ("خرید از فروشگاه رفاه").
+var reFieldLine = regexp.MustCompile(`^(بانک|مانده|موجودی|حساب|کارت|مبلغ|برداشت|واریز|تاریخ|ساعت|زمان|[+-]?[\d,./:*\-_ ]+$)`)
+
+func description(text string, keywords []string) string {
+	var parts []string
+	for _, l := range strings.Split(text, "\n") {
+		l = strings.Trim(l, "*-_ ")
+		if strings.HasPrefix(l, "خرید از") || strings.HasPrefix(l, "برداشت: خرید از") {
+			l = strings.TrimSpace(l[strings.Index(l, "خرید از")+len("خرید از"):])
+		}
+		if l == "" || reFieldLine.MatchString(l) || containsAny(strings.ToLower(l), keywords) {
+			continue
+		}
+		parts = append(parts, l)
+	}
+	return strings.Join(parts, " ")
+}
+
+func init() {
+	for _, f := range []KeywordFormat{
+		{Bank: "mellat", Keywords: []string{"ملت", "mellat"}},
+		{Bank: "melli", Keywords: []string{"بانک ملی", "bmi"}},
+		{Bank: "saman", Keywords: []string{"سامان", "saman"}},
+		{Bank: "pasargad", Keywords: []string{"پاسارگاد", "pasargad", "bpi"}},
+		{Bank: "tejarat", Keywords: []string{"تجارت", "tejarat"}},
+		{Bank: "parsian", Keywords: []string{"پارسیان", "parsian"}},
+		{Bank: "sepah", Keywords: []string{"سپه", "sepah"}},
+		{Bank: "saderat", Keywords: []string{"صادرات", "saderat"}},
+		{Bank: "keshavarzi", Keywords: []string{"کشاورزی", "keshavarzi"}},
+		{Bank: "maskan", Keywords: []string{"مسکن", "maskan"}},
+		{Bank: "refah", Keywords: []string{"بانک رفاه"}},
+		{Bank: "blu", Keywords: []string{"بلو", "blu"}},
+	} {
+		Register(f)
+	}
+}
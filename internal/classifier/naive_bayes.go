@@ -0,0 +1,184 @@
+// Package classifier is a small on-device text classifier (multinomial naive Bayes
+// over character n-grams) used as a second opinion next to the LLM's category.
+package classifier
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"example/AI/internal/utils"
+)
+
+const (
+	minGram = 2
+	maxGram = 4
+)
+
+// Features returns the character n-grams of the normalized text. Each word is padded
+// with spaces so prefixes/suffixes ("_سوپر") become features of their own.
+func Features(text string) []string {
+	var feats []string
+	for _, w := range strings.Fields(utils.NormalizeText(text)) {
+		r := []rune(" " + w + " ")
+		for n := minGram; n <= maxGram; n++ {
+			for i := 0; i+n <= len(r); i++ {
+				feats = append(feats, string(r[i:i+n]))
+			}
+		}
+	}
+	return feats
+}
+
+// NaiveBayes is safe for concurrent use and can be trained incrementally.
+type NaiveBayes struct {
+	mu          sync.RWMutex
+	docs        int
+	classDocs   map[string]int
+	classTokens map[string]int
+	counts      map[string]map[string]int // class -> feature -> count
+	vocab       map[string]int            // feature -> number of classes/docs using it
+}
+
+func New() *NaiveBayes {
+	return &NaiveBayes{
+		classDocs:   map[string]int{},
+		classTokens: map[string]int{},
+		counts:      map[string]map[string]int{},
+		vocab:       map[string]int{},
+	}
+}
+
+// Learn adds one labelled example.
+func (nb *NaiveBayes) Learn(text, class string) {
+	nb.update(text, class, 1)
+}
+
+// Forget removes an example learned earlier (used when a user re-labels a purchase).
+func (nb *NaiveBayes) Forget(text, class string) {
+	nb.update(text, class, -1)
+}
+
+func (nb *NaiveBayes) update(text, class string, delta int) {
+	if class == "" {
+		return
+	}
+	feats := Features(text)
+	if len(feats) == 0 {
+		return
+	}
+
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+
+	if delta < 0 && nb.classDocs[class] == 0 {
+		return
+	}
+	if nb.counts[class] == nil {
+		nb.counts[class] = map[string]int{}
+	}
+	nb.docs += delta
+	nb.classDocs[class] += delta
+	for _, f := range feats {
+		nb.counts[class][f] += delta
+		nb.classTokens[class] += delta
+		nb.vocab[f] += delta
+		if nb.counts[class][f] <= 0 {
+			delete(nb.counts[class], f)
+		}
+		if nb.vocab[f] <= 0 {
+			delete(nb.vocab, f)
+		}
+	}
+	if nb.classDocs[class] <= 0 {
+		delete(nb.classDocs, class)
+		delete(nb.classTokens, class)
+		delete(nb.counts, class)
+	}
+}
+
+// Docs returns the number of training examples.
+func (nb *NaiveBayes) Docs() int {
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
+	return nb.docs
+}
+
+// Distribution returns P(class | text) for every known class (sums to 1), or nil when
+// the model is empty.
+func (nb *NaiveBayes) Distribution(text string) map[string]float64 {
+	feats := Features(text)
+
+	nb.mu.RLock()
+	defer nb.mu.RUnlock()
+
+	if nb.docs == 0 || len(feats) == 0 {
+		return nil
+	}
+
+	vocab := float64(len(nb.vocab) + 1)
+	logs := make(map[string]float64, len(nb.classDocs))
+	best := math.Inf(-1)
+	for class, docs := range nb.classDocs {
+		lp := math.Log(float64(docs) / float64(nb.docs))
+		denom := float64(nb.classTokens[class]) + vocab
+		for _, f := range feats {
+			lp += math.Log((float64(nb.counts[class][f]) + 1) / denom) // Laplace smoothing
+		}
+		logs[class] = lp
+		best = math.Max(best, lp)
+	}
+
+	// softmax in log space
+	sum := 0.0
+	dist := make(map[string]float64, len(logs))
+	for class, lp := range logs {
+		dist[class] = math.Exp(lp - best)
+		sum += dist[class]
+	}
+	for class := range dist {
+		dist[class] /= sum
+	}
+	return dist
+}
+
+// Predict returns the most likely class and its probability ("" when the model is empty).
+func (nb *NaiveBayes) Predict(text string) (string, float64) {
+	return Best(nb.Distribution(text))
+}
+
+// Best picks the top class of a distribution (ties broken alphabetically for stability).
+func Best(dist map[string]float64) (string, float64) {
+	classes := make([]string, 0, len(dist))
+	for c := range dist {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+
+	best, prob := "", 0.0
+	for _, c := range classes {
+		if dist[c] > prob {
+			best, prob = c, dist[c]
+		}
+	}
+	return best, prob
+}
+
+// Blend mixes two distributions: w*a + (1-w)*b.
+func Blend(a, b map[string]float64, w float64) map[string]float64 {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	out := map[string]float64{}
+	for c, p := range a {
+		out[c] += w * p
+	}
+	for c, p := range b {
+		out[c] += (1 - w) * p
+	}
+	return out
+}
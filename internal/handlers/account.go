@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/services"
+	"example/AI/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountHandler struct {
+	Accounts *services.AccountService
+}
+
+func NewAccountHandler(as *services.AccountService) *AccountHandler {
+	return &AccountHandler{Accounts: as}
+}
+
+type accountReq struct {
+	Name           string       `json:"name" binding:"required"`
+	Type           string       `json:"type"` // cash | card | wallet | credit
+	Bank           string       `json:"bank"`
+	Number         string       `json:"number"`
+	Currency       string       `json:"currency"`
+	OpeningBalance models.Money `json:"opening_balance"`
+	OpenedAt       *time.Time   `json:"opened_at"`
+	IsDefault      bool         `json:"is_default"`
+}
+
+func (r accountReq) apply(a *models.Account) {
+	a.Name, a.Type, a.Bank, a.Number = r.Name, r.Type, r.Bank, r.Number
+	a.Currency, a.OpeningBalance, a.IsDefault = r.Currency, r.OpeningBalance, r.IsDefault
+	if r.OpenedAt != nil {
+		a.OpenedAt = *r.OpenedAt
+	}
+}
+
+type transferReq struct {
+	FromAccountID uint64       `json:"from_account_id" binding:"required"`
+	ToAccountID   uint64       `json:"to_account_id" binding:"required"`
+	Amount        models.Money `json:"amount" binding:"required"`
+	Fee           models.Money `json:"fee"`
+	At            *time.Time   `json:"at"`
+	Note          string       `json:"note"`
+}
+
+type reconcileReq struct {
+	Balance models.Money `json:"balance" binding:"required"` // statement balance
+	Date    string       `json:"date"`                       // statement date, default now
+	Adjust  bool         `json:"adjust"`                     // book the difference
+}
+
+func accountError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrAccountNotFound) || errors.Is(err, services.ErrTransferNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+func accountID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// GET /api/accounts?archived=true
+func (h *AccountHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := h.Accounts.List(c.GetInt("userID"), c.Query("archived") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"accounts": items})
+	}
+}
+
+// GET /api/accounts/balances
+func (h *AccountHandler) Balances() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := h.Accounts.Balances(c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"accounts": items})
+	}
+}
+
+// POST /api/accounts
+func (h *AccountHandler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body accountReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		a := models.Account{UserID: c.GetInt("userID")}
+		body.apply(&a)
+		if err := h.Accounts.Create(&a); err != nil {
+			accountError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"account": a})
+	}
+}
+
+// PUT /api/accounts/:id
+func (h *AccountHandler) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := accountID(c)
+		if !ok {
+			return
+		}
+		var body accountReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		a, err := h.Accounts.Get(c.GetInt("userID"), id)
+		if err != nil {
+			accountError(c, err)
+			return
+		}
+		body.apply(a)
+		if err := h.Accounts.Update(a); err != nil {
+			accountError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"account": a})
+	}
+}
+
+// DELETE /api/accounts/:id  (archives; the history stays)
+func (h *AccountHandler) Archive() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := accountID(c)
+		if !ok {
+			return
+		}
+		if err := h.Accounts.Archive(c.GetInt("userID"), id); err != nil {
+			accountError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "account archived"})
+	}
+}
+
+// GET /api/accounts/:id/ledger?from_date=&to_date=
+func (h *AccountHandler) Ledger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := accountID(c)
+		if !ok {
+			return
+		}
+		pf, err := filterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		a, entries, err := h.Accounts.Ledger(c.GetInt("userID"), id, pf.FromDate, pf.ToDate)
+		if err != nil {
+			accountError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"account": a, "entries": entries})
+	}
+}
+
+// POST /api/accounts/:id/reconcile
+func (h *AccountHandler) Reconcile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := accountID(c)
+		if !ok {
+			return
+		}
+		var body reconcileReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		at := time.Now().UTC()
+		if body.Date != "" {
+			d, err := utils.ParseDate(body.Date)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+				return
+			}
+			at = d.Add(24*time.Hour - time.Nanosecond) // end of the statement day
+		}
+		res, err := h.Accounts.Reconcile(c.GetInt("userID"), id, body.Balance, at, body.Adjust)
+		if err != nil {
+			accountError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// GET /api/accounts/:id/reconciliations
+func (h *AccountHandler) Reconciliations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := accountID(c)
+		if !ok {
+			return
+		}
+		items, err := h.Accounts.Reconciliations(c.GetInt("userID"), id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"reconciliations": items})
+	}
+}
+
+// GET /api/transfers?limit=
+func (h *AccountHandler) Transfers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		items, err := h.Accounts.Transfers(c.GetInt("userID"), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"transfers": items})
+	}
+}
+
+// POST /api/transfers
+func (h *AccountHandler) CreateTransfer() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body transferReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		t := models.Transfer{
+			UserID:        c.GetInt("userID"),
+			FromAccountID: body.FromAccountID,
+			ToAccountID:   body.ToAccountID,
+			Amount:        body.Amount,
+			Fee:           body.Fee,
+			Note:          body.Note,
+		}
+		if body.At != nil {
+			t.At = *body.At
+		}
+		if err := h.Accounts.CreateTransfer(&t); err != nil {
+			accountError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"transfer": t})
+	}
+}
+
+// DELETE /api/transfers/:id
+func (h *AccountHandler) DeleteTransfer() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := accountID(c)
+		if !ok {
+			return
+		}
+		if err := h.Accounts.DeleteTransfer(c.GetInt("userID"), id); err != nil {
+			accountError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "transfer deleted"})
+	}
+}
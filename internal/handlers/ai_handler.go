@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"example/AI/internal/models"
@@ -19,9 +21,22 @@ type AiMessageReq struct {
 }
 
 type AiHandler struct {
-	AI       *services.AIService
-	Purchase *services.PurchaseService
-	DB       *gorm.DB // or *gorm.DB
+	AI        *services.AIService
+	Purchase  *services.PurchaseService
+	DB        *gorm.DB // or *gorm.DB
+	Drafts    *services.DraftService
+	Budgets   *services.BudgetService
+	Schedules *services.ScheduleService
+	Anomalies *services.AnomalyService
+	Forecasts *services.ForecastService
+	Emotions  *services.EmotionService
+	Exports   *services.ExportService
+	Incomes   *services.IncomeService
+	Accounts  *services.AccountService
+	Refunds   *services.RefundService
+	// installment plans (add_installment / pay_installment, debt in analyses)
+	Installments *services.InstallmentService
+	Prices       *services.PriceService
 }
 
 func NewAiHandler(ai *services.AIService, ps *services.PurchaseService, dbw *gorm.DB) *AiHandler {
@@ -46,6 +61,18 @@ func (h *AiHandler) HandleMessage() gin.HandlerFunc {
 		}
 		userID := uidVal.(int)
 
+		// pending draft? a bare answer ("۲۵۰ هزار") is merged without calling the model
+		var draft *models.PurchaseDraft
+		if h.Drafts != nil {
+			draft, _ = h.Drafts.Active(userID)
+			if draft != nil {
+				if data, ok := h.Drafts.QuickAnswer(draft, body.Message); ok {
+					h.savePurchase(c, userID, data, draft.InputText+"\n"+body.Message, draft, "")
+					return
+				}
+			}
+		}
+
 		// send to AI
 		parsed, assistantText, err := h.AI.ProcessMessage(body.Message, userID, username.(string), role.(string))
 		if err != nil {
@@ -76,25 +103,204 @@ func (h *AiHandler) HandleMessage() gin.HandlerFunc {
 				mapData = parsed.Data
 			}
 
-			p, err := h.Purchase.CreateFromAIData(userID, mapData)
+			source := body.Message
+			if draft != nil && !h.Drafts.Answers(draft, mapData) {
+				draft = nil // an unrelated purchase: saved on its own, the draft keeps waiting
+			}
+			if draft != nil {
+				mapData = h.Drafts.Merge(draft, mapData)
+				source = draft.InputText + "\n" + body.Message
+			}
+			h.savePurchase(c, userID, mapData, source, draft, parsed.AssistantReply)
+			return
+
+		case "set_budget":
+			if h.Budgets == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": assistantText, "error": "budgets are not enabled"})
+				return
+			}
+			budgetData := parsed.Budget
+			if budgetData == nil {
+				budgetData, _ = parsed.Data["budget"].(map[string]interface{})
+			}
+			b, err := h.Budgets.SetFromAIData(userID, budgetData)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": parsed.AssistantReply, "error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"message": parsed.AssistantReply,
+				"budget":  b,
+			})
+			return
+
+		case "add_recurring":
+			if h.Schedules == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": assistantText, "error": "scheduled purchases are not enabled"})
+				return
+			}
+			scheduleData := parsed.Schedule
+			if scheduleData == nil {
+				scheduleData, _ = parsed.Data["schedule"].(map[string]interface{})
+			}
+			sp, created, err := h.Schedules.FromAIData(userID, scheduleData, body.Message)
 			if err != nil {
-				// reply natural-language assistantText + an error
-				c.JSON(http.StatusBadRequest, gin.H{
-					"message": assistantText,
-					"error":   err.Error(),
+				c.JSON(http.StatusBadRequest, gin.H{"message": parsed.AssistantReply, "error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"message":           parsed.AssistantReply,
+				"scheduled":         sp,
+				"created_purchases": created,
+			})
+			return
+
+		case "add_income":
+			if h.Incomes == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": assistantText, "error": "incomes are not enabled"})
+				return
+			}
+			incomeData := parsed.Income
+			if incomeData == nil {
+				incomeData, _ = parsed.Data["income"].(map[string]interface{})
+			}
+			in, err := h.Incomes.CreateFromAIData(userID, incomeData, body.Message)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": parsed.AssistantReply, "error": err.Error()})
+				return
+			}
+			reply := parsed.AssistantReply
+			if reply == "" {
+				reply = "درآمدت ثبت شد."
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"message": reply,
+				"income":  in,
+			})
+			return
+
+		case "add_refund":
+			if h.Refunds == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": assistantText, "error": "refunds are not enabled"})
+				return
+			}
+			refundData := parsed.Refund
+			if refundData == nil {
+				refundData, _ = parsed.Data["refund"].(map[string]interface{})
+			}
+			r, p, err := h.Refunds.FromAIData(userID, refundData, body.Message)
+			var ambiguous *services.AmbiguousRefundError
+			if errors.As(err, &ambiguous) {
+				// the user picks one: POST /api/purchases/:id/refunds
+				reply := "خریدی که پولش برگشته رو پیدا نکردم؛ دقیق‌تر بگو چی بود و کی خریدی."
+				if len(ambiguous.Candidates) > 0 {
+					reply = "کدوم خرید بود؟ یکی از این‌ها رو انتخاب کن."
+				}
+				c.JSON(http.StatusOK, gin.H{
+					"message":    reply,
+					"candidates": ambiguous.Candidates,
 				})
 				return
 			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": parsed.AssistantReply, "error": err.Error()})
+				return
+			}
+			reply := parsed.AssistantReply
+			if reply == "" {
+				reply = "برگشت پول ثبت شد."
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"message":  reply,
+				"refund":   r,
+				"purchase": p,
+				"net":      p.Net(),
+			})
+			return
+
+		case "add_installment", "pay_installment":
+			if h.Installments == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": assistantText, "error": "installments are not enabled"})
+				return
+			}
+			planData := parsed.Installment
+			if planData == nil {
+				planData, _ = parsed.Data["installment"].(map[string]interface{})
+			}
+			var st *services.InstallmentStatus
+			var err error
+			if parsed.Action == "add_installment" {
+				st, err = h.Installments.FromAIData(userID, planData, body.Message)
+			} else {
+				st, err = h.Installments.PayFromAIData(userID, planData)
+			}
+			if errors.Is(err, services.ErrPlanNotFound) {
+				c.JSON(http.StatusOK, gin.H{"message": "قسط کدوم خرید رو دادی؟ اسمش رو دقیق‌تر بگو."})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": parsed.AssistantReply, "error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"message":     parsed.AssistantReply,
+				"installment": st,
+			})
+			return
 
+		case "transfer":
+			if h.Accounts == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": assistantText, "error": "accounts are not enabled"})
+				return
+			}
+			transferData := parsed.Transfer
+			if transferData == nil {
+				transferData, _ = parsed.Data["transfer"].(map[string]interface{})
+			}
+			t, err := h.Accounts.TransferFromAIData(userID, transferData, body.Message)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": "نتونستم انتقال رو ثبت کنم؛ حساب مبدا و مقصد رو دقیق بگو.", "error": err.Error()})
+				return
+			}
 			c.JSON(http.StatusOK, gin.H{
 				"message":  parsed.AssistantReply,
-				"purchase": p,
+				"transfer": t,
+			})
+			return
+
+		case "export":
+			if h.Exports == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"message": assistantText, "error": "exports are not enabled"})
+				return
+			}
+			pf := utils.ConvertAIFiltersToPurchaseFilter(parsed.Filters, parsed.RequestContext)
+			_ = h.Purchase.CanonicalizeFilter(userID, &pf)
+			format, _ := parsed.Export["format"].(string)
+			format = strings.ToLower(format)
+			if format == "excel" || format == "xls" {
+				format = services.ExportXLSX
+			}
+			if !services.ValidExportFormat(format) {
+				format = services.ExportCSV
+			}
+			period, _ := parsed.Export["period"].(string)
+			if !utils.ValidPeriod(period) {
+				period = utils.PeriodMonthly
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"message": parsed.AssistantReply,
+				"export": gin.H{
+					"format": format,
+					"url":    exportURL(userID, format, period, pf),
+				},
 			})
 			return
 
 		case "get_purchases", "query":
 			fmt.Println(parsed)
 			pf := utils.ConvertAIFiltersToPurchaseFilter(parsed.Filters, parsed.RequestContext)
+			_ = h.Purchase.CanonicalizeFilter(userID, &pf)
+			pf.IncludeUnconfirmed = true // listings show everything except rejected, with status
 			fmt.Println("=================")
 			fmt.Println("filter : ", pf)
 			fmt.Println("=================")
@@ -114,6 +320,7 @@ func (h *AiHandler) HandleMessage() gin.HandlerFunc {
 		case "analyze":
 			// parsed.Filters -> convert to PurchaseFilter
 			pf := utils.ConvertAIFiltersToPurchaseFilter(parsed.Filters, parsed.RequestContext)
+			_ = h.Purchase.CanonicalizeFilter(userID, &pf)
 
 			// compute server-side analytics
 			total, _ := h.Purchase.SumAmount(pf)
@@ -127,6 +334,86 @@ func (h *AiHandler) HandleMessage() gin.HandlerFunc {
 				"purchase_count":     count,
 				"filters":            parsed.Filters,
 			}
+			if pf.Basis == models.BasisCash {
+				analysisPayload["basis"] = models.BasisCash // installments counted as paid, not at full price
+			}
+			if hasDimension(parsed.Analysis, "category") || intentMatches(parsed.Analysis, "category") {
+				// receipt items count under their own categories
+				if cats, err := h.Purchase.CategoryTotals(pf); err == nil {
+					analysisPayload["categories"] = cats
+				}
+			}
+			if hasDimension(parsed.Analysis, "vendor") && h.Purchase.Vendors != nil {
+				if vendors, err := h.Purchase.Vendors.Report(pf); err == nil {
+					analysisPayload["vendors"] = vendors
+				}
+			}
+			if h.Anomalies != nil && intentMatches(parsed.Analysis, "overspend", "anomal", "unusual", "spike", "outlier") {
+				if len(pf.UserIDs) == 0 {
+					pf.UserIDs = []int{userID}
+				}
+				if flags, err := scanAnomalies(h.Anomalies, pf); err == nil {
+					analysisPayload["anomalies"] = flags
+				}
+			}
+			if h.Forecasts != nil && intentMatches(parsed.Analysis, "forecast", "predict", "projection", "at this rate") {
+				period := utils.PeriodMonthly
+				if level, _ := parsed.Analysis["aggregation_level"].(string); utils.ValidPeriod(level) {
+					period = level
+				}
+				if f, err := h.Forecasts.Forecast(userID, period, time.Now().UTC(), pf.Categories); err == nil {
+					analysisPayload["forecast"] = f
+				}
+			}
+			if h.Incomes != nil && (hasDimension(parsed.Analysis, "income") ||
+				intentMatches(parsed.Analysis, "income", "cash-flow", "cash flow", "saving", "earn")) {
+				if len(pf.UserIDs) == 0 {
+					pf.UserIDs = []int{userID}
+				}
+				incomePF := pf
+				incomePF.Categories, incomePF.MinAmount, incomePF.MaxAmount = nil, nil, nil
+				if income, err := h.Incomes.Sum(incomePF); err == nil {
+					analysisPayload["income_total"] = income
+				}
+				if level, _ := parsed.Analysis["aggregation_level"].(string); utils.ValidPeriod(level) {
+					if flow, err := h.Incomes.CashFlow(incomePF, level); err == nil {
+						analysisPayload["cash_flow"] = flow
+					}
+				} else if flow, err := h.Incomes.Summary(incomePF); err == nil {
+					analysisPayload["cash_flow"] = flow
+				}
+			}
+			if h.Installments != nil && intentMatches(parsed.Analysis, "installment", "debt", "loan", "credit") {
+				if debt, err := h.Installments.Debt(userID); err == nil {
+					analysisPayload["installments"] = debt
+				}
+			}
+			if h.Prices != nil && intentMatches(parsed.Analysis, "inflation", "price", "گرانی") {
+				level, _ := parsed.Analysis["aggregation_level"].(string)
+				if !utils.ValidPeriod(level) {
+					level = utils.PeriodMonthly
+				}
+				if len(pf.UserIDs) == 0 {
+					pf.UserIDs = []int{userID}
+				}
+				if report, err := h.Prices.Inflation(pf, level); err == nil {
+					analysisPayload["inflation"] = report
+				}
+				item := ""
+				if kw, _ := parsed.Filters["keywords"].([]interface{}); len(kw) > 0 {
+					item, _ = kw[0].(string)
+				}
+				if prices, err := h.Prices.Prices(pf, level, item, ""); err == nil {
+					analysisPayload["prices"] = prices
+				}
+			}
+			if h.Emotions != nil && (hasDimension(parsed.Analysis, "emotion") || hasDimension(parsed.Analysis, "necessity") ||
+				intentMatches(parsed.Analysis, "emotion", "impulse", "necessity", "mood")) {
+				level, _ := parsed.Analysis["aggregation_level"].(string)
+				if report, err := h.Emotions.Report(pf, level); err == nil {
+					analysisPayload["emotions"] = report
+				}
+			}
 
 			// generate friendly natural summary via AI
 			natural, raw, err := h.AI.GenerateNaturalAnalysis(analysisPayload)
@@ -153,3 +440,78 @@ func (h *AiHandler) HandleMessage() gin.HandlerFunc {
 		}
 	}
 }
+
+// hasDimension reports whether analysis.dimensions contains dim (case-insensitive).
+func hasDimension(analysis map[string]interface{}, dim string) bool {
+	dims, _ := analysis["dimensions"].([]interface{})
+	for _, d := range dims {
+		if s, ok := d.(string); ok && strings.EqualFold(s, dim) {
+			return true
+		}
+	}
+	return false
+}
+
+// intentMatches reports whether analysis.intent contains any of the words (case-insensitive).
+func intentMatches(analysis map[string]interface{}, words ...string) bool {
+	intent, _ := analysis["intent"].(string)
+	intent = strings.ToLower(intent)
+	for _, w := range words {
+		if strings.Contains(intent, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// savePurchase creates the purchase; if required fields are missing it keeps the data as a
+// draft and replies with a follow-up question instead of an error.
+func (h *AiHandler) savePurchase(c *gin.Context, userID int, data map[string]interface{}, source string, draft *models.PurchaseDraft, reply string) {
+	p, err := h.Purchase.CreateFromAIData(userID, data, source)
+
+	var incomplete *services.IncompletePurchaseError
+	if errors.As(err, &incomplete) && h.Drafts != nil {
+		d, derr := h.Drafts.Save(userID, data, incomplete.Missing, source)
+		if derr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": derr.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":     d.Question,
+			"needs_input": true,
+			"missing":     incomplete.Missing,
+			"draft_id":    d.ID,
+		})
+		return
+	}
+	if err != nil {
+		// reply natural-language assistantText + an error
+		c.JSON(http.StatusBadRequest, gin.H{
+			"message": reply,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if draft != nil {
+		_ = h.Drafts.Discard(draft)
+	}
+	if reply == "" {
+		reply = "خریدت ثبت شد."
+	}
+	resp := gin.H{
+		"message":  reply,
+		"purchase": p,
+	}
+	if p.DuplicateOf != nil {
+		resp["message"] = fmt.Sprintf("%s احتمالاً تکراری است (خرید #%d)؛ اگر جداست تاییدش کن، وگرنه ادغامش کن.", reply, *p.DuplicateOf)
+		resp["duplicate_of"] = *p.DuplicateOf
+		resp["duplicate_warning"] = services.DuplicateWarning(*p.DuplicateOf)
+	}
+	if h.Budgets != nil {
+		if warnings, _ := h.Budgets.Evaluate(p); len(warnings) > 0 {
+			resp["budget_warnings"] = warnings
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"example/AI/internal/banksms"
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// max messages accepted in one request
+const maxBankSMS = 500
+
+type BankSMSHandler struct {
+	SMS *services.BankSMSService
+}
+
+func NewBankSMSHandler(ss *services.BankSMSService) *BankSMSHandler {
+	return &BankSMSHandler{SMS: ss}
+}
+
+// POST /api/bank-sms {"messages": ["..."]} or {"text": "sms 1\n\nsms 2"}
+func (h *BankSMSHandler) Import() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body struct {
+			Messages []string `json:"messages"`
+			Text     string   `json:"text"` // several SMS separated by blank lines
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		messages := append(body.Messages, banksms.Split(body.Text)...)
+		if len(messages) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no messages"})
+			return
+		}
+		if len(messages) > maxBankSMS {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "too many messages"})
+			return
+		}
+		results, err := h.SMS.Import(c.GetInt("userID"), messages)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		counts := map[string]int{}
+		for _, r := range results {
+			counts[r.Status]++
+		}
+		c.JSON(http.StatusOK, gin.H{"results": results, "counts": counts})
+	}
+}
+
+// GET /api/bank-sms?limit=100
+func (h *BankSMSHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		items, err := h.SMS.Transactions(c.GetInt("userID"), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"transactions": items})
+	}
+}
+
+// GET /api/bank-sms/balances
+func (h *BankSMSHandler) Balances() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := h.SMS.Balances(c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"balances": items})
+	}
+}
+
+// GET /api/bank-sms/formats
+func (h *BankSMSHandler) Formats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"formats": banksms.Formats()})
+	}
+}
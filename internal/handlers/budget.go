@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BudgetHandler struct {
+	Budgets *services.BudgetService
+}
+
+func NewBudgetHandler(bs *services.BudgetService) *BudgetHandler {
+	return &BudgetHandler{Budgets: bs}
+}
+
+type budgetReq struct {
+	Category    string       `json:"category"` // "" = overall
+	Period      string       `json:"period"`   // weekly | monthly | jalali_monthly
+	Limit       models.Money `json:"limit" binding:"required"`
+	Rollover    string       `json:"rollover"` // none | surplus | full
+	WarnPercent int          `json:"warn_percent"`
+}
+
+func budgetError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrBudgetNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// GET /api/budgets
+func (h *BudgetHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := h.Budgets.List(c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"budgets": items})
+	}
+}
+
+// GET /api/budgets/status
+func (h *BudgetHandler) Status() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := h.Budgets.StatusAll(c.GetInt("userID"), time.Now().UTC())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"budgets": items})
+	}
+}
+
+// POST /api/budgets
+func (h *BudgetHandler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body budgetReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		b := models.Budget{
+			UserID:      c.GetInt("userID"),
+			Category:    body.Category,
+			Period:      body.Period,
+			Limit:       body.Limit,
+			Rollover:    body.Rollover,
+			WarnPercent: body.WarnPercent,
+		}
+		if err := h.Budgets.Upsert(&b); err != nil {
+			budgetError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"budget": b})
+	}
+}
+
+// PUT /api/budgets/:id
+func (h *BudgetHandler) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body budgetReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		b, err := h.Budgets.Get(c.GetInt("userID"), id)
+		if err != nil {
+			budgetError(c, err)
+			return
+		}
+		b.Category, b.Period, b.Limit, b.Rollover, b.WarnPercent = body.Category, body.Period, body.Limit, body.Rollover, body.WarnPercent
+		if err := h.Budgets.Update(b); err != nil {
+			budgetError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"budget": b})
+	}
+}
+
+// DELETE /api/budgets/:id
+func (h *BudgetHandler) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		if err := h.Budgets.Delete(c.GetInt("userID"), id); err != nil {
+			budgetError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "budget deleted"})
+	}
+}
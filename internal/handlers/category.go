@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryHandler struct {
+	Taxonomy *services.TaxonomyService
+}
+
+func NewCategoryHandler(t *services.TaxonomyService) *CategoryHandler {
+	return &CategoryHandler{Taxonomy: t}
+}
+
+type createCategoryReq struct {
+	Key       string   `json:"key"`
+	Label     string   `json:"label" binding:"required"`
+	Lang      string   `json:"lang"`
+	ParentKey string   `json:"parent_key"`
+	Synonyms  []string `json:"synonyms"`
+	Global    bool     `json:"global"` // admin only
+}
+
+type synonymReq struct {
+	Text   string `json:"text" binding:"required"`
+	Global bool   `json:"global"` // admin only
+}
+
+type mergeCategoriesReq struct {
+	From   string `json:"from" binding:"required"`
+	Into   string `json:"into" binding:"required"`
+	Global bool   `json:"global"` // admin only
+}
+
+type recategorizeReq struct {
+	UserIDs         []int  `json:"user_ids"` // admin only, default = current user
+	FromCategory    string `json:"from_category"`
+	FromSubcategory string `json:"from_subcategory"`
+	ToCategory      string `json:"to_category"` // empty = re-map everything to the current taxonomy
+	ToSubcategory   string `json:"to_subcategory"`
+}
+
+func isAdmin(c *gin.Context) bool {
+	return c.GetString("role") == "admin"
+}
+
+func categoryError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrCategoryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// GET /api/categories
+func (h *CategoryHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tree, err := h.Taxonomy.Tree(c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"categories": tree})
+	}
+}
+
+// POST /api/categories
+func (h *CategoryHandler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body createCategoryReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if body.Global && !isAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin only"})
+			return
+		}
+
+		var owner *int
+		if !body.Global {
+			uid := c.GetInt("userID")
+			owner = &uid
+		}
+		cat, err := h.Taxonomy.CreateCategory(owner, services.CustomCategoryInput{
+			Key:       body.Key,
+			Label:     body.Label,
+			Lang:      body.Lang,
+			ParentKey: body.ParentKey,
+			Synonyms:  body.Synonyms,
+		})
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"category": cat})
+	}
+}
+
+// POST /api/categories/:id/synonyms
+func (h *CategoryHandler) AddSynonym() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body synonymReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if body.Global && !isAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin only"})
+			return
+		}
+
+		var owner *int
+		if !body.Global {
+			uid := c.GetInt("userID")
+			owner = &uid
+		}
+		syn, err := h.Taxonomy.AddSynonym(id, owner, body.Text)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"synonym": syn})
+	}
+}
+
+// POST /api/categories/merge
+func (h *CategoryHandler) Merge() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body mergeCategoriesReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		if body.Global && !isAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "admin only"})
+			return
+		}
+
+		moved, err := h.Taxonomy.MergeCategories(c.GetInt("userID"), body.From, body.Into, body.Global)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "categories merged", "purchases_updated": moved})
+	}
+}
+
+// POST /api/categories/recategorize
+func (h *CategoryHandler) Recategorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body recategorizeReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		userIDs := []int{c.GetInt("userID")}
+		if len(body.UserIDs) > 0 {
+			if !isAdmin(c) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "admin only"})
+				return
+			}
+			userIDs = body.UserIDs
+		}
+
+		updated, err := h.Taxonomy.Recategorize(userIDs, body.FromCategory, body.FromSubcategory, body.ToCategory, body.ToSubcategory)
+		if err != nil {
+			categoryError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"purchases_updated": updated})
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DuplicateHandler struct {
+	Duplicates *services.DuplicateService
+}
+
+func NewDuplicateHandler(ds *services.DuplicateService) *DuplicateHandler {
+	return &DuplicateHandler{Duplicates: ds}
+}
+
+// GET /api/duplicates
+func (h *DuplicateHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := h.Duplicates.List(c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"duplicates": items, "total": len(items)})
+	}
+}
+
+// POST /api/duplicates/scan?from_date=&to_date=  (default: last 90 days)
+func (h *DuplicateHandler) Scan() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pf, err := filterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		from, to := insightRange(pf, 90)
+		found, err := h.Duplicates.Scan(c.GetInt("userID"), from, to)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"duplicates": found, "total": len(found)})
+	}
+}
+
+// POST /api/purchases/:id/keep — not a duplicate
+func (h *DuplicateHandler) Keep() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := purchaseID(c)
+		if !ok {
+			return
+		}
+		p, err := h.Duplicates.Keep(c.GetInt("userID"), id)
+		if err != nil {
+			purchaseError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"purchase": p})
+	}
+}
+
+// POST /api/purchases/:id/merge {"into": 123}  (default: the purchase it duplicates)
+func (h *DuplicateHandler) Merge() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := purchaseID(c)
+		if !ok {
+			return
+		}
+		var body struct {
+			Into uint64 `json:"into"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+				return
+			}
+		}
+		p, err := h.Duplicates.Merge(c.GetInt("userID"), id, body.Into)
+		if errors.Is(err, services.ErrPurchaseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"purchase": p, "merged": id})
+	}
+}
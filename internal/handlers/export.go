@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/services"
+	"example/AI/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	Exports *services.ExportService
+}
+
+func NewExportHandler(es *services.ExportService) *ExportHandler {
+	return &ExportHandler{Exports: es}
+}
+
+// GET /api/exports?format=csv|xlsx|ndjson|beancount|hledger&<purchase filter>
+// GET /api/exports?format=pdf&period=monthly|jalali_monthly|weekly&date=YYYY-MM-DD
+func (h *ExportHandler) Download() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", services.ExportCSV)
+		if !services.ValidExportFormat(format) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv, xlsx, ndjson, pdf, beancount or hledger"})
+			return
+		}
+		pf, err := filterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		_ = h.Exports.Purchase.CanonicalizeFilter(c.GetInt("userID"), &pf)
+
+		name := "purchases-" + time.Now().UTC().Format("20060102")
+		write := func() error { return h.Exports.Stream(c.Writer, format, pf) }
+		if format == services.ExportPDF {
+			period := c.DefaultQuery("period", utils.PeriodMonthly)
+			if !utils.ValidPeriod(period) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid period"})
+				return
+			}
+			at := time.Now().UTC()
+			if raw := c.Query("date"); raw != "" {
+				if at, err = utils.ParseDate(raw); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+			}
+			name = "report-" + utils.PeriodLabel(period, at)
+			write = func() error { return h.Exports.Report(c.Writer, c.GetInt("userID"), period, at) }
+		}
+
+		c.Header("Content-Type", services.ExportContentType(format))
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, url.PathEscape(name), services.ExportExtension(format)))
+		c.Status(http.StatusOK)
+		if err := write(); err != nil {
+			if !c.Writer.Written() {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			// the body is already streaming; the client gets a truncated file
+			log.Printf("export error: %v", err)
+			c.Abort()
+		}
+	}
+}
+
+// exportURL is the download link for an export requested in chat.
+func exportURL(userID int, format, period string, pf models.PurchaseFilter) string {
+	q := url.Values{}
+	q.Set("format", format)
+	if len(pf.UserIDs) > 1 || (len(pf.UserIDs) == 1 && pf.UserIDs[0] != userID) {
+		for _, id := range pf.UserIDs {
+			q.Add("user_id", strconv.Itoa(id))
+		}
+	}
+	for _, cat := range pf.Categories {
+		q.Add("category", cat)
+	}
+	if pf.FromDate != nil {
+		q.Set("from_date", pf.FromDate.Format("2006-01-02"))
+	}
+	if pf.ToDate != nil {
+		q.Set("to_date", pf.ToDate.Format("2006-01-02"))
+	}
+	if pf.MinAmount != nil {
+		q.Set("min_amount", pf.MinAmount.Decimal())
+		q.Set("currency", pf.MinAmount.Currency)
+	}
+	if pf.MaxAmount != nil {
+		q.Set("max_amount", pf.MaxAmount.Decimal())
+		q.Set("currency", pf.MaxAmount.Currency)
+	}
+	if pf.IncludeUnconfirmed {
+		q.Set("include_unconfirmed", "true")
+	}
+	if format == services.ExportPDF {
+		q.Set("period", period)
+		if pf.FromDate != nil {
+			q.Set("date", pf.FromDate.Format("2006-01-02"))
+		}
+	}
+	return "/api/exports?" + q.Encode()
+}
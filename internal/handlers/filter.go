@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"example/AI/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// filterFromQuery builds a PurchaseFilter for REST endpoints from the query string:
+// from_date, to_date (YYYY-MM-DD), category (repeatable), min_amount, max_amount, currency,
+// include_unconfirmed (true/false).
+// Users always get their own data; admins may pass user_id (repeatable).
+func filterFromQuery(c *gin.Context) (models.PurchaseFilter, error) {
+	var pf models.PurchaseFilter
+
+	pf.UserIDs = []int{c.GetInt("userID")}
+	if ids := c.QueryArray("user_id"); len(ids) > 0 && isAdmin(c) {
+		pf.UserIDs = nil
+		for _, raw := range ids {
+			id, err := strconv.Atoi(raw)
+			if err != nil {
+				return pf, fmt.Errorf("invalid user_id %q", raw)
+			}
+			pf.UserIDs = append(pf.UserIDs, id)
+		}
+	}
+
+	pf.Categories = c.QueryArray("category")
+
+	for key, dst := range map[string]**time.Time{"from_date": &pf.FromDate, "to_date": &pf.ToDate} {
+		if raw := c.Query(key); raw != "" {
+			t, err := time.Parse("2006-01-02", raw)
+			if err != nil {
+				return pf, fmt.Errorf("invalid %s %q", key, raw)
+			}
+			if key == "to_date" {
+				t = t.Add(24*time.Hour - time.Nanosecond) // inclusive
+			}
+			*dst = &t
+		}
+	}
+
+	pf.IncludeUnconfirmed = c.Query("include_unconfirmed") == "true"
+
+	switch basis := c.Query("basis"); basis {
+	case "", models.BasisAccrual:
+	case models.BasisCash:
+		pf.Basis = basis
+	default:
+		return pf, fmt.Errorf("invalid basis %q (accrual | cash)", basis)
+	}
+
+	currency := c.Query("currency")
+	for key, dst := range map[string]**models.Money{"min_amount": &pf.MinAmount, "max_amount": &pf.MaxAmount} {
+		if raw := c.Query(key); raw != "" {
+			m, err := models.ParseMoney(raw, currency)
+			if err != nil {
+				return pf, fmt.Errorf("invalid %s: %v", key, err)
+			}
+			*dst = &m
+		}
+	}
+
+	return pf, nil
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// max upload size for an import file
+const maxImportFileSize = 20 << 20
+
+type ImportHandler struct {
+	Imports *services.ImportService
+}
+
+func NewImportHandler(is *services.ImportService) *ImportHandler {
+	return &ImportHandler{Imports: is}
+}
+
+func importError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrImportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// POST /api/imports (multipart: file, optional mapping JSON, currency)
+func (h *ImportHandler) Upload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		if fh.Size > maxImportFileSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var mapping map[string]interface{}
+		if m := c.PostForm("mapping"); m != "" {
+			if err := json.Unmarshal([]byte(m), &mapping); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mapping"})
+				return
+			}
+		}
+		preview, err := h.Imports.Upload(c.GetInt("userID"), fh.Filename, data, mapping, c.PostForm("currency"))
+		if err != nil {
+			importError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, preview)
+	}
+}
+
+// GET /api/imports/:id?status=invalid&limit=100
+func (h *ImportHandler) Preview() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		preview, err := h.Imports.Preview(c.GetInt("userID"), id, c.Query("status"), limit)
+		if err != nil {
+			importError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, preview)
+	}
+}
+
+// PUT /api/imports/:id/mapping
+func (h *ImportHandler) Remap() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body struct {
+			Mapping  map[string]interface{} `json:"mapping" binding:"required"` // field -> header or column index
+			Currency string                 `json:"currency"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		preview, err := h.Imports.Remap(c.GetInt("userID"), id, body.Mapping, body.Currency)
+		if err != nil {
+			importError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, preview)
+	}
+}
+
+// POST /api/imports/:id/commit
+func (h *ImportHandler) Commit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body struct {
+			IncludeDuplicates bool `json:"include_duplicates"`
+			Categorize        bool `json:"categorize"`
+			BatchSize         int  `json:"batch_size"`
+		}
+		// body is optional
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+				return
+			}
+		}
+		job, err := h.Imports.Commit(c.GetInt("userID"), id, services.ImportCommitOptions{
+			IncludeDuplicates: body.IncludeDuplicates,
+			Categorize:        body.Categorize,
+			BatchSize:         body.BatchSize,
+		})
+		if err != nil {
+			if job != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "job": job})
+				return
+			}
+			importError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"job": job})
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IncomeHandler struct {
+	Incomes *services.IncomeService
+}
+
+func NewIncomeHandler(is *services.IncomeService) *IncomeHandler {
+	return &IncomeHandler{Incomes: is}
+}
+
+type incomeReq struct {
+	Title      string       `json:"title"`
+	Amount     models.Money `json:"amount" binding:"required"`
+	Kind       string       `json:"kind"` // salary | transfer | refund | gift | interest | other
+	Source     string       `json:"source"`
+	ReceivedAt *time.Time   `json:"received_at"`
+}
+
+func incomeError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrIncomeNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// GET /api/incomes?from_date=&to_date=&include_unconfirmed=
+func (h *IncomeHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pf, err := filterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		items, err := h.Incomes.List(pf)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		totals, err := h.Incomes.Sum(pf)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"incomes": items, "totals": totals})
+	}
+}
+
+// POST /api/incomes
+func (h *IncomeHandler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body incomeReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		in := models.Income{
+			UserID:     c.GetInt("userID"),
+			Title:      body.Title,
+			Amount:     body.Amount,
+			Kind:       body.Kind,
+			Source:     body.Source,
+			ReceivedAt: body.ReceivedAt,
+		}
+		if err := h.Incomes.Create(&in); err != nil {
+			incomeError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"income": in})
+	}
+}
+
+// POST /api/incomes/:id/confirm  and  /api/incomes/:id/reject
+func (h *IncomeHandler) SetStatus(status string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		in, err := h.Incomes.SetStatus(c.GetInt("userID"), id, status)
+		if err != nil {
+			incomeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"income": in})
+	}
+}
+
+// DELETE /api/incomes/:id
+func (h *IncomeHandler) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		if err := h.Incomes.Delete(c.GetInt("userID"), id); err != nil {
+			incomeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "income deleted"})
+	}
+}
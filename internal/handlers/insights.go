@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InsightsHandler serves the computed analytics under /api/insights.
+type InsightsHandler struct {
+	Anomalies *services.AnomalyService
+	Forecasts *services.ForecastService
+	Emotions  *services.EmotionService
+	Incomes   *services.IncomeService // optional, for cash flow
+	Prices    *services.PriceService  // optional, for price tracking
+}
+
+func NewInsightsHandler(as *services.AnomalyService, fs *services.ForecastService, es *services.EmotionService) *InsightsHandler {
+	return &InsightsHandler{Anomalies: as, Forecasts: fs, Emotions: es}
+}
+
+// insightRange is the filter's date range, defaulting to the last `days` days.
+func insightRange(pf models.PurchaseFilter, days int) (time.Time, time.Time) {
+	to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if pf.ToDate != nil {
+		to = *pf.ToDate
+	}
+	from := to.AddDate(0, 0, -days)
+	if pf.FromDate != nil {
+		from = *pf.FromDate
+	}
+	return from, to
+}
+
+// scanAnomalies runs the anomaly scan for every user of the filter.
+func scanAnomalies(as *services.AnomalyService, pf models.PurchaseFilter) ([]models.AnomalyFlag, error) {
+	from, to := insightRange(pf, 30)
+	var out []models.AnomalyFlag
+	for _, uid := range pf.UserIDs {
+		flags, err := as.Scan(uid, from, to)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, flags...)
+	}
+	return out, nil
+}
+
+// GET /api/insights/anomalies?from_date=&to_date=  (default: last 30 days)
+func (h *InsightsHandler) AnomalyList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pf, err := filterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		flags, err := scanAnomalies(h.Anomalies, pf)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"anomalies": flags, "total": len(flags)})
+	}
+}
+
+// POST /api/insights/anomalies/:id/dismiss
+func (h *InsightsHandler) DismissAnomaly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		if err := h.Anomalies.Dismiss(c.GetInt("userID"), id); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, services.ErrAnomalyNotFound) {
+				status = http.StatusNotFound
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "anomaly dismissed"})
+	}
+}
+
+// GET /api/insights/forecast?period=monthly|jalali_monthly|weekly&category=...
+func (h *InsightsHandler) Forecast() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, err := h.Forecasts.Forecast(c.GetInt("userID"), c.DefaultQuery("period", "monthly"), time.Now().UTC(), c.QueryArray("category"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"forecast": f})
+	}
+}
+
+// GET /api/insights/emotions?period=monthly&from_date=&to_date=&category=...
+func (h *InsightsHandler) EmotionReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pf, err := filterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		report, err := h.Emotions.Report(pf, c.DefaultQuery("period", "monthly"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"emotions": report})
+	}
+}
+
+// GET /api/insights/cash-flow?period=monthly&from_date=&to_date=  (default: last 6 periods)
+func (h *InsightsHandler) CashFlow() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.Incomes == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "incomes are not enabled"})
+			return
+		}
+		pf, err := filterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// cash flow is about all spending
+		pf.Categories, pf.MinAmount, pf.MaxAmount = nil, nil, nil
+		flow, err := h.Incomes.CashFlow(pf, c.DefaultQuery("period", "monthly"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"cash_flow": flow})
+	}
+}
+
+// GET /api/insights/prices?period=monthly&item=&vendor=&from_date=&to_date=  (default: last 12 periods)
+func (h *InsightsHandler) PriceList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.Prices == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "price tracking is not enabled"})
+			return
+		}
+		pf, err := filterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		prices, err := h.Prices.Prices(pf, c.DefaultQuery("period", "monthly"), c.Query("item"), c.Query("vendor"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"prices": prices})
+	}
+}
+
+// GET /api/insights/inflation?period=monthly&from_date=&to_date=  (default: last 12 periods)
+func (h *InsightsHandler) Inflation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.Prices == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "price tracking is not enabled"})
+			return
+		}
+		pf, err := filterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		report, err := h.Prices.Inflation(pf, c.DefaultQuery("period", "monthly"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"inflation": report})
+	}
+}
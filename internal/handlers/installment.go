@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/services"
+	"example/AI/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InstallmentHandler struct {
+	Installments *services.InstallmentService
+}
+
+func NewInstallmentHandler(is *services.InstallmentService) *InstallmentHandler {
+	return &InstallmentHandler{Installments: is}
+}
+
+type installmentReq struct {
+	Title       string       `json:"title" binding:"required"`
+	Total       models.Money `json:"total" binding:"required"`
+	DownPayment models.Money `json:"down_payment"`
+	Count       int          `json:"count" binding:"required"`
+	Rule        string       `json:"rule"`         // default monthly
+	PurchasedAt string       `json:"purchased_at"` // default today
+	FirstDue    string       `json:"first_due"`    // default a month after the purchase
+	Category    string       `json:"category"`
+	Subcategory string       `json:"subcategory"`
+	Vendor      string       `json:"vendor"`
+	AccountID   *uint64      `json:"account_id"`
+	AutoPay     bool         `json:"auto_pay"`
+}
+
+type payInstallmentReq struct {
+	Seq  int    `json:"seq"`  // 0 = the next unpaid one
+	Date string `json:"date"` // default now
+}
+
+func installmentError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrPlanNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// GET /api/installments?all=true
+func (h *InstallmentHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := h.Installments.List(c.GetInt("userID"), c.Query("all") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"installments": items})
+	}
+}
+
+// GET /api/installments/debt
+func (h *InstallmentHandler) Debt() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		debt, err := h.Installments.Debt(c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, debt)
+	}
+}
+
+// GET /api/installments/:id
+func (h *InstallmentHandler) Get() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c)
+		if !ok {
+			return
+		}
+		st, err := h.Installments.Status(c.GetInt("userID"), id)
+		if err != nil {
+			installmentError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, st)
+	}
+}
+
+// POST /api/installments
+func (h *InstallmentHandler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body installmentReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		plan := models.InstallmentPlan{
+			UserID:      c.GetInt("userID"),
+			Title:       body.Title,
+			Total:       body.Total,
+			DownPayment: body.DownPayment,
+			Count:       body.Count,
+			Rule:        body.Rule,
+			Category:    body.Category,
+			Subcategory: body.Subcategory,
+			AccountID:   body.AccountID,
+			AutoPay:     body.AutoPay,
+		}
+		if body.Vendor != "" {
+			plan.Vendor = &body.Vendor
+		}
+		for raw, dst := range map[string]*time.Time{body.PurchasedAt: &plan.PurchasedAt, body.FirstDue: &plan.FirstDue} {
+			if raw == "" {
+				continue
+			}
+			d, err := utils.ParseDate(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date " + raw})
+				return
+			}
+			*dst = d
+		}
+		st, err := h.Installments.Create(&plan)
+		if err != nil {
+			installmentError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, st)
+	}
+}
+
+// POST /api/installments/:id/pay
+func (h *InstallmentHandler) Pay() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c)
+		if !ok {
+			return
+		}
+		var body payInstallmentReq
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+				return
+			}
+		}
+		var at *time.Time
+		if body.Date != "" {
+			d, err := utils.ParseDate(body.Date)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+				return
+			}
+			at = &d
+		}
+		st, err := h.Installments.Pay(c.GetInt("userID"), id, body.Seq, at)
+		if err != nil {
+			installmentError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, st)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LedgerHandler struct {
+	Ledger *services.LedgerService
+}
+
+func NewLedgerHandler(ls *services.LedgerService) *LedgerHandler {
+	return &LedgerHandler{Ledger: ls}
+}
+
+// POST /api/imports/ledger (multipart "file", or the journal as the raw body)
+func (h *LedgerHandler) Import() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var r io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize)
+		if fh, err := c.FormFile("file"); err == nil {
+			f, err := fh.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			defer f.Close()
+			r = io.LimitReader(f, maxImportFileSize)
+		}
+		res, err := h.Ledger.Import(c.GetInt("userID"), r)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"result": res})
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"example/AI/internal/models"
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PurchaseHandler struct {
+	Purchase *services.PurchaseService
+	Refunds  *services.RefundService // optional, refunds in the details
+}
+
+func NewPurchaseHandler(ps *services.PurchaseService) *PurchaseHandler {
+	return &PurchaseHandler{Purchase: ps}
+}
+
+type correctPurchaseReq struct {
+	Category    *string `json:"category"`
+	Subcategory *string `json:"subcategory"`
+	Vendor      *string `json:"vendor"`
+	AccountID   *uint64 `json:"account_id"` // 0 = no account
+	Remember    bool    `json:"remember"`   // create a rule for this vendor
+}
+
+type purchaseItemsReq struct {
+	Items []models.PurchaseItem `json:"items"`
+}
+
+func purchaseID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+func purchaseError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrPurchaseNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// PATCH /api/purchases/:id
+func (h *PurchaseHandler) Correct() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := purchaseID(c)
+		if !ok {
+			return
+		}
+		var body correctPurchaseReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		p, corrections, err := h.Purchase.Correct(c.GetInt("userID"), id, services.PurchaseCorrection{
+			Category:    body.Category,
+			Subcategory: body.Subcategory,
+			Vendor:      body.Vendor,
+			AccountID:   body.AccountID,
+			Remember:    body.Remember,
+		})
+		if err != nil {
+			purchaseError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"purchase": p, "corrections": corrections})
+	}
+}
+
+// GET /api/purchases/:id  (with its items, refunds and the net amount)
+func (h *PurchaseHandler) Details() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := purchaseID(c)
+		if !ok {
+			return
+		}
+		userID := c.GetInt("userID")
+		p, err := h.Purchase.Get(userID, id)
+		if err != nil {
+			purchaseError(c, err)
+			return
+		}
+		items, err := h.Purchase.Items(userID, id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		res := gin.H{
+			"purchase": p,
+			"items":    items,
+			"refunded": p.Refunded(),
+			"net":      p.Net(),
+		}
+		if h.Refunds != nil {
+			refunds, err := h.Refunds.List(userID, id)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			res["refunds"] = refunds
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// PUT /api/purchases/:id/items  (replaces all items; [] removes them)
+func (h *PurchaseHandler) SetItems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := purchaseID(c)
+		if !ok {
+			return
+		}
+		var body purchaseItemsReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		items, err := h.Purchase.SetItems(c.GetInt("userID"), id, body.Items)
+		if errors.Is(err, services.ErrPurchaseNotFound) {
+			purchaseError(c, err)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"items": items})
+	}
+}
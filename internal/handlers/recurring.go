@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RecurringHandler struct {
+	Recurring *services.RecurringService
+}
+
+func NewRecurringHandler(rs *services.RecurringService) *RecurringHandler {
+	return &RecurringHandler{Recurring: rs}
+}
+
+func recurringError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrSeriesNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// GET /api/recurring?status=proposed|active|dismissed
+func (h *RecurringHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := h.Recurring.List(c.GetInt("userID"), c.Query("status"))
+		if err != nil {
+			recurringError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"series": items})
+	}
+}
+
+// POST /api/recurring/detect
+func (h *RecurringHandler) Detect() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := h.Recurring.Detect(c.GetInt("userID"))
+		if err != nil {
+			recurringError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"series": items, "total": len(items)})
+	}
+}
+
+// POST /api/recurring/:id/confirm  and  /api/recurring/:id/dismiss
+func (h *RecurringHandler) SetStatus(confirm bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		action := h.Recurring.Dismiss
+		if confirm {
+			action = h.Recurring.Confirm
+		}
+		series, err := action(c.GetInt("userID"), id)
+		if err != nil {
+			recurringError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"series": series})
+	}
+}
+
+// GET /api/recurring/upcoming?days=30
+func (h *RecurringHandler) Upcoming() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+		if err != nil || days <= 0 || days > 366 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 366"})
+			return
+		}
+		from := time.Now().UTC().Truncate(24 * time.Hour)
+		items, err := h.Recurring.Upcoming(c.GetInt("userID"), from, from.AddDate(0, 0, days))
+		if err != nil {
+			recurringError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"upcoming": items})
+	}
+}
+
+// GET /api/subscriptions
+func (h *RecurringHandler) Subscriptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, totals, err := h.Recurring.Subscriptions(c.GetInt("userID"))
+		if err != nil {
+			recurringError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"subscriptions": items, "monthly_total": totals})
+	}
+}
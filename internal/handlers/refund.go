@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"example/AI/internal/models"
+	"example/AI/internal/services"
+	"example/AI/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RefundHandler struct {
+	Refunds *services.RefundService
+}
+
+func NewRefundHandler(rs *services.RefundService) *RefundHandler {
+	return &RefundHandler{Refunds: rs}
+}
+
+type refundReq struct {
+	Amount models.Money `json:"amount"` // empty = what is left of the purchase
+	Date   string       `json:"date"`   // default now
+	Reason string       `json:"reason"`
+}
+
+func refundError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrPurchaseNotFound) || errors.Is(err, services.ErrRefundNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// POST /api/purchases/:id/refunds
+func (h *RefundHandler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := purchaseID(c)
+		if !ok {
+			return
+		}
+		var body refundReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		r := models.Refund{
+			UserID:     c.GetInt("userID"),
+			PurchaseID: id,
+			Amount:     body.Amount,
+			Reason:     body.Reason,
+		}
+		if body.Date != "" {
+			d, err := utils.ParseDate(body.Date)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+				return
+			}
+			r.RefundedAt = &d
+		}
+		if err := h.Refunds.Create(&r); err != nil {
+			refundError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"refund": r})
+	}
+}
+
+// GET /api/refunds?purchase_id=
+func (h *RefundHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		purchaseID, _ := strconv.ParseUint(c.Query("purchase_id"), 10, 64)
+		items, err := h.Refunds.List(c.GetInt("userID"), purchaseID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"refunds": items})
+	}
+}
+
+// DELETE /api/refunds/:id
+func (h *RefundHandler) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c)
+		if !ok {
+			return
+		}
+		if err := h.Refunds.Delete(c.GetInt("userID"), id); err != nil {
+			refundError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "refund deleted"})
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+
+	"example/AI/internal/models"
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewHandler struct {
+	Review *services.ReviewService
+}
+
+func NewReviewHandler(rs *services.ReviewService) *ReviewHandler {
+	return &ReviewHandler{Review: rs}
+}
+
+type reviewItemReq struct {
+	ID          uint64        `json:"id" binding:"required"`
+	Action      string        `json:"action" binding:"required"` // approve | reject | edit
+	Title       *string       `json:"title"`
+	Amount      *models.Money `json:"amount"`
+	Category    *string       `json:"category"`
+	Subcategory *string       `json:"subcategory"`
+	Vendor      *string       `json:"vendor"`
+}
+
+type reviewReq struct {
+	Items []reviewItemReq `json:"items" binding:"required,min=1,dive"`
+}
+
+// GET /api/review
+func (h *ReviewHandler) Queue() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := h.Review.Pending(c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"purchases": items, "total": len(items)})
+	}
+}
+
+// POST /api/review  {"items": [{"id": 1, "action": "approve", "category": "food"}, ...]}
+func (h *ReviewHandler) Apply() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body reviewReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		items := make([]services.ReviewItem, 0, len(body.Items))
+		for _, it := range body.Items {
+			items = append(items, services.ReviewItem{
+				ID:     it.ID,
+				Action: it.Action,
+				Edit: services.PurchaseCorrection{
+					Title:       it.Title,
+					Amount:      it.Amount,
+					Category:    it.Category,
+					Subcategory: it.Subcategory,
+					Vendor:      it.Vendor,
+				},
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{"results": h.Review.Apply(c.GetInt("userID"), items)})
+	}
+}
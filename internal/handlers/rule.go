@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"example/AI/internal/models"
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RuleHandler struct {
+	Corrections *services.CorrectionService
+	Purchase    *services.PurchaseService
+}
+
+func NewRuleHandler(cs *services.CorrectionService, ps *services.PurchaseService) *RuleHandler {
+	return &RuleHandler{Corrections: cs, Purchase: ps}
+}
+
+type createRuleReq struct {
+	Field       string `json:"field" binding:"required"` // vendor | title | text
+	Pattern     string `json:"pattern" binding:"required"`
+	Category    string `json:"category" binding:"required"`
+	Subcategory string `json:"subcategory"`
+}
+
+// GET /api/rules
+func (h *RuleHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := h.Corrections.ListRules(c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"rules": rules})
+	}
+}
+
+// POST /api/rules
+func (h *RuleHandler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body createRuleReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		userID := c.GetInt("userID")
+
+		rule := models.UserRule{
+			UserID:      userID,
+			Field:       body.Field,
+			Pattern:     body.Pattern,
+			Category:    body.Category,
+			Subcategory: body.Subcategory,
+		}
+		if h.Purchase.Taxonomy != nil {
+			cat, sub, err := h.Purchase.Taxonomy.Resolve(userID, body.Category, body.Subcategory)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			rule.Category, rule.Subcategory = cat, sub
+		}
+		if body.Field == models.RuleFieldVendor && h.Purchase.Vendors != nil {
+			if v, err := h.Purchase.Vendors.Match(userID, body.Pattern); err == nil {
+				rule.VendorID = &v.ID
+			}
+		}
+
+		if err := h.Corrections.CreateRule(&rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"rule": rule})
+	}
+}
+
+// DELETE /api/rules/:id
+func (h *RuleHandler) Delete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		if err := h.Corrections.DeleteRule(c.GetInt("userID"), id); err != nil {
+			if errors.Is(err, services.ErrRuleNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "rule deleted"})
+	}
+}
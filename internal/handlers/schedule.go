@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/services"
+	"example/AI/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleHandler struct {
+	Schedules *services.ScheduleService
+}
+
+func NewScheduleHandler(ss *services.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{Schedules: ss}
+}
+
+type scheduleReq struct {
+	Title       string       `json:"title" binding:"required"`
+	Amount      models.Money `json:"amount" binding:"required"`
+	Category    string       `json:"category"`
+	Subcategory string       `json:"subcategory"`
+	Vendor      *string      `json:"vendor"`
+	Rule        string       `json:"rule" binding:"required"` // e.g. FREQ=JMONTHLY;BYMONTHDAY=1
+	StartDate   string       `json:"start_date"`              // YYYY-MM-DD or Jalali YYYY/MM/DD
+	EndDate     string       `json:"end_date"`
+}
+
+type scheduleDateReq struct {
+	Date string `json:"date"`
+}
+
+func scheduleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrScheduleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOccurrenceExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+func scheduleID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// optionalDate reads {"date": "..."} from the body; a missing body or date means nil.
+func optionalDate(c *gin.Context) (*time.Time, bool) {
+	var body scheduleDateReq
+	_ = c.ShouldBindJSON(&body)
+	if body.Date == "" {
+		return nil, true
+	}
+	d, err := utils.ParseDate(body.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &d, true
+}
+
+// GET /api/scheduled
+func (h *ScheduleHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := h.Schedules.List(c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"scheduled": items})
+	}
+}
+
+// POST /api/scheduled
+func (h *ScheduleHandler) Create() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body scheduleReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		sp := models.ScheduledPurchase{
+			UserID:      c.GetInt("userID"),
+			Title:       body.Title,
+			Amount:      body.Amount,
+			Category:    body.Category,
+			Subcategory: body.Subcategory,
+			Vendor:      body.Vendor,
+			Rule:        body.Rule,
+		}
+		if body.StartDate != "" {
+			d, err := utils.ParseDate(body.StartDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			sp.StartDate = d
+		}
+		if body.EndDate != "" {
+			d, err := utils.ParseDate(body.EndDate)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			sp.EndDate = &d
+		}
+		created, err := h.Schedules.Create(&sp)
+		if err != nil {
+			scheduleError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"scheduled": sp, "created_purchases": created})
+	}
+}
+
+// GET /api/scheduled/:id/occurrences
+func (h *ScheduleHandler) Occurrences() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := scheduleID(c)
+		if !ok {
+			return
+		}
+		items, err := h.Schedules.Occurrences(c.GetInt("userID"), id)
+		if err != nil {
+			scheduleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"occurrences": items})
+	}
+}
+
+// POST /api/scheduled/:id/pause
+func (h *ScheduleHandler) Pause() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := scheduleID(c)
+		if !ok {
+			return
+		}
+		sp, err := h.Schedules.Pause(c.GetInt("userID"), id)
+		if err != nil {
+			scheduleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"scheduled": sp})
+	}
+}
+
+// POST /api/scheduled/:id/resume
+func (h *ScheduleHandler) Resume() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := scheduleID(c)
+		if !ok {
+			return
+		}
+		sp, created, err := h.Schedules.Resume(c.GetInt("userID"), id)
+		if err != nil {
+			scheduleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"scheduled": sp, "created_purchases": created})
+	}
+}
+
+// POST /api/scheduled/:id/skip  {"date": "YYYY-MM-DD"} (default: next run)
+func (h *ScheduleHandler) Skip() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := scheduleID(c)
+		if !ok {
+			return
+		}
+		date, ok := optionalDate(c)
+		if !ok {
+			return
+		}
+		occ, err := h.Schedules.Skip(c.GetInt("userID"), id, date)
+		if err != nil {
+			scheduleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"occurrence": occ})
+	}
+}
+
+// POST /api/scheduled/:id/end  {"date": "YYYY-MM-DD"} (default: today)
+func (h *ScheduleHandler) End() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := scheduleID(c)
+		if !ok {
+			return
+		}
+		date, ok := optionalDate(c)
+		if !ok {
+			return
+		}
+		sp, err := h.Schedules.End(c.GetInt("userID"), id, date)
+		if err != nil {
+			scheduleError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"scheduled": sp})
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatementHandler reconciles imported bank statements with the logged purchases.
+type StatementHandler struct {
+	Statements *services.StatementService
+}
+
+func NewStatementHandler(ss *services.StatementService) *StatementHandler {
+	return &StatementHandler{Statements: ss}
+}
+
+func statementError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrImportNotFound), errors.Is(err, services.ErrStatementLineNotFound),
+		errors.Is(err, services.ErrPurchaseNotFound), errors.Is(err, services.ErrAccountNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+func pathID(c *gin.Context) (uint64, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// POST /api/imports/:id/reconcile {"account_id": 3}
+// reads an uploaded import as a bank statement instead of committing it
+func (h *StatementHandler) FromImport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c)
+		if !ok {
+			return
+		}
+		var body struct {
+			AccountID *uint64 `json:"account_id"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+				return
+			}
+		}
+		rep, err := h.Statements.FromImport(c.GetInt("userID"), id, body.AccountID)
+		if err != nil {
+			statementError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rep)
+	}
+}
+
+// GET /api/imports/:id/reconciliation
+func (h *StatementHandler) Report() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c)
+		if !ok {
+			return
+		}
+		rep, err := h.Statements.Report(c.GetInt("userID"), id)
+		if err != nil {
+			statementError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rep)
+	}
+}
+
+// POST /api/imports/:id/reconciliation/auto-match
+func (h *StatementHandler) AutoMatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c)
+		if !ok {
+			return
+		}
+		userID := c.GetInt("userID")
+		n, err := h.Statements.AutoMatch(userID, id)
+		if err != nil {
+			statementError(c, err)
+			return
+		}
+		rep, err := h.Statements.Report(userID, id)
+		if err != nil {
+			statementError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"new_matches": n, "report": rep})
+	}
+}
+
+// POST /api/statement-lines/:id/match {"purchase_id": 12}
+func (h *StatementHandler) Match() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c)
+		if !ok {
+			return
+		}
+		var body struct {
+			PurchaseID uint64 `json:"purchase_id" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "purchase_id is required"})
+			return
+		}
+		line, err := h.Statements.Match(c.GetInt("userID"), id, body.PurchaseID)
+		if err != nil {
+			statementError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"line": line})
+	}
+}
+
+// DELETE /api/statement-lines/:id/match
+func (h *StatementHandler) Unmatch() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c)
+		if !ok {
+			return
+		}
+		line, err := h.Statements.Unmatch(c.GetInt("userID"), id)
+		if err != nil {
+			statementError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"line": line})
+	}
+}
+
+// POST /api/statement-lines/:id/ignore
+func (h *StatementHandler) Ignore() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c)
+		if !ok {
+			return
+		}
+		line, err := h.Statements.Ignore(c.GetInt("userID"), id)
+		if err != nil {
+			statementError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"line": line})
+	}
+}
+
+// POST /api/statement-lines/:id/purchase  (the user never logged it)
+func (h *StatementHandler) CreatePurchase() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := pathID(c)
+		if !ok {
+			return
+		}
+		p, err := h.Statements.CreatePurchase(c.GetInt("userID"), id)
+		if err != nil {
+			statementError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"purchase": p})
+	}
+}
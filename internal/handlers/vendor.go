@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"example/AI/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VendorHandler struct {
+	Vendors  *services.VendorService
+	Purchase *services.PurchaseService
+}
+
+func NewVendorHandler(v *services.VendorService, ps *services.PurchaseService) *VendorHandler {
+	return &VendorHandler{Vendors: v, Purchase: ps}
+}
+
+type vendorAliasReq struct {
+	Alias string `json:"alias" binding:"required"`
+}
+
+type vendorUpdateReq struct {
+	DefaultCategory    string `json:"default_category"`
+	DefaultSubcategory string `json:"default_subcategory"`
+}
+
+type mergeVendorsReq struct {
+	FromID uint64 `json:"from_id" binding:"required"`
+	IntoID uint64 `json:"into_id" binding:"required"`
+}
+
+func vendorError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrVendorNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// GET /api/vendors
+func (h *VendorHandler) List() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		vendors, err := h.Vendors.List(c.GetInt("userID"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"vendors": vendors})
+	}
+}
+
+// POST /api/vendors/:id/aliases
+func (h *VendorHandler) AddAlias() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body vendorAliasReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		alias, err := h.Vendors.AddAlias(c.GetInt("userID"), id, body.Alias, isAdmin(c))
+		if err != nil {
+			vendorError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"alias": alias})
+	}
+}
+
+// PUT /api/vendors/:id
+func (h *VendorHandler) Update() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		var body vendorUpdateReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		userID := c.GetInt("userID")
+		cat, sub := body.DefaultCategory, body.DefaultSubcategory
+		if cat != "" && h.Purchase.Taxonomy != nil {
+			if cat, sub, err = h.Purchase.Taxonomy.Resolve(userID, cat, sub); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		v, err := h.Vendors.SetDefaultCategory(userID, id, cat, sub, isAdmin(c))
+		if err != nil {
+			vendorError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"vendor": v})
+	}
+}
+
+// POST /api/vendors/merge
+func (h *VendorHandler) Merge() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body mergeVendorsReq
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		moved, err := h.Vendors.Merge(c.GetInt("userID"), body.FromID, body.IntoID, isAdmin(c))
+		if err != nil {
+			vendorError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "vendors merged", "purchases_updated": moved})
+	}
+}
+
+// GET /api/vendors/report
+func (h *VendorHandler) Report() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pf, err := filterFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		_ = h.Purchase.CanonicalizeFilter(c.GetInt("userID"), &pf)
+
+		rows, err := h.Vendors.Report(pf)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"vendors": rows})
+	}
+}
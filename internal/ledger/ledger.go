@@ -0,0 +1,269 @@
+// Package ledger converts purchases to and from plain-text accounting journals
+// (beancount and hledger).
+//
+// A purchase becomes a two-posting transaction: the expense account derived from
+// its category ("food"/"fast-food" -> Expenses:Food:Fast-Food) and a funding
+// account. The purchase ID is kept as metadata (beancount) or a tag (hledger):
+//
+//	2024-05-01 * "Snapp" "taxi to work"
+//	  purchase-id: "123"
+//	  Expenses:Transport:Taxi  250000 IRR
+//	  Assets:Cash
+//
+// A refund reverses (part of) its purchase:
+//
+//	2024-05-03 * "Snapp" "refund: taxi to work"
+//	  purchase-id: "123"
+//	  refund-id: "7"
+//	  Expenses:Transport:Taxi  -250000 IRR
+//	  Assets:Cash
+package ledger
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+)
+
+const (
+	Beancount = "beancount"
+	Hledger   = "hledger"
+)
+
+const (
+	ExpensesRoot    = "Expenses"
+	uncategorized   = "Uncategorized"
+	DefaultFunding  = "Assets:Cash"
+	PurchaseIDKey   = "purchase-id"
+	RefundIDKey     = "refund-id"
+	FlagCleared     = "*"
+	FlagPending     = "!"
+	defaultCurrency = "IRR"
+)
+
+type Posting struct {
+	Account string
+	Amount  *models.Money // nil = elided (balances the transaction)
+}
+
+type Transaction struct {
+	Date      time.Time
+	Flag      string // * cleared | ! pending
+	Payee     string
+	Narration string
+	Meta      map[string]string // beancount metadata / hledger tags
+	Postings  []Posting
+	Line      int // line of the header in the parsed file
+}
+
+// AccountFor maps a category/subcategory key to an expense account.
+func AccountFor(category, subcategory string) string {
+	if category == "" {
+		return ExpensesRoot + ":" + uncategorized
+	}
+	acc := ExpensesRoot + ":" + component(category)
+	if subcategory != "" {
+		acc += ":" + component(subcategory)
+	}
+	return acc
+}
+
+// component capitalizes every dash separated part: "fast-food" -> "Fast-Food".
+func component(key string) string {
+	parts := strings.Split(key, "-")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(strings.Fields(strings.Join(parts, "-")), "-")
+}
+
+// CategoryOf is the inverse of AccountFor; ok is false for non-expense accounts.
+func CategoryOf(account string) (category, subcategory string, ok bool) {
+	parts := strings.Split(account, ":")
+	if len(parts) < 2 || !strings.EqualFold(parts[0], ExpensesRoot) {
+		return "", "", false
+	}
+	if strings.EqualFold(parts[1], uncategorized) {
+		return "", "", true
+	}
+	category = strings.ToLower(parts[1])
+	if len(parts) > 2 {
+		subcategory = strings.ToLower(parts[2])
+	}
+	return category, subcategory, true
+}
+
+// FromPurchase builds the journal transaction of a purchase.
+func FromPurchase(p *models.Purchase, funding string) Transaction {
+	if funding == "" {
+		funding = DefaultFunding
+	}
+	t := Transaction{
+		Flag:      FlagCleared,
+		Narration: p.Title,
+		Meta:      map[string]string{PurchaseIDKey: strconv.FormatUint(p.ID, 10)},
+	}
+	if p.PurchaseTime != nil {
+		t.Date = *p.PurchaseTime
+	} else {
+		t.Date = p.CreatedAt
+	}
+	if p.Status != models.StatusConfirmed {
+		t.Flag = FlagPending
+	}
+	if p.Vendor != nil && *p.Vendor != "" {
+		t.Payee = *p.Vendor
+	}
+	amount := p.Amount
+	t.Postings = []Posting{
+		{Account: AccountFor(p.Category, p.Subcategory), Amount: &amount},
+		{Account: funding},
+	}
+	return t
+}
+
+// FromRefund builds the transaction reversing a refunded part of the purchase. Importing it
+// changes nothing: an expense posting with a negative amount is not a purchase.
+func FromRefund(r *models.Refund, p *models.Purchase, funding string) Transaction {
+	t := FromPurchase(p, funding)
+	t.Flag = FlagCleared
+	t.Narration = "refund: " + p.Title
+	if r.Reason != "" {
+		t.Narration += " (" + r.Reason + ")"
+	}
+	t.Meta[RefundIDKey] = strconv.FormatUint(r.ID, 10)
+	if r.RefundedAt != nil {
+		t.Date = *r.RefundedAt
+	} else {
+		t.Date = r.CreatedAt
+	}
+	amount := models.NewMoney(-r.Amount.Minor, r.Amount.Currency)
+	t.Postings[0].Amount = &amount
+	return t
+}
+
+// Write prints transactions in the given dialect.
+func Write(w io.Writer, dialect string, txs ...Transaction) error {
+	for _, t := range txs {
+		var err error
+		switch dialect {
+		case Beancount:
+			err = writeBeancount(w, t)
+		case Hledger:
+			err = writeHledger(w, t)
+		default:
+			return fmt.Errorf("unknown ledger dialect %q", dialect)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ").Replace(s) + `"`
+}
+
+func amountString(m *models.Money) string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func writeBeancount(w io.Writer, t Transaction) error {
+	var b strings.Builder
+	b.WriteString(t.Date.Format("2006-01-02") + " " + t.Flag)
+	if t.Payee != "" {
+		b.WriteString(" " + quote(t.Payee))
+	}
+	b.WriteString(" " + quote(t.Narration) + "\n")
+	for _, k := range sortedKeys(t.Meta) {
+		b.WriteString("  " + k + ": " + quote(t.Meta[k]) + "\n")
+	}
+	for _, p := range t.Postings {
+		b.WriteString("  " + p.Account)
+		if p.Amount != nil {
+			b.WriteString("  " + amountString(p.Amount))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeHledger(w io.Writer, t Transaction) error {
+	var b strings.Builder
+	b.WriteString(t.Date.Format("2006-01-02") + " " + t.Flag + " ")
+	desc := strings.ReplaceAll(t.Narration, "|", "/")
+	if t.Payee != "" {
+		desc = strings.ReplaceAll(t.Payee, "|", "/") + " | " + desc
+	}
+	b.WriteString(strings.ReplaceAll(desc, ";", ","))
+	if len(t.Meta) > 0 {
+		var tags []string
+		for _, k := range sortedKeys(t.Meta) {
+			tags = append(tags, k+":"+strings.NewReplacer(",", " ", "\n", " ").Replace(t.Meta[k]))
+		}
+		b.WriteString("  ; " + strings.Join(tags, ", "))
+	}
+	b.WriteString("\n")
+	for _, p := range t.Postings {
+		b.WriteString("    " + p.Account)
+		if p.Amount != nil {
+			b.WriteString("    " + amountString(p.Amount))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Expense returns the expense posting of a transaction with its (positive) amount.
+// An elided expense amount is taken from the other postings.
+func (t Transaction) Expense() (account string, amount models.Money, err error) {
+	var other *models.Money
+	found := -1
+	for i, p := range t.Postings {
+		if _, _, ok := CategoryOf(p.Account); ok && found < 0 {
+			found = i
+			continue
+		}
+		if p.Amount != nil && other == nil {
+			m := *p.Amount
+			other = &m
+		}
+	}
+	if found < 0 {
+		return "", models.Money{}, fmt.Errorf("line %d: no %s posting", t.Line, ExpensesRoot)
+	}
+	p := t.Postings[found]
+	switch {
+	case p.Amount != nil:
+		amount = *p.Amount
+	case other != nil:
+		amount = models.NewMoney(-other.Minor, other.Currency)
+	default:
+		return "", models.Money{}, fmt.Errorf("line %d: no amount", t.Line)
+	}
+	if amount.Minor <= 0 {
+		return "", models.Money{}, fmt.Errorf("line %d: expense amount must be positive", t.Line)
+	}
+	return p.Account, amount, nil
+}
@@ -0,0 +1,277 @@
+package ledger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+)
+
+var (
+	reHeader  = regexp.MustCompile(`^(\d{4}[-/.]\d{1,2}[-/.]\d{1,2})(?:=\S+)?\s*(.*)$`)
+	reMeta    = regexp.MustCompile(`^\s+([a-z][a-zA-Z0-9_-]*):(?:\s+(.*))?$`)
+	reTag     = regexp.MustCompile(`([^\s,:]+):([^,]*)`)
+	reNumber  = regexp.MustCompile(`^[-+]?[\d,]*\.?\d+$`)
+	reComment = regexp.MustCompile(`^\s*[;#%*]`)
+)
+
+// Parse reads a beancount or hledger journal. Only transactions are returned; directives
+// (open, option, commodity, prices, ...) are ignored. Errors are per transaction, so one bad
+// entry does not stop the import.
+func Parse(r io.Reader) ([]Transaction, []error) {
+	var (
+		txs  []Transaction
+		errs []error
+		cur  *Transaction
+		bad  bool
+	)
+	flush := func() {
+		if cur != nil && !bad {
+			txs = append(txs, *cur)
+		}
+		cur, bad = nil, false
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimRight(sc.Text(), " \t\r")
+		if strings.TrimSpace(text) == "" {
+			flush()
+			continue
+		}
+		indented := text[0] == ' ' || text[0] == '\t'
+
+		if !indented {
+			flush()
+			if reComment.MatchString(text) {
+				continue
+			}
+			m := reHeader.FindStringSubmatch(text)
+			if m == nil {
+				continue // directive of another kind
+			}
+			t, err := parseHeader(m[1], m[2])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+				cur, bad = &Transaction{}, true
+				continue
+			}
+			if t == nil {
+				continue // "open", "balance", "price", ... directives
+			}
+			t.Line = line
+			cur = t
+			continue
+		}
+
+		if cur == nil || bad {
+			continue
+		}
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, ";") {
+			addTags(cur, trimmed[1:]) // hledger tags on their own line
+			continue
+		}
+		if m := reMeta.FindStringSubmatch(text); m != nil {
+			cur.Meta[m[1]] = unquote(strings.TrimSpace(m[2]))
+			continue
+		}
+		p, err := parsePosting(trimmed)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", line, err))
+			bad = true
+			continue
+		}
+		cur.Postings = append(cur.Postings, p)
+	}
+	flush()
+	if err := sc.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return txs, errs
+}
+
+func parseHeader(date, rest string) (*Transaction, error) {
+	d, err := time.Parse("2006-01-02", strings.NewReplacer("/", "-", ".", "-").Replace(normalizeDate(date)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", date)
+	}
+	t := &Transaction{Date: d.UTC(), Meta: map[string]string{}, Flag: FlagCleared}
+
+	// hledger comment / tags after ";"
+	if i := strings.Index(rest, ";"); i >= 0 && !inQuotes(rest, i) {
+		addTags(t, rest[i+1:])
+		rest = rest[:i]
+	}
+	rest = strings.TrimSpace(rest)
+
+	switch {
+	case strings.HasPrefix(rest, "txn"):
+		rest = strings.TrimSpace(rest[3:])
+	case strings.HasPrefix(rest, "*"), strings.HasPrefix(rest, "!"):
+		t.Flag = rest[:1]
+		rest = strings.TrimSpace(rest[1:])
+	default:
+		word := strings.Fields(rest + " ")
+		if len(word) > 0 && !strings.HasPrefix(rest, `"`) && isDirective(word[0]) {
+			return nil, nil
+		}
+	}
+	// hledger (code)
+	if strings.HasPrefix(rest, "(") {
+		if i := strings.Index(rest, ")"); i > 0 {
+			rest = strings.TrimSpace(rest[i+1:])
+		}
+	}
+
+	if strings.HasPrefix(rest, `"`) {
+		// beancount: "payee" "narration" | "narration"
+		strs := quotedStrings(rest)
+		switch len(strs) {
+		case 0:
+		case 1:
+			t.Narration = strs[0]
+		default:
+			t.Payee, t.Narration = strs[0], strs[1]
+		}
+	} else if payee, note, ok := strings.Cut(rest, "|"); ok {
+		t.Payee, t.Narration = strings.TrimSpace(payee), strings.TrimSpace(note)
+	} else {
+		t.Narration = rest
+	}
+	return t, nil
+}
+
+func normalizeDate(d string) string {
+	parts := strings.FieldsFunc(d, func(r rune) bool { return r == '-' || r == '/' || r == '.' })
+	if len(parts) != 3 {
+		return d
+	}
+	for i := 1; i < 3; i++ {
+		if len(parts[i]) == 1 {
+			parts[i] = "0" + parts[i]
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+func isDirective(w string) bool {
+	switch w {
+	case "open", "close", "commodity", "balance", "pad", "note", "document", "price", "event", "query", "custom":
+		return true
+	}
+	return false
+}
+
+func inQuotes(s string, pos int) bool {
+	return strings.Count(s[:pos], `"`)%2 == 1
+}
+
+func quotedStrings(s string) []string {
+	var out []string
+	var b strings.Builder
+	in, esc := false, false
+	for _, r := range s {
+		switch {
+		case esc:
+			b.WriteRune(r)
+			esc = false
+		case r == '\\' && in:
+			esc = true
+		case r == '"':
+			if in {
+				out = append(out, b.String())
+				b.Reset()
+			}
+			in = !in
+		case in:
+			b.WriteRune(r)
+		}
+	}
+	return out
+}
+
+func unquote(s string) string {
+	if strs := quotedStrings(s); len(strs) > 0 && strings.HasPrefix(s, `"`) {
+		return strs[0]
+	}
+	return s
+}
+
+func addTags(t *Transaction, comment string) {
+	for _, m := range reTag.FindAllStringSubmatch(comment, -1) {
+		t.Meta[strings.TrimSpace(m[1])] = strings.TrimSpace(m[2])
+	}
+}
+
+// parsePosting reads "Expenses:Food  250,000 IRR", "Assets:Cash  IRR -250000" or a bare account.
+func parsePosting(s string) (Posting, error) {
+	if i := strings.Index(s, ";"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	// hledger allows single spaces inside account names; the amount follows 2+ spaces or a tab
+	account, amount := s, ""
+	if i := strings.Index(s, "  "); i >= 0 {
+		account, amount = s[:i], s[i:]
+	} else if i := strings.Index(s, "\t"); i >= 0 {
+		account, amount = s[:i], s[i:]
+	} else if f := strings.Fields(s); len(f) > 1 && strings.Contains(f[0], ":") {
+		account, amount = f[0], strings.Join(f[1:], " ")
+	}
+	p := Posting{Account: strings.TrimSpace(account)}
+	if strings.HasPrefix(p.Account, "(") || strings.HasPrefix(p.Account, "[") {
+		p.Account = strings.Trim(p.Account, "()[]") // hledger virtual postings
+	}
+	amount = strings.TrimSpace(amount)
+	// drop costs/prices ("@ 1.2 USD", "{...}") and balance assertions ("= 100 IRR")
+	for _, sep := range []string{"@", "{", "="} {
+		if i := strings.Index(amount, sep); i >= 0 {
+			amount = strings.TrimSpace(amount[:i])
+		}
+	}
+	if amount == "" {
+		return p, nil
+	}
+
+	num, cur := "", ""
+	for _, f := range strings.Fields(amount) {
+		if reNumber.MatchString(f) && num == "" {
+			num = f
+		} else {
+			cur = f
+		}
+	}
+	if num == "" {
+		// "$12.50" / "-$12.50" style
+		sym := strings.Trim(amount, "-+0123456789.,")
+		if sym != "" && sym != amount {
+			num = strings.Replace(amount, sym, "", 1)
+			cur = sym
+		}
+	}
+	if num == "" {
+		return p, fmt.Errorf("invalid amount %q", amount)
+	}
+	if cur == "" {
+		cur = defaultCurrency
+	}
+	switch cur {
+	case "$":
+		cur = "USD"
+	case "€":
+		cur = "EUR"
+	}
+	m, err := models.ParseMoney(num, cur)
+	if err != nil {
+		return p, err
+	}
+	p.Amount = &m
+	return p, nil
+}
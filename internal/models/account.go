@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// Account is where money is paid from / received into: cash, a bank card, a digital wallet, ...
+type Account struct {
+	ID             uint64    `gorm:"primaryKey" json:"id"`
+	UserID         int       `gorm:"index;not null" json:"user_id"`
+	Name           string    `gorm:"size:100;not null" json:"name"` // "کارت ملت"
+	Type           string    `gorm:"size:20;not null" json:"type"`
+	Bank           string    `gorm:"size:50" json:"bank"`   // bank key as in bank SMS (mellat, saman, ...)
+	Number         string    `gorm:"size:40" json:"number"` // card/account number, the last digits are enough
+	Currency       string    `gorm:"size:3;not null" json:"currency"`
+	OpeningBalance Money     `gorm:"embedded;embeddedPrefix:opening_" json:"opening_balance"`
+	OpenedAt       time.Time `json:"opened_at"`  // the opening balance is the balance at this time
+	IsDefault      bool      `json:"is_default"` // used when a purchase doesn't name an account
+	Archived       bool      `json:"archived"`
+
+	// last reconciliation against a statement
+	ReconciledAt      *time.Time `json:"reconciled_at"`
+	ReconciledBalance Money      `gorm:"embedded;embeddedPrefix:reconciled_" json:"reconciled_balance"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const (
+	AccountCash   = "cash"
+	AccountCard   = "card" // bank card / account
+	AccountWallet = "wallet"
+	AccountCredit = "credit"
+)
+
+var AccountTypes = []string{AccountCash, AccountCard, AccountWallet, AccountCredit}
+
+// Transfer moves money between two accounts of the same user (not income, not spending).
+type Transfer struct {
+	ID            uint64    `gorm:"primaryKey" json:"id"`
+	UserID        int       `gorm:"index;not null" json:"user_id"`
+	FromAccountID uint64    `gorm:"index;not null" json:"from_account_id"`
+	ToAccountID   uint64    `gorm:"index;not null" json:"to_account_id"`
+	Amount        Money     `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Fee           Money     `gorm:"embedded;embeddedPrefix:fee_" json:"fee"` // paid by the source account
+	At            time.Time `gorm:"column:transfer_time;index" json:"at"`
+	Note          string    `gorm:"size:200" json:"note"`
+	SourceText    string    `json:"source_text"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Reconciliation records one check of an account against a statement balance.
+type Reconciliation struct {
+	ID         uint64    `gorm:"primaryKey" json:"id"`
+	UserID     int       `gorm:"index;not null" json:"user_id"`
+	AccountID  uint64    `gorm:"index;not null" json:"account_id"`
+	At         time.Time `gorm:"column:statement_time" json:"at"`
+	Statement  Money     `gorm:"embedded;embeddedPrefix:statement_" json:"statement"`
+	Computed   Money     `gorm:"embedded;embeddedPrefix:computed_" json:"computed"`
+	Difference Money     `gorm:"embedded;embeddedPrefix:difference_" json:"difference"` // statement - computed
+	// the difference was booked as a balance adjustment
+	Adjusted  bool      `json:"adjusted"`
+	CreatedAt time.Time `json:"created_at"`
+}
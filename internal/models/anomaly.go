@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// AnomalyFlag marks an unusual purchase or an unusual period (a day, or a week of one category)
+// compared with the user's own baseline.
+type AnomalyFlag struct {
+	ID          uint64    `gorm:"primaryKey" json:"id"`
+	UserID      int       `gorm:"index;not null" json:"user_id"`
+	Kind        string    `gorm:"size:20;not null" json:"kind"` // purchase | day | week
+	PurchaseID  *uint64   `gorm:"index" json:"purchase_id"`
+	Category    string    `json:"category"`     // "" for day flags
+	PeriodStart time.Time `json:"period_start"` // day of the purchase, the day, or the week start
+	Value       Money     `gorm:"embedded;embeddedPrefix:value_" json:"value"`
+	Baseline    Money     `gorm:"embedded;embeddedPrefix:baseline_" json:"baseline"` // median of the history
+	Score       float64   `json:"score"`                                             // robust z-score
+	Reason      string    `json:"reason"`
+	Dismissed   bool      `json:"dismissed"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+const (
+	AnomalyPurchase = "purchase"
+	AnomalyDay      = "day"
+	AnomalyWeek     = "week"
+)
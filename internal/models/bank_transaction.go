@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// BankTransaction is one parsed bank SMS. The balance (مانده) is kept for reconciliation.
+type BankTransaction struct {
+	ID         uint64     `gorm:"primaryKey" json:"id"`
+	UserID     int        `gorm:"uniqueIndex:idx_bank_tx_user_hash;not null" json:"user_id"`
+	Hash       string     `gorm:"size:40;uniqueIndex:idx_bank_tx_user_hash" json:"-"` // sha1 of the normalized text
+	Bank       string     `gorm:"size:30" json:"bank"`
+	Account    string     `gorm:"size:40;index" json:"account"`
+	Kind       string     `gorm:"size:10" json:"kind"` // debit | credit
+	Amount     Money      `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	HasBalance bool       `json:"has_balance"`
+	Balance    Money      `gorm:"embedded;embeddedPrefix:balance_" json:"balance"`
+	Time       *time.Time `gorm:"column:sms_time" json:"time"`
+	Text       string     `json:"text"`
+	PurchaseID *uint64    `gorm:"index" json:"purchase_id"`
+	IncomeID   *uint64    `gorm:"index" json:"income_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Budget is a spending limit per user, per category ("" = overall) and per period.
+type Budget struct {
+	ID          uint64    `gorm:"primaryKey" json:"id"`
+	UserID      int       `gorm:"index;not null" json:"user_id"`
+	Category    string    `gorm:"size:100" json:"category"` // "" = all spending
+	Period      string    `gorm:"size:20;not null" json:"period"`
+	Limit       Money     `gorm:"embedded;embeddedPrefix:limit_" json:"limit"`
+	Rollover    string    `gorm:"size:10;not null;default:none" json:"rollover"` // none | surplus | full
+	WarnPercent int       `gorm:"not null;default:80" json:"warn_percent"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+const (
+	RolloverNone    = "none"
+	RolloverSurplus = "surplus" // unspent money carries over
+	RolloverFull    = "full"    // unspent money and overspending carry over
+)
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Category is a node of the canonical taxonomy. Top-level nodes are categories,
+// their children are subcategories. UserID == nil means a global (managed) entry,
+// otherwise it's a user's custom category. Purchases store the Key.
+type Category struct {
+	ID        uint64            `gorm:"primaryKey" json:"id"`
+	Key       string            `gorm:"size:100;not null;index" json:"key"`
+	ParentID  *uint64           `gorm:"index" json:"parent_id"`
+	UserID    *int              `gorm:"index" json:"user_id"`
+	CreatedAt time.Time         `json:"created_at"`
+	Labels    []CategoryLabel   `gorm:"foreignKey:CategoryID" json:"labels,omitempty"`
+	Synonyms  []CategorySynonym `gorm:"foreignKey:CategoryID" json:"synonyms,omitempty"`
+	Children  []Category        `gorm:"-" json:"children,omitempty"`
+}
+
+// CategoryLabel = نام نمایشی هر دسته به هر زبان
+type CategoryLabel struct {
+	ID         uint64 `gorm:"primaryKey" json:"id"`
+	CategoryID uint64 `gorm:"index;not null" json:"category_id"`
+	Lang       string `gorm:"size:10;not null" json:"lang"` // "fa", "en"
+	Label      string `gorm:"size:100;not null" json:"label"`
+}
+
+// CategorySynonym maps free text (already normalized) to a category.
+// UserID == nil -> applies to everyone, otherwise only to that user.
+type CategorySynonym struct {
+	ID         uint64 `gorm:"primaryKey" json:"id"`
+	CategoryID uint64 `gorm:"index;not null" json:"category_id"`
+	UserID     *int   `gorm:"index" json:"user_id"`
+	Text       string `gorm:"size:100;not null;index" json:"text"`
+}
+
+// OtherCategory is the fallback when the model output can't be mapped.
+const OtherCategory = "other"
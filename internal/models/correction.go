@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Correction = هر بار کاربر خروجی مدل را اصلاح می‌کند یک ردیف ثبت می‌شود
+type Correction struct {
+	ID             uint64    `gorm:"primaryKey" json:"id"`
+	UserID         int       `gorm:"index;not null" json:"user_id"`
+	PurchaseID     uint64    `gorm:"index" json:"purchase_id"`
+	Field          string    `gorm:"size:30;not null" json:"field"` // category, subcategory, vendor
+	OriginalValue  string    `gorm:"size:150" json:"original_value"`
+	CorrectedValue string    `gorm:"size:150" json:"corrected_value"`
+	InputText      string    `json:"input_text"` // the message the purchase came from
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// UserRule deterministically overrides the model's category before a purchase is saved,
+// e.g. vendor "Snapp" -> transport/taxi.
+type UserRule struct {
+	ID          uint64    `gorm:"primaryKey" json:"id"`
+	UserID      int       `gorm:"index;not null" json:"user_id"`
+	Field       string    `gorm:"size:20;not null" json:"field"` // vendor | title | text
+	Pattern     string    `gorm:"size:150;not null" json:"pattern"`
+	VendorID    *uint64   `json:"vendor_id,omitempty"`
+	Category    string    `gorm:"size:100;not null" json:"category"`
+	Subcategory string    `gorm:"size:100" json:"subcategory"`
+	Hits        int       `json:"hits"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+const (
+	RuleFieldVendor = "vendor"
+	RuleFieldTitle  = "title"
+	RuleFieldText   = "text"
+)
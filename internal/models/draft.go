@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PurchaseDraft keeps a partially extracted purchase while we ask the user a follow-up
+// question (e.g. the amount). One active draft per user.
+type PurchaseDraft struct {
+	ID        uint64    `gorm:"primaryKey" json:"id"`
+	UserID    int       `gorm:"index;not null" json:"user_id"`
+	Data      string    `json:"-"`                        // extracted fields as JSON
+	Missing   string    `gorm:"size:100" json:"missing"`  // comma separated: title,amount
+	Question  string    `gorm:"size:300" json:"question"` // what we asked the user
+	InputText string    `json:"input_text"`               // original message(s)
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
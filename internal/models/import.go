@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// ImportJob is one uploaded CSV/XLSX file; its rows stay in preview until committed.
+type ImportJob struct {
+	ID         uint64    `gorm:"primaryKey" json:"id"`
+	UserID     int       `gorm:"index;not null" json:"user_id"`
+	FileName   string    `gorm:"size:255" json:"file_name"`
+	Format     string    `gorm:"size:10" json:"format"` // csv | xlsx
+	Status     string    `gorm:"size:20;not null" json:"status"`
+	Headers    string    `json:"-"`                      // JSON array
+	Mapping    string    `json:"-"`                      // JSON object field -> column index
+	Currency   string    `gorm:"size:3" json:"currency"` // default when the file has no currency column
+	Total      int       `json:"total"`
+	Valid      int       `json:"valid"`
+	Invalid    int       `json:"invalid"`
+	Duplicates int       `json:"duplicates"`
+	Imported   int       `json:"imported"`
+	Error      string    `json:"error,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+const (
+	ImportPreview   = "preview"
+	ImportCommitted = "committed"
+	ImportFailed    = "failed"
+	// read as a bank statement and matched against existing purchases instead of committed
+	ImportReconciled = "reconciled"
+)
+
+// ImportRow is one parsed line of an import with its validation result.
+type ImportRow struct {
+	ID           uint64     `gorm:"primaryKey" json:"id"`
+	JobID        uint64     `gorm:"index;not null" json:"job_id"`
+	RowNum       int        `json:"row"` // 1-based line in the file, header = 1
+	Raw          string     `json:"-"`   // JSON array of the cells
+	Title        string     `json:"title"`
+	Amount       Money      `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Category     string     `json:"category"`
+	Subcategory  string     `json:"subcategory"`
+	Vendor       *string    `json:"vendor"`
+	PurchaseTime *time.Time `json:"purchase_time"`
+	Status       string     `gorm:"size:20;index;not null" json:"status"`
+	Errors       string     `json:"errors,omitempty"` // "; " separated
+	DuplicateOf  *uint64    `json:"duplicate_of"`     // existing purchase
+	PurchaseID   *uint64    `json:"purchase_id"`      // created on commit
+}
+
+const (
+	RowValid     = "valid"
+	RowInvalid   = "invalid"
+	RowDuplicate = "duplicate"
+	RowImported  = "imported"
+)
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Income is money coming in: salary, transfers from others, refunds, ...
+type Income struct {
+	ID         uint64     `gorm:"primaryKey" json:"id"`
+	UserID     int        `gorm:"index;not null" json:"user_id"`
+	Title      string     `json:"title"`
+	Amount     Money      `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Kind       string     `gorm:"size:20;not null" json:"kind"`
+	Source     string     `gorm:"size:200" json:"source"` // employer, payer, ...
+	ReceivedAt *time.Time `gorm:"index" json:"received_at"`
+	Status     string     `gorm:"size:20;not null" json:"status"` // confirmed | guessed
+	AccountID  *uint64    `gorm:"index" json:"account_id"`        // account it was paid into
+	SourceText string     `json:"source_text"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+const (
+	IncomeSalary   = "salary"
+	IncomeTransfer = "transfer"
+	IncomeRefund   = "refund"
+	IncomeGift     = "gift"
+	IncomeInterest = "interest"
+	IncomeOther    = "other"
+)
+
+var IncomeKinds = []string{IncomeSalary, IncomeTransfer, IncomeRefund, IncomeGift, IncomeInterest, IncomeOther}
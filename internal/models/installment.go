@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// InstallmentPlan is a purchase paid in installments (اقساطی): a down payment, then Count dues
+// on a schedule. The full price is one purchase (Basis accrual) and every paid due is another
+// one (Basis cash); reports pick one of the two views with PurchaseFilter.Basis.
+type InstallmentPlan struct {
+	ID          uint64    `gorm:"primaryKey" json:"id"`
+	UserID      int       `gorm:"index;not null" json:"user_id"`
+	Title       string    `gorm:"size:200;not null" json:"title"`
+	Total       Money     `gorm:"embedded;embeddedPrefix:total_" json:"total"`
+	DownPayment Money     `gorm:"embedded;embeddedPrefix:down_" json:"down_payment"`
+	Count       int       `gorm:"not null" json:"count"`         // number of dues after the down payment
+	Rule        string    `gorm:"size:200;not null" json:"rule"` // schedule of the dues, see package schedule
+	PurchasedAt time.Time `json:"purchased_at"`
+	FirstDue    time.Time `json:"first_due"`
+	Category    string    `json:"category"`
+	Subcategory string    `json:"subcategory"`
+	Vendor      *string   `json:"vendor"`
+	VendorID    *uint64   `json:"vendor_id"`
+	AccountID   *uint64   `json:"account_id"`                           // account the dues are paid from
+	PurchaseID  *uint64   `json:"purchase_id"`                          // the full-price (accrual) purchase
+	AutoPay     bool      `json:"auto_pay"`                             // dues are paid on their date (direct debit, cheques)
+	Status      string    `gorm:"size:20;index;not null" json:"status"` // active | paid
+	SourceText  string    `json:"source_text"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+const (
+	PlanActive = "active"
+	PlanPaid   = "paid"
+)
+
+// InstallmentDue is one payment of a plan; Seq 0 is the down payment.
+type InstallmentDue struct {
+	ID         uint64     `gorm:"primaryKey" json:"id"`
+	PlanID     uint64     `gorm:"uniqueIndex:idx_plan_seq;not null" json:"plan_id"`
+	Seq        int        `gorm:"uniqueIndex:idx_plan_seq;not null" json:"seq"`
+	DueDate    time.Time  `gorm:"index;not null" json:"due_date"`
+	Amount     Money      `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	PaidAt     *time.Time `json:"paid_at"`
+	PurchaseID *uint64    `json:"purchase_id"` // the payment (cash) purchase
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Views of installment purchases (PurchaseFilter.Basis, Purchase.Basis)
+const (
+	BasisAccrual = "accrual" // the full price on the purchase date
+	BasisCash    = "cash"    // the payments on their dates
+)
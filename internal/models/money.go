@@ -0,0 +1,236 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used when the AI (or the user) doesn't give a currency.
+const DefaultCurrency = "IRR"
+
+// currencyExponents = تعداد رقم اعشار واحد خرد هر ارز
+// ریال و تومان در عمل واحد خرد ندارند، پس 0.
+var currencyExponents = map[string]int{
+	"IRR": 0,
+	"IRT": 0,
+	"JPY": 0,
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"AED": 2,
+	"TRY": 2,
+	"CNY": 2,
+}
+
+var currencyAliases = map[string]string{
+	"RIAL":      "IRR",
+	"RIALS":     "IRR",
+	"ریال":      "IRR",
+	"TOMAN":     "IRT",
+	"TOMANS":    "IRT",
+	"تومان":     "IRT",
+	"تومن":      "IRT",
+	"$":         "USD",
+	"DOLLAR":    "USD",
+	"DOLLARS":   "USD",
+	"US DOLLAR": "USD",
+	"دلار":      "USD",
+	"€":         "EUR",
+	"EURO":      "EUR",
+	"EUROS":     "EUR",
+	"یورو":      "EUR",
+	"£":         "GBP",
+	"POUND":     "GBP",
+	"POUNDS":    "GBP",
+	"پوند":      "GBP",
+	"DIRHAM":    "AED",
+	"DIRHAMS":   "AED",
+	"درهم":      "AED",
+	"LIRA":      "TRY",
+	"لیر":       "TRY",
+	"YUAN":      "CNY",
+	"یوان":      "CNY",
+	"YEN":       "JPY",
+	"ین":        "JPY",
+}
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// NormalizeCurrency maps free-form currency names to a currency code.
+func NormalizeCurrency(c string) string {
+	c = strings.ToUpper(strings.Join(strings.Fields(c), " "))
+	if c == "" {
+		return DefaultCurrency
+	}
+	if code, ok := currencyAliases[c]; ok {
+		return code
+	}
+	return c
+}
+
+// ParseCurrency is NormalizeCurrency for input: anything that isn't a supported code
+// ("تومان" -> IRT, "dollar" -> USD) is rejected before it reaches the 3-char column.
+func ParseCurrency(c string) (string, error) {
+	code := NormalizeCurrency(c)
+	if _, ok := currencyExponents[code]; !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownCurrency, c)
+	}
+	return code, nil
+}
+
+// CurrencyExponent returns the number of minor-unit digits for a currency (default 2).
+func CurrencyExponent(currency string) int {
+	if e, ok := currencyExponents[NormalizeCurrency(currency)]; ok {
+		return e
+	}
+	return 2
+}
+
+// Money is an exact amount stored as integer minor units with its currency.
+// In the database it is embedded as two columns (<prefix>minor, <prefix>currency),
+// in JSON it is {"value": "1250000", "currency": "IRR"} with a string-encoded decimal.
+type Money struct {
+	Minor    int64  `gorm:"column:minor;not null;default:0"`
+	Currency string `gorm:"column:currency;size:3;not null;default:'IRR'"`
+}
+
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+func NewMoney(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: NormalizeCurrency(currency)}
+}
+
+// ParseMoney parses a decimal string in major units ("12.50", "1,250,000") exactly.
+func ParseMoney(s, currency string) (Money, error) {
+	currency, err := ParseCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	exp := CurrencyExponent(currency)
+
+	s = strings.TrimSpace(strings.NewReplacer(",", "", "_", "", " ", "", "٬", "").Replace(s))
+	if s == "" {
+		return Money{}, errors.New("empty amount")
+	}
+	neg := false
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" {
+		intPart = "0"
+	}
+	if len(fracPart) > exp {
+		// extra digits are only allowed if they are zeros
+		if strings.Trim(fracPart[exp:], "0") != "" {
+			return Money{}, fmt.Errorf("amount %q has more precision than %s allows", s, currency)
+		}
+		fracPart = fracPart[:exp]
+	}
+	fracPart += strings.Repeat("0", exp-len(fracPart))
+
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return Money{}, fmt.Errorf("invalid amount %q", s)
+		}
+	}
+	minor, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	if neg {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// MoneyFromFloat is only meant for legacy/LLM float inputs; it rounds to the nearest minor unit.
+func MoneyFromFloat(f float64, currency string) (Money, error) {
+	currency, err := ParseCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	scaled := math.Round(f * math.Pow10(CurrencyExponent(currency)))
+	if math.IsNaN(scaled) || math.Abs(scaled) > math.MaxInt64/2 {
+		return Money{}, fmt.Errorf("amount out of range: %v", f)
+	}
+	return Money{Minor: int64(scaled), Currency: currency}, nil
+}
+
+func (m Money) IsZero() bool     { return m.Minor == 0 }
+func (m Money) IsPositive() bool { return m.Minor > 0 }
+
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency && m.Minor != 0 && o.Minor != 0 {
+		return m, ErrCurrencyMismatch
+	}
+	cur := m.Currency
+	if cur == "" || m.Minor == 0 {
+		cur = o.Currency
+	}
+	return Money{Minor: m.Minor + o.Minor, Currency: cur}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(Money{Minor: -o.Minor, Currency: o.Currency})
+}
+
+// Decimal returns the amount in major units, e.g. "12.50" or "1250000".
+func (m Money) Decimal() string {
+	exp := CurrencyExponent(m.Currency)
+	neg := m.Minor < 0
+	v := m.Minor
+	if neg {
+		v = -v
+	}
+	digits := strconv.FormatInt(v, 10)
+	if exp > 0 {
+		if len(digits) <= exp {
+			digits = strings.Repeat("0", exp-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+	}
+	if neg {
+		digits = "-" + digits
+	}
+	return digits
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+type moneyJSON struct {
+	Value    json.Number `json:"value"`
+	Currency string      `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Value    string `json:"value"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.Currency})
+}
+
+// UnmarshalJSON accepts {"value": "12.5", "currency": "USD"} (value may also be a number).
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var v moneyJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	parsed, err := ParseMoney(v.Value.String(), v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
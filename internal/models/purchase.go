@@ -6,11 +6,11 @@ type Purchase struct {
 	ID            uint64     `gorm:"primaryKey" json:"id"`
 	UserID        int        `json:"user_id"`
 	Title         string     `json:"title"`
-	Amount        float64    `json:"amount"`
-	Currency      string     `json:"currency"`
+	Amount        Money      `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
 	Category      string     `json:"category"`
 	Subcategory   string     `json:"subcategory"`
-	Vendor        *string    `json:"vendor"`
+	Vendor        *string    `json:"vendor"` // canonical vendor name
+	VendorID      *uint64    `gorm:"index" json:"vendor_id"`
 	PurchaseTime  *time.Time `json:"purchase_time"`
 	CreatedAt     time.Time  `json:"created_at"`
 	Necessity     string     `json:"necessity"`      // ضروری؟ غیرضروری؟
@@ -18,13 +18,49 @@ type Purchase struct {
 	ReasonGuess   string     `json:"reason_guess"`   // حدس دلیل خرید
 	Confidence    float64    `json:"confidence"`     // اعتماد AI
 	Status        string     `json:"status"`         // مثلا: "confirmed", "guessed"
+	SourceText    string     `json:"source_text"`    // متن پیام کاربر
+
+	// second opinion from the local classifier
+	ClassifierCategory   string  `gorm:"size:100" json:"classifier_category"`
+	ClassifierConfidence float64 `json:"classifier_confidence"`
+	// why a purchase went to review, comma separated (low_confidence, classifier_disagrees, ...)
+	ReviewReasons string `gorm:"size:200" json:"review_reasons"`
+	// recurring series (rent, subscriptions, ...) this purchase belongs to
+	RecurringSeriesID *uint64 `gorm:"index" json:"recurring_series_id"`
+	// possible duplicate of another purchase, until the user confirms or merges it
+	DuplicateOf      *uint64 `gorm:"index" json:"duplicate_of"`
+	DuplicateCleared bool    `json:"-"` // the user said it is not a duplicate
+	// account/wallet it was paid from
+	AccountID *uint64 `gorm:"index" json:"account_id"`
+	// sum of the refunds of this purchase (same currency), see Net
+	RefundedMinor int64 `gorm:"not null;default:0" json:"-"`
+	// installment plan: the full-price purchase (basis accrual) or one of its payments (basis cash);
+	// empty for normal purchases, which count in both views
+	InstallmentPlanID *uint64 `gorm:"index" json:"installment_plan_id,omitempty"`
+	Basis             string  `gorm:"size:10;not null;default:''" json:"basis,omitempty"`
 }
 
+// Refunded is the amount returned so far.
+func (p *Purchase) Refunded() Money { return NewMoney(p.RefundedMinor, p.Amount.Currency) }
+
+// Net is what the purchase really cost after refunds; spending totals use it.
+func (p *Purchase) Net() Money { return NewMoney(p.Amount.Minor-p.RefundedMinor, p.Amount.Currency) }
+
+const (
+	StatusConfirmed = "confirmed"
+	StatusGuessed   = "guessed" // needs review
+	StatusRejected  = "rejected"
+)
+
 type PurchaseFilter struct {
 	UserIDs    []int
 	Categories []string
 	FromDate   *time.Time
 	ToDate     *time.Time
-	MinAmount  *float64
-	MaxAmount  *float64
+	MinAmount  *Money
+	MaxAmount  *Money
+	// false = only confirmed purchases; rejected ones are never included
+	IncludeUnconfirmed bool
+	// installment purchases: accrual (default) counts the full price, cash the payments
+	Basis string
 }
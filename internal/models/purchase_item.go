@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PurchaseItem is one line of a purchase (a supermarket receipt: bread, detergent, chips).
+// When a purchase has items, their categories are used in category totals instead of the
+// purchase's own category.
+type PurchaseItem struct {
+	ID          uint64    `gorm:"primaryKey" json:"id"`
+	PurchaseID  uint64    `gorm:"index;not null" json:"purchase_id"`
+	UserID      int       `gorm:"index;not null" json:"user_id"`
+	Name        string    `gorm:"size:200;not null" json:"name"`
+	Quantity    float64   `json:"quantity"`
+	Unit        string    `gorm:"size:20" json:"unit"` // kg, l, pcs, ...
+	UnitPrice   Money     `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+	Amount      Money     `gorm:"embedded;embeddedPrefix:amount_" json:"amount"` // line total
+	Category    string    `json:"category"`                                      // "" = the purchase's category
+	Subcategory string    `json:"subcategory"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// RecurringSeries is a periodic expense (rent, internet, subscriptions, ...) found in the
+// purchase history. Detected series are "proposed" until the user confirms or dismisses them.
+type RecurringSeries struct {
+	ID          uint64  `gorm:"primaryKey" json:"id"`
+	UserID      int     `gorm:"index;not null" json:"user_id"`
+	Key         string  `gorm:"column:series_key;size:200;not null" json:"key"` // grouping key: vendor id or normalized title
+	Title       string  `gorm:"size:200" json:"title"`
+	Vendor      *string `json:"vendor"`
+	VendorID    *uint64 `json:"vendor_id"`
+	Category    string  `json:"category"`
+	Subcategory string  `json:"subcategory"`
+	// typical (median) charge
+	Amount       Money     `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Cadence      string    `gorm:"size:20;not null" json:"cadence"` // weekly | biweekly | monthly | jalali_monthly | quarterly | yearly
+	IntervalDays float64   `json:"interval_days"`                   // median observed interval
+	Occurrences  int       `json:"occurrences"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+	NextExpected time.Time `json:"next_expected"`
+	Confidence   float64   `json:"confidence"`
+	Status       string    `gorm:"size:20;index;not null" json:"status"` // proposed | active | dismissed
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+const (
+	SeriesProposed  = "proposed"
+	SeriesActive    = "active"
+	SeriesDismissed = "dismissed"
+)
+
+const (
+	CadenceWeekly        = "weekly"
+	CadenceBiweekly      = "biweekly"
+	CadenceMonthly       = "monthly"
+	CadenceJalaliMonthly = "jalali_monthly"
+	CadenceQuarterly     = "quarterly"
+	CadenceYearly        = "yearly"
+)
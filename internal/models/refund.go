@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Refund is money returned for a purchase (a returned item, a cancelled order), full or partial.
+// The purchase keeps its amount; totals use Purchase.Net().
+type Refund struct {
+	ID         uint64     `gorm:"primaryKey" json:"id"`
+	UserID     int        `gorm:"index;not null" json:"user_id"`
+	PurchaseID uint64     `gorm:"index;not null" json:"purchase_id"`
+	Amount     Money      `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	RefundedAt *time.Time `gorm:"index" json:"refunded_at"`
+	Reason     string     `gorm:"size:200" json:"reason"`
+	AccountID  *uint64    `gorm:"index" json:"account_id"` // account the money came back to
+	SourceText string     `json:"source_text"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
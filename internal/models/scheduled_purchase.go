@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// ScheduledPurchase is a user-defined recurring purchase ("rent, 15M, every Jalali month on
+// the 1st") that the scheduler turns into real purchases.
+type ScheduledPurchase struct {
+	ID          uint64     `gorm:"primaryKey" json:"id"`
+	UserID      int        `gorm:"index;not null" json:"user_id"`
+	Title       string     `gorm:"size:200;not null" json:"title"`
+	Amount      Money      `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Category    string     `json:"category"`
+	Subcategory string     `json:"subcategory"`
+	Vendor      *string    `json:"vendor"`
+	VendorID    *uint64    `json:"vendor_id"`
+	Rule        string     `gorm:"size:200;not null" json:"rule"` // RRULE-like, see package schedule
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     *time.Time `json:"end_date"`                             // inclusive
+	Status      string     `gorm:"size:20;index;not null" json:"status"` // active | paused | ended
+	// occurrences before this date are already materialized (or deliberately not, e.g. while paused)
+	MaterializedUntil *time.Time `json:"materialized_until"`
+	NextRun           *time.Time `json:"next_run"`
+	SourceText        string     `json:"source_text"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+const (
+	ScheduleActive = "active"
+	SchedulePaused = "paused"
+	ScheduleEnded  = "ended"
+)
+
+// ScheduledOccurrence records one date of a schedule; the unique (schedule, date) index makes
+// materialization idempotent. Skipped dates are stored without a purchase.
+type ScheduledOccurrence struct {
+	ID                  uint64    `gorm:"primaryKey" json:"id"`
+	ScheduledPurchaseID uint64    `gorm:"uniqueIndex:idx_schedule_date;not null" json:"scheduled_purchase_id"`
+	Date                time.Time `gorm:"uniqueIndex:idx_schedule_date;not null" json:"date"`
+	PurchaseID          *uint64   `json:"purchase_id"`
+	Skipped             bool      `json:"skipped"`
+	CreatedAt           time.Time `json:"created_at"`
+}
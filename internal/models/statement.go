@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// StatementLine is one debit of an imported bank statement, matched against the purchases
+// logged by chat. The statement itself is the ImportJob it was read from.
+type StatementLine struct {
+	ID          uint64     `gorm:"primaryKey" json:"id"`
+	UserID      int        `gorm:"index;not null" json:"user_id"`
+	JobID       uint64     `gorm:"index;not null" json:"job_id"`
+	RowNum      int        `json:"row"`
+	AccountID   *uint64    `gorm:"index" json:"account_id"`
+	Time        *time.Time `gorm:"column:line_time" json:"time"`
+	Amount      Money      `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Description string     `json:"description"`
+	Vendor      *string    `json:"vendor"`
+	Status      string     `gorm:"size:20;index;not null" json:"status"`
+	PurchaseID  *uint64    `gorm:"index" json:"purchase_id"`
+	MatchScore  float64    `json:"match_score"`
+	MatchedBy   string     `gorm:"size:10" json:"matched_by,omitempty"` // auto | manual | created
+
+	// the purchase as it was logged, restored when the match is undone
+	LoggedAmount Money  `gorm:"embedded;embeddedPrefix:logged_" json:"logged_amount"`
+	LoggedStatus string `gorm:"size:20" json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	LineUnmatched = "unmatched"
+	LineMatched   = "matched"
+	LineIgnored   = "ignored" // fees, transfers, ... nothing to match
+
+	MatchAuto    = "auto"
+	MatchManual  = "manual"
+	MatchCreated = "created" // a purchase was created from the line
+)
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Vendor is a canonical merchant. UserID == nil means a global vendor (seeded or
+// admin-managed), otherwise it was created from one user's purchases.
+type Vendor struct {
+	ID                 uint64        `gorm:"primaryKey" json:"id"`
+	Name               string        `gorm:"size:150;not null" json:"name"`
+	UserID             *int          `gorm:"index" json:"user_id"`
+	DefaultCategory    string        `gorm:"size:100" json:"default_category"`
+	DefaultSubcategory string        `gorm:"size:100" json:"default_subcategory"`
+	CreatedAt          time.Time     `json:"created_at"`
+	Aliases            []VendorAlias `gorm:"foreignKey:VendorID" json:"aliases,omitempty"`
+}
+
+// VendorAlias = نام‌های دیگر یک فروشنده ("اسنپ", "snap", ...)
+type VendorAlias struct {
+	ID         uint64 `gorm:"primaryKey" json:"id"`
+	VendorID   uint64 `gorm:"index;not null" json:"vendor_id"`
+	UserID     *int   `gorm:"index" json:"user_id"`
+	Alias      string `gorm:"size:150;not null" json:"alias"`
+	Normalized string `gorm:"size:150;not null;index" json:"-"`
+}
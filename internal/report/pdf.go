@@ -0,0 +1,314 @@
+// Package report renders spending reports (PDF) with right-to-left Persian text.
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"example/AI/internal/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Line is one labelled amount (a category, a day, ...).
+type Line struct {
+	Label   string
+	Amount  models.Money
+	Percent float64
+}
+
+type TopPurchase struct {
+	Date     string
+	Title    string
+	Category string
+	Amount   models.Money
+}
+
+// Monthly is the content of the periodic PDF report. Labels are expected in the
+// report language (Persian when a font is available).
+type Monthly struct {
+	Title      string
+	Period     string // "1403/01/01 - 1403/01/31"
+	Totals     []models.Money
+	Previous   []models.Money // same currencies, previous period
+	Count      int
+	DailyAvg   models.Money
+	Categories []Line // primary currency, largest first
+	Daily      []Line // one per day of the period
+	Top        []TopPurchase
+}
+
+// text of the fixed labels, per language
+var labels = map[string]map[string]string{
+	"fa": {
+		"total": "جمع هزینه‌ها", "count": "تعداد خرید", "avg": "میانگین روزانه", "change": "نسبت به دوره‌ی قبل",
+		"categories": "هزینه به تفکیک دسته", "category": "دسته", "amount": "مبلغ", "percent": "درصد",
+		"daily": "هزینه‌ی روزانه", "top": "بزرگ‌ترین خریدها", "date": "تاریخ", "title": "عنوان",
+		"empty": "در این دوره خریدی ثبت نشده است.",
+	},
+	"en": {
+		"total": "Total spending", "count": "Purchases", "avg": "Daily average", "change": "Change vs previous period",
+		"categories": "Spending by category", "category": "Category", "amount": "Amount", "percent": "%",
+		"daily": "Daily spending", "top": "Largest purchases", "date": "Date", "title": "Title",
+		"empty": "No purchases in this period.",
+	},
+}
+
+var palette = [][3]int{{52, 101, 164}, {78, 154, 6}, {245, 121, 0}, {204, 0, 0}, {117, 80, 123}, {193, 125, 17}, {6, 152, 154}, {136, 138, 133}}
+
+type writer struct {
+	pdf  *fpdf.Fpdf
+	rtl  bool
+	lang string
+	font string
+}
+
+// WriteMonthlyPDF renders the report. fontPath is a TTF with Persian glyphs (e.g. Vazirmatn);
+// without it the report falls back to English labels and a core font.
+func WriteMonthlyPDF(w io.Writer, d *Monthly, fontPath string) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	wr := &writer{pdf: pdf, lang: "en", font: "Helvetica"}
+	if fontPath != "" {
+		ttf, err := os.ReadFile(fontPath)
+		if err != nil {
+			return fmt.Errorf("report font: %w", err)
+		}
+		pdf.AddUTF8FontFromBytes("fa", "", ttf)
+		if pdf.Err() {
+			return fmt.Errorf("report font: %w", pdf.Error())
+		}
+		wr.rtl, wr.lang, wr.font = true, "fa", "fa"
+	}
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.AddPage()
+
+	wr.setFont(16)
+	pdf.CellFormat(0, 10, wr.text(d.Title), "", 1, "C", false, 0, "")
+	wr.setFont(10)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.CellFormat(0, 6, wr.text(d.Period), "", 1, "C", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(4)
+
+	if d.Count == 0 {
+		wr.setFont(12)
+		pdf.CellFormat(0, 10, wr.text(wr.label("empty")), "", 1, wr.align(), false, 0, "")
+		return pdf.Output(w)
+	}
+
+	wr.summary(d)
+	if len(d.Categories) > 0 {
+		wr.heading(wr.label("categories"))
+		wr.categoryBars(d.Categories)
+		wr.table([]string{wr.label("category"), wr.label("amount"), wr.label("percent")}, []float64{90, 60, 30}, func(row func(...string)) {
+			for _, l := range d.Categories {
+				row(l.Label, l.Amount.String(), fmt.Sprintf("%.1f", l.Percent))
+			}
+		})
+	}
+	if len(d.Daily) > 0 {
+		wr.heading(wr.label("daily"))
+		wr.dailyChart(d.Daily)
+	}
+	if len(d.Top) > 0 {
+		wr.heading(wr.label("top"))
+		wr.table([]string{wr.label("date"), wr.label("title"), wr.label("category"), wr.label("amount")}, []float64{28, 72, 40, 40}, func(row func(...string)) {
+			for _, t := range d.Top {
+				row(t.Date, t.Title, t.Category, t.Amount.String())
+			}
+		})
+	}
+	return pdf.Output(w)
+}
+
+func (wr *writer) label(key string) string { return labels[wr.lang][key] }
+
+func (wr *writer) setFont(size float64) { wr.pdf.SetFont(wr.font, "", size) }
+
+func (wr *writer) align() string {
+	if wr.rtl {
+		return "R"
+	}
+	return "L"
+}
+
+// text prepares a string for the current font: visual order for Persian, ASCII for the core font.
+func (wr *writer) text(s string) string {
+	if wr.rtl {
+		return Visual(s)
+	}
+	return strings.Map(func(r rune) rune {
+		if r > 0x7E {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+func (wr *writer) heading(s string) {
+	wr.pdf.Ln(4)
+	wr.setFont(13)
+	wr.pdf.CellFormat(0, 8, wr.text(s), "B", 1, wr.align(), false, 0, "")
+	wr.pdf.Ln(2)
+	wr.setFont(10)
+}
+
+func (wr *writer) summary(d *Monthly) {
+	var totals, change []string
+	for _, t := range d.Totals {
+		totals = append(totals, t.String())
+		for _, p := range d.Previous {
+			if p.Currency == t.Currency && p.Minor > 0 {
+				change = append(change, fmt.Sprintf("%+.1f%%", float64(t.Minor-p.Minor)*100/float64(p.Minor)))
+			}
+		}
+	}
+	rows := [][2]string{
+		{wr.label("total"), strings.Join(totals, " / ")},
+		{wr.label("count"), fmt.Sprint(d.Count)},
+		{wr.label("avg"), d.DailyAvg.String()},
+	}
+	if len(change) > 0 {
+		rows = append(rows, [2]string{wr.label("change"), strings.Join(change, " / ")})
+	}
+	wr.setFont(11)
+	for _, r := range rows {
+		wr.pdf.SetFillColor(242, 242, 242)
+		if wr.rtl {
+			wr.pdf.CellFormat(110, 8, wr.text(r[1]), "", 0, "L", true, 0, "")
+			wr.pdf.CellFormat(70, 8, wr.text(r[0]), "", 1, "R", true, 0, "")
+		} else {
+			wr.pdf.CellFormat(70, 8, wr.text(r[0]), "", 0, "L", true, 0, "")
+			wr.pdf.CellFormat(110, 8, wr.text(r[1]), "", 1, "R", true, 0, "")
+		}
+	}
+	wr.setFont(10)
+}
+
+// table draws a header and rows; columns run right to left in Persian.
+func (wr *writer) table(headers []string, widths []float64, rows func(row func(...string))) {
+	draw := func(cells []string, fill bool) {
+		order := make([]int, len(cells))
+		for i := range order {
+			order[i] = i
+			if wr.rtl {
+				order[i] = len(cells) - 1 - i
+			}
+		}
+		for k, i := range order {
+			ln := 0
+			if k == len(order)-1 {
+				ln = 1
+			}
+			wr.pdf.CellFormat(widths[i], 7, wr.text(fit(wr.pdf, wr.text, cells[i], widths[i]-2)), "1", ln, wr.align(), fill, 0, "")
+		}
+	}
+	wr.pdf.SetFillColor(220, 230, 241)
+	draw(headers, true)
+	rows(func(cells ...string) { draw(cells, false) })
+}
+
+// fit shortens s (logical order) with "..." until it fits in width mm.
+func fit(pdf *fpdf.Fpdf, text func(string) string, s string, width float64) string {
+	if pdf.GetStringWidth(text(s)) <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && pdf.GetStringWidth(text(string(r)+"...")) > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
+
+// categoryBars is a horizontal bar chart of the largest categories.
+func (wr *writer) categoryBars(lines []Line) {
+	if len(lines) > len(palette) {
+		lines = lines[:len(palette)]
+	}
+	var max int64
+	for _, l := range lines {
+		if l.Amount.Minor > max {
+			max = l.Amount.Minor
+		}
+	}
+	if max == 0 {
+		return
+	}
+	const labelW, barMax, barH = 45.0, 120.0, 6.0
+	left, _, right, _ := wr.pdf.GetMargins()
+	pageW, _ := wr.pdf.GetPageSize()
+	for i, l := range lines {
+		y := wr.pdf.GetY()
+		w := barMax * float64(l.Amount.Minor) / float64(max)
+		c := palette[i%len(palette)]
+		wr.pdf.SetFillColor(c[0], c[1], c[2])
+		if wr.rtl {
+			// label on the right, bar growing to the left
+			wr.pdf.SetXY(pageW-right-labelW, y)
+			wr.pdf.CellFormat(labelW, barH, wr.text(fit(wr.pdf, wr.text, l.Label, labelW-2)), "", 0, "R", false, 0, "")
+			x := pageW - right - labelW - 2 - w
+			wr.pdf.Rect(x, y+1, w, barH-2, "F")
+			wr.pdf.SetXY(x-20, y)
+			wr.pdf.CellFormat(19, barH, fmt.Sprintf("%.0f%%", l.Percent), "", 0, "R", false, 0, "")
+		} else {
+			wr.pdf.SetXY(left, y)
+			wr.pdf.CellFormat(labelW, barH, wr.text(fit(wr.pdf, wr.text, l.Label, labelW-2)), "", 0, "L", false, 0, "")
+			x := left + labelW + 2
+			wr.pdf.Rect(x, y+1, w, barH-2, "F")
+			wr.pdf.SetXY(x+w+1, y)
+			wr.pdf.CellFormat(19, barH, fmt.Sprintf("%.0f%%", l.Percent), "", 0, "L", false, 0, "")
+		}
+		wr.pdf.SetXY(left, y+barH)
+	}
+	wr.pdf.Ln(3)
+}
+
+// dailyChart is a column chart of the spending per day, with the max on the axis.
+func (wr *writer) dailyChart(days []Line) {
+	const chartH = 45.0
+	left, _, right, _ := wr.pdf.GetMargins()
+	pageW, pageH := wr.pdf.GetPageSize()
+	if wr.pdf.GetY()+chartH+12 > pageH-15 {
+		wr.pdf.AddPage()
+	}
+	var max int64
+	for _, d := range days {
+		if d.Amount.Minor > max {
+			max = d.Amount.Minor
+		}
+	}
+	top := wr.pdf.GetY() + 4
+	base := top + chartH
+	width := pageW - left - right - 20
+	x0 := left + 20
+	step := width / float64(len(days))
+
+	wr.pdf.SetDrawColor(150, 150, 150)
+	wr.pdf.Line(x0, base, x0+width, base)
+	wr.pdf.Line(x0, top, x0, base)
+	wr.setFont(7)
+	if max > 0 {
+		wr.pdf.SetXY(left, top-2)
+		wr.pdf.CellFormat(19, 4, models.NewMoney(max, days[0].Amount.Currency).Decimal(), "", 0, "R", false, 0, "")
+	}
+	wr.pdf.SetFillColor(palette[0][0], palette[0][1], palette[0][2])
+	labelEvery := int(math.Ceil(float64(len(days)) / 10))
+	for i, d := range days {
+		x := x0 + float64(i)*step
+		if max > 0 && d.Amount.Minor > 0 {
+			h := chartH * float64(d.Amount.Minor) / float64(max)
+			wr.pdf.Rect(x+step*0.15, base-h, step*0.7, h, "F")
+		}
+		if i%labelEvery == 0 {
+			wr.pdf.SetXY(x-2, base+1)
+			wr.pdf.CellFormat(step+4, 4, wr.text(d.Label), "", 0, "C", false, 0, "")
+		}
+	}
+	wr.pdf.SetDrawColor(0, 0, 0)
+	wr.pdf.SetXY(left, base+7)
+	wr.setFont(10)
+}
@@ -0,0 +1,216 @@
+package report
+
+import (
+	"strings"
+	"unicode"
+)
+
+// PDF fonts draw glyphs one by one, left to right: Persian text has to be shaped
+// (contextual letter forms) and put in visual order before it is written.
+
+// forms of a letter: isolated, final, initial, medial (0 = the letter does not join forward)
+var letterForms = map[rune][4]rune{
+	'ء': {0xFE80, 0, 0, 0},
+	'آ': {0xFE81, 0xFE82, 0, 0},
+	'أ': {0xFE83, 0xFE84, 0, 0},
+	'ؤ': {0xFE85, 0xFE86, 0, 0},
+	'إ': {0xFE87, 0xFE88, 0, 0},
+	'ئ': {0xFE89, 0xFE8A, 0xFE8B, 0xFE8C},
+	'ا': {0xFE8D, 0xFE8E, 0, 0},
+	'ب': {0xFE8F, 0xFE90, 0xFE91, 0xFE92},
+	'ة': {0xFE93, 0xFE94, 0, 0},
+	'ت': {0xFE95, 0xFE96, 0xFE97, 0xFE98},
+	'ث': {0xFE99, 0xFE9A, 0xFE9B, 0xFE9C},
+	'ج': {0xFE9D, 0xFE9E, 0xFE9F, 0xFEA0},
+	'ح': {0xFEA1, 0xFEA2, 0xFEA3, 0xFEA4},
+	'خ': {0xFEA5, 0xFEA6, 0xFEA7, 0xFEA8},
+	'د': {0xFEA9, 0xFEAA, 0, 0},
+	'ذ': {0xFEAB, 0xFEAC, 0, 0},
+	'ر': {0xFEAD, 0xFEAE, 0, 0},
+	'ز': {0xFEAF, 0xFEB0, 0, 0},
+	'س': {0xFEB1, 0xFEB2, 0xFEB3, 0xFEB4},
+	'ش': {0xFEB5, 0xFEB6, 0xFEB7, 0xFEB8},
+	'ص': {0xFEB9, 0xFEBA, 0xFEBB, 0xFEBC},
+	'ض': {0xFEBD, 0xFEBE, 0xFEBF, 0xFEC0},
+	'ط': {0xFEC1, 0xFEC2, 0xFEC3, 0xFEC4},
+	'ظ': {0xFEC5, 0xFEC6, 0xFEC7, 0xFEC8},
+	'ع': {0xFEC9, 0xFECA, 0xFECB, 0xFECC},
+	'غ': {0xFECD, 0xFECE, 0xFECF, 0xFED0},
+	'ف': {0xFED1, 0xFED2, 0xFED3, 0xFED4},
+	'ق': {0xFED5, 0xFED6, 0xFED7, 0xFED8},
+	'ك': {0xFED9, 0xFEDA, 0xFEDB, 0xFEDC},
+	'ل': {0xFEDD, 0xFEDE, 0xFEDF, 0xFEE0},
+	'م': {0xFEE1, 0xFEE2, 0xFEE3, 0xFEE4},
+	'ن': {0xFEE5, 0xFEE6, 0xFEE7, 0xFEE8},
+	'ه': {0xFEE9, 0xFEEA, 0xFEEB, 0xFEEC},
+	'و': {0xFEED, 0xFEEE, 0, 0},
+	'ى': {0xFEEF, 0xFEF0, 0, 0},
+	'ي': {0xFEF1, 0xFEF2, 0xFEF3, 0xFEF4},
+	'پ': {0xFB56, 0xFB57, 0xFB58, 0xFB59},
+	'چ': {0xFB7A, 0xFB7B, 0xFB7C, 0xFB7D},
+	'ژ': {0xFB8A, 0xFB8B, 0, 0},
+	'ک': {0xFB8E, 0xFB8F, 0xFB90, 0xFB91},
+	'گ': {0xFB92, 0xFB93, 0xFB94, 0xFB95},
+	'ی': {0xFBFC, 0xFBFD, 0xFBFE, 0xFBFF},
+}
+
+// لا and friends: isolated, final
+var lamAlef = map[rune][2]rune{
+	'ا': {0xFEFB, 0xFEFC},
+	'آ': {0xFEF5, 0xFEF6},
+	'أ': {0xFEF7, 0xFEF8},
+	'إ': {0xFEF9, 0xFEFA},
+}
+
+var mirrored = map[rune]rune{'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{', '<': '>', '>': '<', '«': '»', '»': '«'}
+
+func joinsForward(r rune) bool {
+	f, ok := letterForms[r]
+	return ok && f[2] != 0
+}
+
+func isRTL(r rune) bool {
+	return (r >= 0x0600 && r <= 0x06FF && !(r >= 0x06F0 && r <= 0x06F9) && !(r >= 0x0660 && r <= 0x0669)) ||
+		(r >= 0xFB50 && r <= 0xFDFF) || (r >= 0xFE70 && r <= 0xFEFF)
+}
+
+// shape replaces Persian/Arabic letters by their contextual presentation forms.
+func shape(s string) string {
+	in := []rune(s)
+	// diacritics are dropped: they would break joining and most fonts place them badly
+	letters := in[:0:0]
+	for _, r := range in {
+		if !unicode.Is(unicode.Mn, r) {
+			letters = append(letters, r)
+		}
+	}
+	out := make([]rune, 0, len(letters))
+	for i := 0; i < len(letters); i++ {
+		r := letters[i]
+		forms, ok := letterForms[r]
+		if !ok {
+			if r != '‌' && r != '‍' { // ZWNJ only stops joining
+				out = append(out, r)
+			}
+			continue
+		}
+		joinPrev := i > 0 && joinsForward(letters[i-1])
+		if r == 'ل' && i+1 < len(letters) {
+			if la, ok := lamAlef[letters[i+1]]; ok {
+				if joinPrev {
+					out = append(out, la[1])
+				} else {
+					out = append(out, la[0])
+				}
+				i++
+				continue
+			}
+		}
+		nextIsLetter := false
+		if i+1 < len(letters) {
+			_, nextIsLetter = letterForms[letters[i+1]]
+		}
+		joinNext := forms[2] != 0 && nextIsLetter
+		switch {
+		case joinPrev && joinNext:
+			out = append(out, forms[3])
+		case joinPrev:
+			out = append(out, forms[1])
+		case joinNext:
+			out = append(out, forms[2])
+		default:
+			out = append(out, forms[0])
+		}
+	}
+	return string(out)
+}
+
+// Visual shapes s and reorders it for a right-to-left paragraph: runs of Persian are
+// reversed, runs of Latin text and numbers ("IRR 1,250,000", "2024-05") stay as they are.
+func Visual(s string) string {
+	runes := []rune(shape(s))
+	hasRTL := false
+	for _, r := range runes {
+		if isRTL(r) {
+			hasRTL = true
+			break
+		}
+	}
+	if !hasRTL {
+		return s
+	}
+
+	// strong direction of every rune; neutrals take LTR only between two LTR runes
+	const (
+		neutral = iota
+		ltr
+		rtl
+		weakLTR // neutral between two LTR runes
+	)
+	dir := make([]int, len(runes))
+	for i, r := range runes {
+		switch {
+		case isRTL(r):
+			dir[i] = rtl
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			dir[i] = ltr
+		}
+	}
+	for i := range dir {
+		if dir[i] != neutral {
+			continue
+		}
+		prev, next := rtl, rtl
+		for j := i - 1; j >= 0; j-- {
+			if dir[j] == ltr || dir[j] == rtl {
+				prev = dir[j]
+				break
+			}
+		}
+		for j := i + 1; j < len(dir); j++ {
+			if dir[j] != neutral {
+				next = dir[j]
+				break
+			}
+		}
+		if prev == ltr && next == ltr {
+			dir[i] = weakLTR
+		}
+	}
+
+	var runs []string
+	var cur []rune
+	curLTR := false
+	flush := func() {
+		if len(cur) == 0 {
+			return
+		}
+		if !curLTR {
+			for a, b := 0, len(cur)-1; a < b; a, b = a+1, b-1 {
+				cur[a], cur[b] = cur[b], cur[a]
+			}
+			for k, r := range cur {
+				if m, ok := mirrored[r]; ok {
+					cur[k] = m
+				}
+			}
+		}
+		runs = append(runs, string(cur))
+		cur = nil
+	}
+	for i, r := range runes {
+		isLTR := dir[i] == ltr || dir[i] == weakLTR
+		if len(cur) > 0 && isLTR != curLTR {
+			flush()
+		}
+		curLTR = isLTR
+		cur = append(cur, r)
+	}
+	flush()
+
+	var b strings.Builder
+	for i := len(runs) - 1; i >= 0; i-- {
+		b.WriteString(runs[i])
+	}
+	return b.String()
+}
@@ -1,13 +1,17 @@
 package repository
 
-import "time"
+import (
+	"time"
+
+	"example/AI/internal/models"
+)
 
 type PurchaseFilter struct {
 	UserIDs   []uint
 	Usernames []string
 	Category  []string
-	MinAmount *int
-	MaxAmount *int
+	MinAmount *models.Money
+	MaxAmount *models.Money
 	DateFrom  *time.Time
 	DateTo    *time.Time
 }
@@ -0,0 +1,262 @@
+// Package schedule implements a small RRULE-like recurrence format:
+//
+//	FREQ=DAILY|WEEKLY|MONTHLY|JMONTHLY|YEARLY;INTERVAL=n;BYMONTHDAY=1,15,-1;BYDAY=SA,MO;COUNT=n;UNTIL=YYYY-MM-DD
+//
+// JMONTHLY repeats on Jalali (Solar Hijri) months, BYMONTHDAY then being a Jalali day.
+// Month days past the end of a month fall on its last day; -1 is always the last day.
+// Occurrences are dates (midnight, in the location of the start date).
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"example/AI/internal/utils"
+)
+
+const (
+	Daily    = "DAILY"
+	Weekly   = "WEEKLY"
+	Monthly  = "MONTHLY"
+	JMonthly = "JMONTHLY" // Jalali months
+	Yearly   = "YEARLY"
+)
+
+// safety net for malformed rules / huge ranges
+const maxIterations = 100000
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByMonthDay []int
+	ByDay      []time.Weekday
+	Count      int        // 0 = unlimited
+	Until      *time.Time // inclusive
+}
+
+// Parse reads a rule such as "FREQ=JMONTHLY;BYMONTHDAY=1". An optional "RRULE:" prefix is ignored.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(strings.ToUpper(s)), "RRULE:")
+	if s == "" {
+		return nil, errors.New("empty schedule rule")
+	}
+	r := &Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		switch key {
+		case "FREQ":
+			switch val {
+			case Daily, Weekly, Monthly, JMonthly, Yearly:
+				r.Freq = val
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			r.Interval = n
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(d))
+				if err != nil || n == 0 || n < -1 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				wd, ok := weekdays[strings.TrimSpace(d)]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := time.Parse("20060102", strings.ReplaceAll(val, "-", ""))
+			if err != nil {
+				return nil, fmt.Errorf("invalid UNTIL %q", val)
+			}
+			r.Until = &t
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+	if r.Freq == "" {
+		return nil, errors.New("rule needs FREQ")
+	}
+	if len(r.ByDay) > 0 && r.Freq != Weekly {
+		return nil, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != Monthly && r.Freq != JMonthly {
+		return nil, errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY or JMONTHLY")
+	}
+	return r, nil
+}
+
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			days[i] = strings.ToUpper(wd.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// clampDay turns a BYMONTHDAY value into a day of a month of the given length.
+func clampDay(day, length int) int {
+	if day == -1 || day > length {
+		return length
+	}
+	return day
+}
+
+// candidates returns the dates of the k-th period (sorted).
+func (r *Rule) candidates(start time.Time, k int) []time.Time {
+	step := k * r.Interval
+	loc := start.Location()
+	var out []time.Time
+	switch r.Freq {
+	case Daily:
+		out = append(out, start.AddDate(0, 0, step))
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		// weeks start on Saturday
+		weekStart := start.AddDate(0, 0, -((int(start.Weekday())+1)%7)+7*step)
+		for _, wd := range days {
+			out = append(out, weekStart.AddDate(0, 0, (int(wd)+1)%7))
+		}
+	case Monthly:
+		first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, loc).AddDate(0, step, 0)
+		length := first.AddDate(0, 1, -1).Day()
+		for _, d := range r.monthDays(start.Day()) {
+			out = append(out, first.AddDate(0, 0, clampDay(d, length)-1))
+		}
+	case JMonthly:
+		jy, jm, jd := utils.ToJalali(start)
+		ny, nm := utils.AddJalaliMonths(jy, jm, step)
+		length := utils.JalaliMonthLength(ny, nm)
+		for _, d := range r.monthDays(jd) {
+			if t, err := utils.FromJalali(ny, nm, clampDay(d, length), loc); err == nil {
+				out = append(out, t)
+			}
+		}
+	case Yearly:
+		first := time.Date(start.Year()+step, start.Month(), 1, 0, 0, 0, 0, loc)
+		length := first.AddDate(0, 1, -1).Day()
+		out = append(out, first.AddDate(0, 0, clampDay(start.Day(), length)-1))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+func (r *Rule) monthDays(def int) []int {
+	if len(r.ByMonthDay) > 0 {
+		return r.ByMonthDay
+	}
+	return []int{def}
+}
+
+// Each calls fn for every occurrence on or after start, in order, until fn returns false
+// or the rule ends (COUNT / UNTIL).
+func (r *Rule) Each(start time.Time, fn func(time.Time) bool) {
+	start = dateOf(start)
+	n := 0
+	var last time.Time
+	for k := 0; k < maxIterations; k++ {
+		for _, d := range r.candidates(start, k) {
+			if d.Before(start) || !d.After(last) && n > 0 {
+				continue
+			}
+			if r.Until != nil && d.After(*r.Until) {
+				return
+			}
+			n++
+			last = d
+			if !fn(d) {
+				return
+			}
+			if r.Count > 0 && n >= r.Count {
+				return
+			}
+		}
+	}
+}
+
+// Between returns the occurrences in [from, to).
+func (r *Rule) Between(start, from, to time.Time) []time.Time {
+	var out []time.Time
+	r.Each(start, func(d time.Time) bool {
+		if !d.Before(to) {
+			return false
+		}
+		if !d.Before(from) {
+			out = append(out, d)
+		}
+		return true
+	})
+	return out
+}
+
+// Next returns the first occurrence on or after t (false when the rule has ended).
+func (r *Rule) Next(start, t time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	r.Each(start, func(d time.Time) bool {
+		if d.Before(dateOf(t)) {
+			return true
+		}
+		next, found = d, true
+		return false
+	})
+	return next, found
+}
@@ -0,0 +1,606 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"example/AI/internal/banksms"
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// below this name similarity an account hint doesn't match
+const accountMatchThreshold = 0.75
+
+// AccountService keeps the user's accounts/wallets, transfers between them and their balances.
+type AccountService struct {
+	DB *gorm.DB
+}
+
+func NewAccountService(db *gorm.DB) *AccountService {
+	return &AccountService{DB: db}
+}
+
+var (
+	ErrAccountNotFound  = errors.New("account not found")
+	ErrTransferNotFound = errors.New("transfer not found")
+)
+
+// Entry kinds of an account ledger
+const (
+	EntryOpening     = "opening"
+	EntryPurchase    = "purchase"
+	EntryIncome      = "income"
+	EntryRefund      = "refund"
+	EntryTransferIn  = "transfer_in"
+	EntryTransferOut = "transfer_out"
+	EntryAdjustment  = "adjustment"
+)
+
+// AccountEntry is one movement of an account with the balance right after it.
+type AccountEntry struct {
+	Kind    string       `json:"kind"`
+	RefID   uint64       `json:"ref_id"`
+	Time    time.Time    `json:"time"`
+	Title   string       `json:"title"`
+	Amount  models.Money `json:"amount"` // signed: negative = money out
+	Balance models.Money `json:"balance"`
+}
+
+type AccountWithBalance struct {
+	models.Account
+	Balance models.Money `json:"balance"`
+}
+
+type ReconcileResult struct {
+	Reconciliation models.Reconciliation `json:"reconciliation"`
+	// entries since the previous reconciliation, to find the difference
+	Unreconciled []AccountEntry `json:"unreconciled"`
+}
+
+func validAccountType(t string) bool {
+	for _, k := range models.AccountTypes {
+		if k == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *AccountService) validate(a *models.Account) error {
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" {
+		return errors.New("account name is required")
+	}
+	a.Type = strings.ToLower(strings.TrimSpace(a.Type))
+	if a.Type == "" {
+		a.Type = models.AccountCard
+	}
+	if !validAccountType(a.Type) {
+		return fmt.Errorf("invalid type %q (%s)", a.Type, strings.Join(models.AccountTypes, ", "))
+	}
+	a.Bank = strings.ToLower(strings.TrimSpace(a.Bank))
+	a.Number = utils.NormalizeDigits(strings.TrimSpace(a.Number))
+	if a.Currency == "" {
+		a.Currency = a.OpeningBalance.Currency
+	}
+	cur, err := models.ParseCurrency(a.Currency)
+	if err != nil {
+		return err
+	}
+	a.Currency = cur
+	if a.OpeningBalance.Currency == "" || a.OpeningBalance.Minor == 0 {
+		a.OpeningBalance.Currency = a.Currency
+	}
+	if a.OpeningBalance.Currency != a.Currency {
+		return fmt.Errorf("opening balance is in %s, the account in %s", a.OpeningBalance.Currency, a.Currency)
+	}
+	if a.OpenedAt.IsZero() {
+		a.OpenedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// Create adds an account; the first account of a user becomes the default one.
+func (s *AccountService) Create(a *models.Account) error {
+	if err := s.validate(a); err != nil {
+		return err
+	}
+	var count int64
+	if err := s.DB.Model(&models.Account{}).Where("user_id = ? AND archived = ?", a.UserID, false).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		a.IsDefault = true
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if a.IsDefault {
+			if err := tx.Model(&models.Account{}).Where("user_id = ?", a.UserID).Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(a).Error
+	})
+}
+
+func (s *AccountService) Get(userID int, id uint64) (*models.Account, error) {
+	var a models.Account
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&a).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *AccountService) Update(a *models.Account) error {
+	if err := s.validate(a); err != nil {
+		return err
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if a.IsDefault {
+			if err := tx.Model(&models.Account{}).Where("user_id = ? AND id <> ?", a.UserID, a.ID).Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(a).Error
+	})
+}
+
+func (s *AccountService) List(userID int, archived bool) ([]models.Account, error) {
+	q := s.DB.Where("user_id = ?", userID)
+	if !archived {
+		q = q.Where("archived = ?", false)
+	}
+	var items []models.Account
+	err := q.Order("is_default DESC, name").Find(&items).Error
+	return items, err
+}
+
+// Archive hides the account; its history (and the transfers of other accounts) stays.
+func (s *AccountService) Archive(userID int, id uint64) error {
+	res := s.DB.Model(&models.Account{}).Where("id = ? AND user_id = ?", id, userID).
+		Updates(map[string]interface{}{"archived": true, "is_default": false})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+func (s *AccountService) Default(userID int) (*models.Account, error) {
+	var a models.Account
+	err := s.DB.Where("user_id = ? AND is_default = ? AND archived = ?", userID, true, false).First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+var reDigits = regexp.MustCompile(`\d{4,}`)
+
+var accountTypeWords = map[string][]string{
+	models.AccountCash:   {"نقد", "نقدی", "cash", "پول نقد"},
+	models.AccountWallet: {"کیف پول", "wallet", "دیجی پی", "اسنپ پی"},
+	models.AccountCredit: {"اعتباری", "credit", "اقساطی"},
+}
+
+// Resolve finds the account a phrase refers to ("با کارت ملت", "نقدی", "کارت ...۱۲۳۴").
+// Without a hint it returns the default account; nil when nothing matches.
+func (s *AccountService) Resolve(userID int, hint string) (*models.Account, error) {
+	hint = utils.NormalizeText(utils.NormalizeDigits(hint))
+	if hint == "" {
+		return s.Default(userID)
+	}
+	accounts, err := s.List(userID, false)
+	if err != nil || len(accounts) == 0 {
+		return nil, err
+	}
+
+	// exact name
+	for i := range accounts {
+		if utils.NormalizeText(accounts[i].Name) == hint {
+			return &accounts[i], nil
+		}
+	}
+	// card / account number (last 4 digits)
+	for _, d := range reDigits.FindAllString(hint, -1) {
+		for i := range accounts {
+			if n := accounts[i].Number; len(n) >= 4 && strings.HasSuffix(n, d[len(d)-4:]) {
+				return &accounts[i], nil
+			}
+		}
+	}
+	// bank name
+	if bank := banksms.Detect(hint); bank != "" {
+		for i := range accounts {
+			if accounts[i].Bank == bank {
+				return &accounts[i], nil
+			}
+		}
+	}
+	// best fuzzy name
+	var best *models.Account
+	bestScore := 0.0
+	for i := range accounts {
+		name := utils.NormalizeText(accounts[i].Name)
+		score := utils.Similarity(name, hint)
+		if strings.Contains(hint, name) || strings.Contains(name, hint) {
+			score = 1
+		}
+		if score > bestScore {
+			best, bestScore = &accounts[i], score
+		}
+	}
+	if bestScore >= accountMatchThreshold {
+		return best, nil
+	}
+	// account type ("نقدی", "کیف پول")
+	for typ, words := range accountTypeWords {
+		for _, w := range words {
+			if !strings.Contains(hint, w) {
+				continue
+			}
+			for i := range accounts {
+				if accounts[i].Type == typ {
+					return &accounts[i], nil
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// ForBankSMS finds the account of a bank SMS by bank and the last digits of the account number.
+func (s *AccountService) ForBankSMS(userID int, bank, number string) (*models.Account, error) {
+	if bank == "" {
+		return nil, nil
+	}
+	var accounts []models.Account
+	if err := s.DB.Where("user_id = ? AND bank = ? AND archived = ?", userID, bank, false).Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	digits := strings.Join(reDigits.FindAllString(utils.NormalizeDigits(number), -1), "")
+	if len(digits) >= 4 {
+		for i := range accounts {
+			if n := accounts[i].Number; len(n) >= 4 && strings.HasSuffix(digits, n[len(n)-4:]) {
+				return &accounts[i], nil
+			}
+		}
+	}
+	if len(accounts) == 1 {
+		return &accounts[0], nil
+	}
+	return nil, nil
+}
+
+// PromptContext lists the user's accounts so the model can name the one used.
+func (s *AccountService) PromptContext(userID int, _ string) string {
+	accounts, err := s.List(userID, false)
+	if err != nil || len(accounts) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("USER ACCOUNTS (data.account / income.account / transfer.from_account / transfer.to_account MUST be one of these names, or \"\" if not mentioned):\n")
+	for _, a := range accounts {
+		b.WriteString("- " + a.Name + " (" + a.Type)
+		if a.Bank != "" {
+			b.WriteString(", " + a.Bank)
+		}
+		if a.IsDefault {
+			b.WriteString(", default")
+		}
+		b.WriteString(")\n")
+	}
+	return b.String()
+}
+
+// CreateTransfer moves money between two accounts of the user (same currency).
+func (s *AccountService) CreateTransfer(t *models.Transfer) error {
+	if t.FromAccountID == t.ToAccountID {
+		return errors.New("source and destination accounts are the same")
+	}
+	from, err := s.Get(t.UserID, t.FromAccountID)
+	if err != nil {
+		return err
+	}
+	to, err := s.Get(t.UserID, t.ToAccountID)
+	if err != nil {
+		return err
+	}
+	if from.Currency != to.Currency {
+		return fmt.Errorf("accounts have different currencies (%s, %s)", from.Currency, to.Currency)
+	}
+	if t.Amount.Currency == "" {
+		t.Amount.Currency = from.Currency
+	}
+	if !t.Amount.IsPositive() {
+		return errors.New("transfer amount must be positive")
+	}
+	if t.Amount.Currency != from.Currency {
+		return fmt.Errorf("transfer is in %s, the accounts in %s", t.Amount.Currency, from.Currency)
+	}
+	if t.Fee.Currency == "" || t.Fee.IsZero() {
+		t.Fee = models.NewMoney(t.Fee.Minor, from.Currency)
+	}
+	if t.Fee.Minor < 0 || t.Fee.Currency != from.Currency {
+		return errors.New("invalid transfer fee")
+	}
+	if t.At.IsZero() {
+		t.At = time.Now().UTC()
+	}
+	t.CreatedAt = time.Now().UTC()
+	return s.DB.Create(t).Error
+}
+
+// TransferFromAIData creates a transfer from the model's "transfer" object
+// ({"from_account", "to_account", "amount", "currency", "fee", "date", "note"}).
+func (s *AccountService) TransferFromAIData(userID int, data map[string]interface{}, sourceText string) (*models.Transfer, error) {
+	str := func(k string) string {
+		v, _ := data[k].(string)
+		return strings.TrimSpace(v)
+	}
+	amount, err := toMoney(data["amount"], str("currency"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid transfer amount: %w", err)
+	}
+	ends := [2]*models.Account{}
+	for i, key := range []string{"from_account", "to_account"} {
+		hint := str(key)
+		if hint == "" {
+			return nil, fmt.Errorf("%s is missing", key)
+		}
+		if ends[i], err = s.Resolve(userID, hint); err != nil {
+			return nil, err
+		}
+		if ends[i] == nil {
+			return nil, fmt.Errorf("unknown account %q", hint)
+		}
+	}
+	t := &models.Transfer{
+		UserID:        userID,
+		FromAccountID: ends[0].ID,
+		ToAccountID:   ends[1].ID,
+		Amount:        amount,
+		Note:          str("note"),
+		SourceText:    sourceText,
+	}
+	if v, ok := data["fee"]; ok {
+		if fee, err := toMoney(v, amount.Currency); err == nil {
+			t.Fee = fee
+		}
+	}
+	if raw := str("date"); raw != "" {
+		if d, err := utils.ParseDate(raw); err == nil {
+			t.At = d
+		}
+	}
+	if err := s.CreateTransfer(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *AccountService) Transfers(userID int, limit int) ([]models.Transfer, error) {
+	var items []models.Transfer
+	q := s.DB.Where("user_id = ?", userID).Order("transfer_time DESC, id DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	err := q.Find(&items).Error
+	return items, err
+}
+
+func (s *AccountService) DeleteTransfer(userID int, id uint64) error {
+	res := s.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Transfer{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTransferNotFound
+	}
+	return nil
+}
+
+// entries loads every movement of the account in its currency, oldest first, with running balances.
+// Rejected purchases/incomes and unresolved duplicates don't move money.
+func (s *AccountService) entries(a *models.Account) ([]AccountEntry, error) {
+	out := []AccountEntry{{Kind: EntryOpening, RefID: a.ID, Time: a.OpenedAt, Title: a.Name, Amount: a.OpeningBalance}}
+
+	var purchases []models.Purchase
+	if err := s.DB.Select("id", "title", "amount_minor", "amount_currency", "purchase_time", "created_at").
+		Where("user_id = ? AND account_id = ? AND amount_currency = ? AND status <> ? AND duplicate_of IS NULL AND basis <> ?",
+			a.UserID, a.ID, a.Currency, models.StatusRejected, models.BasisAccrual).
+		Find(&purchases).Error; err != nil {
+		return nil, err
+	}
+	for i := range purchases {
+		p := &purchases[i]
+		out = append(out, AccountEntry{Kind: EntryPurchase, RefID: p.ID, Time: purchaseDate(p), Title: p.Title,
+			Amount: models.NewMoney(-p.Amount.Minor, a.Currency)})
+	}
+
+	var incomes []models.Income
+	if err := s.DB.Where("user_id = ? AND account_id = ? AND amount_currency = ? AND status <> ?",
+		a.UserID, a.ID, a.Currency, models.StatusRejected).Find(&incomes).Error; err != nil {
+		return nil, err
+	}
+	for _, in := range incomes {
+		at := in.CreatedAt
+		if in.ReceivedAt != nil {
+			at = *in.ReceivedAt
+		}
+		out = append(out, AccountEntry{Kind: EntryIncome, RefID: in.ID, Time: at.UTC(), Title: in.Title, Amount: in.Amount})
+	}
+
+	var refunds []models.Refund
+	if err := s.DB.Where("user_id = ? AND account_id = ? AND amount_currency = ?", a.UserID, a.ID, a.Currency).
+		Find(&refunds).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range refunds {
+		at := r.CreatedAt
+		if r.RefundedAt != nil {
+			at = *r.RefundedAt
+		}
+		out = append(out, AccountEntry{Kind: EntryRefund, RefID: r.ID, Time: at.UTC(), Title: r.Reason, Amount: r.Amount})
+	}
+
+	var transfers []models.Transfer
+	if err := s.DB.Where("user_id = ? AND (from_account_id = ? OR to_account_id = ?)", a.UserID, a.ID, a.ID).
+		Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range transfers {
+		if t.ToAccountID == a.ID {
+			out = append(out, AccountEntry{Kind: EntryTransferIn, RefID: t.ID, Time: t.At.UTC(), Title: t.Note, Amount: t.Amount})
+		}
+		if t.FromAccountID == a.ID {
+			out = append(out, AccountEntry{Kind: EntryTransferOut, RefID: t.ID, Time: t.At.UTC(), Title: t.Note,
+				Amount: models.NewMoney(-(t.Amount.Minor + t.Fee.Minor), a.Currency)})
+		}
+	}
+
+	var recs []models.Reconciliation
+	if err := s.DB.Where("account_id = ? AND adjusted = ?", a.ID, true).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range recs {
+		out = append(out, AccountEntry{Kind: EntryAdjustment, RefID: r.ID, Time: r.At.UTC(), Title: "reconciliation", Amount: r.Difference})
+	}
+
+	// the opening balance stays first
+	sort.SliceStable(out[1:], func(i, j int) bool { return out[1+i].Time.Before(out[1+j].Time) })
+	var bal int64
+	for i := range out {
+		bal += out[i].Amount.Minor
+		out[i].Balance = models.NewMoney(bal, a.Currency)
+	}
+	return out, nil
+}
+
+// Ledger returns the account's entries in [from, to] with running balances (nil = open end).
+func (s *AccountService) Ledger(userID int, id uint64, from, to *time.Time) (*models.Account, []AccountEntry, error) {
+	a, err := s.Get(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	all, err := s.entries(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := []AccountEntry{}
+	for _, e := range all {
+		if (from != nil && e.Time.Before(*from)) || (to != nil && e.Time.After(*to)) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return a, out, nil
+}
+
+// balanceAt is the balance after the last entry up to at.
+func balanceAt(a *models.Account, entries []AccountEntry, at time.Time) models.Money {
+	bal := models.NewMoney(0, a.Currency)
+	for _, e := range entries {
+		if e.Time.After(at) {
+			break
+		}
+		bal = e.Balance
+	}
+	return bal
+}
+
+// Balances returns the current balance of every active account of the user.
+func (s *AccountService) Balances(userID int) ([]AccountWithBalance, error) {
+	accounts, err := s.List(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	out := make([]AccountWithBalance, 0, len(accounts))
+	for i := range accounts {
+		entries, err := s.entries(&accounts[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, AccountWithBalance{Account: accounts[i], Balance: balanceAt(&accounts[i], entries, now)})
+	}
+	return out, nil
+}
+
+// Reconcile compares the computed balance at `at` with a statement balance. With adjust, the
+// difference is booked so the balance matches the statement from then on.
+func (s *AccountService) Reconcile(userID int, id uint64, statement models.Money, at time.Time, adjust bool) (*ReconcileResult, error) {
+	a, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if statement.Currency == "" {
+		statement.Currency = a.Currency
+	}
+	if statement.Currency != a.Currency {
+		return nil, fmt.Errorf("statement is in %s, the account in %s", statement.Currency, a.Currency)
+	}
+	entries, err := s.entries(a)
+	if err != nil {
+		return nil, err
+	}
+	computed := balanceAt(a, entries, at)
+	r := models.Reconciliation{
+		UserID:     userID,
+		AccountID:  a.ID,
+		At:         at,
+		Statement:  statement,
+		Computed:   computed,
+		Difference: models.NewMoney(statement.Minor-computed.Minor, a.Currency),
+		CreatedAt:  time.Now().UTC(),
+	}
+	r.Adjusted = adjust && !r.Difference.IsZero()
+
+	res := &ReconcileResult{Reconciliation: r, Unreconciled: []AccountEntry{}}
+	for _, e := range entries {
+		if e.Kind == EntryOpening || e.Time.After(at) || (a.ReconciledAt != nil && !e.Time.After(*a.ReconciledAt)) {
+			continue
+		}
+		res.Unreconciled = append(res.Unreconciled, e)
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&r).Error; err != nil {
+			return err
+		}
+		// only a matching (or adjusted) balance moves the reconciled mark
+		if !r.Difference.IsZero() && !r.Adjusted {
+			return nil
+		}
+		return tx.Model(a).Updates(map[string]interface{}{
+			"reconciled_at":       at,
+			"reconciled_minor":    statement.Minor,
+			"reconciled_currency": statement.Currency,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.Reconciliation = r
+	return res, nil
+}
+
+func (s *AccountService) Reconciliations(userID int, id uint64) ([]models.Reconciliation, error) {
+	var items []models.Reconciliation
+	err := s.DB.Where("user_id = ? AND account_id = ?", userID, id).Order("statement_time DESC, id DESC").Find(&items).Error
+	return items, err
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"time"
 
 	"example/AI/internal/models"
@@ -17,6 +18,25 @@ type AIService struct {
 	Model  string
 	// SystemPrompt string // میتونی از متغیر استفاده کنی یا از فایل بفرستی
 	SystemPrompt string
+	// per-user context appended to the system prompt (allowed categories, ...)
+	PromptContexts []PromptContextFunc
+}
+
+// PromptContextFunc returns extra context for one user/message ("" = nothing to add).
+type PromptContextFunc func(userID int, message string) string
+
+func (s *AIService) AddPromptContext(f PromptContextFunc) {
+	s.PromptContexts = append(s.PromptContexts, f)
+}
+
+func (s *AIService) systemPromptFor(userID int, message string) string {
+	parts := []string{s.SystemPrompt}
+	for _, f := range s.PromptContexts {
+		if extra := f(userID, message); extra != "" {
+			parts = append(parts, extra)
+		}
+	}
+	return strings.Join(parts, "\n\n")
 }
 
 type ParsedSystemOutput struct {
@@ -25,6 +45,13 @@ type ParsedSystemOutput struct {
 	Data           map[string]interface{} `json:"data"`
 	Filters        map[string]interface{} `json:"filters"`
 	Analysis       map[string]interface{} `json:"analysis"`
+	Budget         map[string]interface{} `json:"budget,omitempty"`
+	Schedule       map[string]interface{} `json:"schedule,omitempty"`
+	Export         map[string]interface{} `json:"export,omitempty"`
+	Income         map[string]interface{} `json:"income,omitempty"`
+	Transfer       map[string]interface{} `json:"transfer,omitempty"`
+	Refund         map[string]interface{} `json:"refund,omitempty"`
+	Installment    map[string]interface{} `json:"installment,omitempty"`
 	AssistantReply string                 `json:"assistant_reply,omitempty"`
 }
 
@@ -57,8 +84,8 @@ func (s *AIService) ProcessMessage(userMessage string, userID int, username, rol
 	req := openAIRequest{
 		Model: s.Model,
 		Messages: []chatMessage{
-			{Role: "system", Content: s.SystemPrompt},
-			{Role: "user", Content: fmt.Sprintf("(userID: %s, username: %s, role: %s) \n\n %s", userID, username, role, userMessage)},
+			{Role: "system", Content: s.systemPromptFor(userID, userMessage)},
+			{Role: "user", Content: fmt.Sprintf("(userID: %d, username: %s, role: %s) \n\n %s", userID, username, role, userMessage)},
 		},
 		MaxTokens: 800,
 	}
@@ -91,8 +118,11 @@ func (s *AIService) ProcessMessage(userMessage string, userID int, username, rol
 	assistantText := oresp.Choices[0].Message.Content
 	fmt.Println(assistantText)
 
+	// UseNumber keeps amounts exact (json.Number instead of float64)
 	var result ParsedSystemOutput
-	if err := json.Unmarshal([]byte(assistantText), &result); err != nil {
+	dec := json.NewDecoder(strings.NewReader(assistantText))
+	dec.UseNumber()
+	if err := dec.Decode(&result); err != nil {
 		return nil, assistantText, fmt.Errorf("invalid json: %v", err)
 	}
 
@@ -199,11 +229,85 @@ Rules:
 
 // SumAmount already داشتیم؛ بیارش
 func (s *PurchaseService) CountPurchases(filter models.PurchaseFilter) (int64, error) {
-	db := s.DB.Model(&models.Purchase{})
-	// apply same filters...
+	db := applyFilter(s.DB.Model(&models.Purchase{}), filter)
 	var cnt int64
 	if err := db.Count(&cnt).Error; err != nil {
 		return 0, err
 	}
 	return cnt, nil
 }
+
+// chat sends a single system + user exchange and returns the assistant's text.
+func (s *AIService) chat(system, user string, maxTokens int) (string, error) {
+	req := openAIRequest{
+		Model: s.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		MaxTokens: maxTokens,
+	}
+	b, _ := json.Marshal(req)
+	httpReq, err := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewBuffer(b))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+s.ApiKey)
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	var oresp openAIResponse
+	if err := json.Unmarshal(body, &oresp); err != nil {
+		return "", fmt.Errorf("openai response parse error: %v", err)
+	}
+	if len(oresp.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned: %s", body)
+	}
+	return oresp.Choices[0].Message.Content, nil
+}
+
+type CategorizeItem struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Vendor string `json:"vendor,omitempty"`
+	Amount string `json:"amount,omitempty"`
+}
+
+type CategoryGuess struct {
+	ID          int    `json:"id"`
+	Category    string `json:"category"`
+	Subcategory string `json:"subcategory"`
+}
+
+// CategorizeBatch asks the model for the category of several purchases at once.
+// allowed is the ALLOWED CATEGORIES block of the user (see TaxonomyService.PromptContext).
+func (s *AIService) CategorizeBatch(allowed string, items []CategorizeItem) ([]CategoryGuess, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	system := `You categorize purchases. Reply with ONE JSON object only:
+{"items": [{"id": 0, "category": "", "subcategory": ""}]}
+- One entry per input item, same id.
+- category/subcategory MUST be keys from the ALLOWED CATEGORIES list; use "other" when unsure.
+
+` + allowed
+	in, _ := json.Marshal(items)
+	text, err := s.chat(system, string(in), 60+40*len(items))
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Items []CategoryGuess `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil, fmt.Errorf("invalid categorizer json: %v", err)
+	}
+	return out.Items, nil
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// modified z-score above which a value is unusual
+	AnomalyZThreshold = 3.5
+	anomalyLookback   = 180 * 24 * time.Hour
+	// history needed before anything is flagged
+	anomalyMinPurchases = 8
+	anomalyMinPeriods   = 6
+	// how many previous same-weekdays / weeks form a period baseline
+	anomalyPeriodHistory = 12
+)
+
+var ErrAnomalyNotFound = errors.New("anomaly not found")
+
+var persianWeekdays = [...]string{"یکشنبه", "دوشنبه", "سه‌شنبه", "چهارشنبه", "پنجشنبه", "جمعه", "شنبه"}
+
+// AnomalyService compares purchases, days and category-weeks with the user's own baselines
+// (median/MAD by category and weekday) and stores what stands out.
+type AnomalyService struct {
+	DB       *gorm.DB
+	Taxonomy *TaxonomyService
+}
+
+func NewAnomalyService(db *gorm.DB, ts *TaxonomyService) *AnomalyService {
+	return &AnomalyService{DB: db, Taxonomy: ts}
+}
+
+func (s *AnomalyService) categoryLabel(userID int, key string) string {
+	if s.Taxonomy == nil || key == "" {
+		return key
+	}
+	return s.Taxonomy.Label(userID, key, "fa")
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func medianMoney(xs []float64, currency string) models.Money {
+	return models.NewMoney(int64(utils.Median(xs)), currency)
+}
+
+// Scan flags the unusual purchases, days and category-weeks of [from, to) and stores them.
+// Flags the user dismissed stay dismissed. Returns the active flags of the range, strongest first.
+func (s *AnomalyService) Scan(userID int, from, to time.Time) ([]models.AnomalyFlag, error) {
+	var purchases []models.Purchase
+	// duplicates (imported or kept anyway) would count twice in both baseline and flags
+	if err := s.DB.Where("user_id = ? AND status <> ? AND basis <> ? AND duplicate_of IS NULL AND purchase_time >= ? AND purchase_time < ?",
+		userID, models.StatusRejected, models.BasisCash, from.Add(-anomalyLookback), to).
+		Order("purchase_time").Find(&purchases).Error; err != nil {
+		return nil, err
+	}
+
+	var flags []models.AnomalyFlag
+	flags = append(flags, s.purchaseAnomalies(userID, purchases, from, to)...)
+	flags = append(flags, s.dayAnomalies(userID, purchases, from, to)...)
+	split, err := categoryShares(s.DB, purchases)
+	if err != nil {
+		return nil, err
+	}
+	flags = append(flags, s.weekAnomalies(userID, purchases, split, from, to)...)
+
+	out := make([]models.AnomalyFlag, 0, len(flags))
+	for i := range flags {
+		if err := s.save(&flags[i]); err != nil {
+			return nil, err
+		}
+		if !flags[i].Dismissed {
+			out = append(out, flags[i])
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out, nil
+}
+
+// purchaseAnomalies: a purchase against earlier purchases of its category (same weekday when
+// there is enough history).
+func (s *AnomalyService) purchaseAnomalies(userID int, purchases []models.Purchase, from, to time.Time) []models.AnomalyFlag {
+	groups := map[string][]models.Purchase{}
+	for _, p := range purchases {
+		k := p.Category + "|" + p.Amount.Currency
+		groups[k] = append(groups[k], p)
+	}
+
+	var flags []models.AnomalyFlag
+	for _, group := range groups {
+		for i := range group {
+			p := &group[i]
+			d := purchaseDate(p)
+			if d.Before(from) || !d.Before(to) {
+				continue
+			}
+			var all, sameWeekday []float64
+			for j := 0; j < i; j++ {
+				hd := purchaseDate(&group[j])
+				if hd.Before(d.Add(-anomalyLookback)) {
+					continue
+				}
+				all = append(all, float64(group[j].Net().Minor))
+				if hd.Weekday() == d.Weekday() {
+					sameWeekday = append(sameWeekday, float64(group[j].Net().Minor))
+				}
+			}
+			history, scope := all, ""
+			if len(sameWeekday) >= anomalyMinPurchases {
+				history, scope = sameWeekday, " در روزهای "+persianWeekdays[d.Weekday()]
+			}
+			if len(history) < anomalyMinPurchases {
+				continue
+			}
+			z := utils.RobustZ(float64(p.Net().Minor), history)
+			if z <= AnomalyZThreshold {
+				continue
+			}
+			baseline := medianMoney(history, p.Amount.Currency)
+			id := p.ID
+			flags = append(flags, models.AnomalyFlag{
+				UserID:      userID,
+				Kind:        models.AnomalyPurchase,
+				PurchaseID:  &id,
+				Category:    p.Category,
+				PeriodStart: startOfDay(d),
+				Value:       p.Net(),
+				Baseline:    baseline,
+				Score:       round2(z),
+				Reason: fmt.Sprintf("«%s» (%s) خیلی بیشتر از خریدهای معمول %s%s است (معمولا %s).",
+					p.Title, p.Net(), s.categoryLabel(userID, p.Category), scope, baseline),
+			})
+		}
+	}
+	return flags
+}
+
+// totalsByDay sums spending per day and currency.
+func totalsByDay(purchases []models.Purchase) map[string]map[time.Time]int64 {
+	out := map[string]map[time.Time]int64{}
+	for i := range purchases {
+		p := &purchases[i]
+		if out[p.Amount.Currency] == nil {
+			out[p.Amount.Currency] = map[time.Time]int64{}
+		}
+		out[p.Amount.Currency][startOfDay(purchaseDate(p))] += p.Net().Minor
+	}
+	return out
+}
+
+// dayAnomalies: a day's total against the same weekday of previous weeks.
+func (s *AnomalyService) dayAnomalies(userID int, purchases []models.Purchase, from, to time.Time) []models.AnomalyFlag {
+	var flags []models.AnomalyFlag
+	for currency, totals := range totalsByDay(purchases) {
+		first := to
+		for d := range totals {
+			if d.Before(first) {
+				first = d
+			}
+		}
+		for d := startOfDay(from); d.Before(to); d = d.AddDate(0, 0, 1) {
+			value := totals[d]
+			if value == 0 {
+				continue
+			}
+			var history []float64
+			for w := 1; w <= anomalyPeriodHistory; w++ {
+				hd := d.AddDate(0, 0, -7*w)
+				if hd.Before(first) {
+					break
+				}
+				history = append(history, float64(totals[hd])) // days without spending count as 0
+			}
+			if len(history) < anomalyMinPeriods {
+				continue
+			}
+			z := utils.RobustZ(float64(value), history)
+			if z <= AnomalyZThreshold {
+				continue
+			}
+			v := models.NewMoney(value, currency)
+			baseline := medianMoney(history, currency)
+			flags = append(flags, models.AnomalyFlag{
+				UserID:      userID,
+				Kind:        models.AnomalyDay,
+				PeriodStart: d,
+				Value:       v,
+				Baseline:    baseline,
+				Score:       round2(z),
+				Reason: fmt.Sprintf("خرج %s %s (%s) خیلی بیشتر از %s‌های معمول بود (معمولا %s).",
+					persianWeekdays[d.Weekday()], d.Format("2006-01-02"), v, persianWeekdays[d.Weekday()], baseline),
+			})
+		}
+	}
+	return flags
+}
+
+// weekAnomalies: a category's weekly total against its previous weeks.
+// Mixed purchases count per item category (split).
+func (s *AnomalyService) weekAnomalies(userID int, purchases []models.Purchase, split map[uint64][]CategoryShare, from, to time.Time) []models.AnomalyFlag {
+	type key struct {
+		category, currency string
+	}
+	totals := map[key]map[time.Time]int64{}
+	first := map[key]time.Time{}
+	for i := range purchases {
+		p := &purchases[i]
+		ws, _, _ := utils.PeriodBounds(utils.PeriodWeekly, startOfDay(purchaseDate(p)))
+		for _, sh := range sharesOf(split, p) {
+			k := key{sh.Category, sh.Amount.Currency}
+			if totals[k] == nil {
+				totals[k] = map[time.Time]int64{}
+				first[k] = ws
+			}
+			totals[k][ws] += sh.Amount.Minor
+		}
+	}
+
+	var flags []models.AnomalyFlag
+	startWeek, _, _ := utils.PeriodBounds(utils.PeriodWeekly, startOfDay(from))
+	for k, weeks := range totals {
+		for ws := startWeek; ws.Before(to); ws = ws.AddDate(0, 0, 7) {
+			value := weeks[ws]
+			if value == 0 {
+				continue
+			}
+			var history []float64
+			for w := 1; w <= anomalyPeriodHistory; w++ {
+				hw := ws.AddDate(0, 0, -7*w)
+				if hw.Before(first[k]) {
+					break
+				}
+				history = append(history, float64(weeks[hw]))
+			}
+			if len(history) < anomalyMinPeriods {
+				continue
+			}
+			z := utils.RobustZ(float64(value), history)
+			if z <= AnomalyZThreshold {
+				continue
+			}
+			v := models.NewMoney(value, k.currency)
+			baseline := medianMoney(history, k.currency)
+			flags = append(flags, models.AnomalyFlag{
+				UserID:      userID,
+				Kind:        models.AnomalyWeek,
+				Category:    k.category,
+				PeriodStart: ws,
+				Value:       v,
+				Baseline:    baseline,
+				Score:       round2(z),
+				Reason: fmt.Sprintf("خرج %s در هفته‌ی %s (%s) خیلی بیشتر از هفته‌های معمول بود (معمولا %s).",
+					s.categoryLabel(userID, k.category), ws.Format("2006-01-02"), v, baseline),
+			})
+		}
+	}
+	return flags
+}
+
+func round2(f float64) float64 {
+	return float64(int64(f*100+0.5)) / 100
+}
+
+// save upserts a flag by its identity (purchase, or kind+category+period+currency).
+func (s *AnomalyService) save(f *models.AnomalyFlag) error {
+	q := s.DB.Where("user_id = ? AND kind = ?", f.UserID, f.Kind)
+	if f.PurchaseID != nil {
+		q = q.Where("purchase_id = ?", *f.PurchaseID)
+	} else {
+		q = q.Where("category = ? AND period_start = ? AND value_currency = ?", f.Category, f.PeriodStart, f.Value.Currency)
+	}
+	var existing models.AnomalyFlag
+	err := q.First(&existing).Error
+	if err == nil {
+		f.ID, f.Dismissed, f.CreatedAt = existing.ID, existing.Dismissed, existing.CreatedAt
+		return s.DB.Save(f).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	f.CreatedAt = time.Now().UTC()
+	return s.DB.Create(f).Error
+}
+
+// List returns the stored, not dismissed flags of [from, to).
+func (s *AnomalyService) List(userID int, from, to time.Time) ([]models.AnomalyFlag, error) {
+	var items []models.AnomalyFlag
+	err := s.DB.Where("user_id = ? AND dismissed = ? AND period_start >= ? AND period_start < ?", userID, false, from, to).
+		Order("score DESC").Find(&items).Error
+	return items, err
+}
+
+// Dismiss hides a flag ("this was expected"); rescans keep it hidden.
+func (s *AnomalyService) Dismiss(userID int, id uint64) error {
+	res := s.DB.Model(&models.AnomalyFlag{}).Where("id = ? AND user_id = ?", id, userID).Update("dismissed", true)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrAnomalyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"example/AI/internal/banksms"
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// confidence of purchases created from bank SMS: amount/date are exact, the category is a guess
+const bankSMSConfidence = 0.6
+
+// BankSMSService turns pasted bank SMS into guessed purchases (debits) and keeps the balances.
+type BankSMSService struct {
+	DB       *gorm.DB
+	Purchase *PurchaseService
+	AI       *AIService     // optional, only used to categorize
+	Incomes  *IncomeService // optional, credits become guessed incomes
+}
+
+func NewBankSMSService(db *gorm.DB, ps *PurchaseService, ai *AIService) *BankSMSService {
+	return &BankSMSService{DB: db, Purchase: ps, AI: ai}
+}
+
+const (
+	SMSCreated   = "created"   // debit -> guessed purchase
+	SMSRecorded  = "recorded"  // credit: the balance, and a guessed income when incomes are enabled
+	SMSDuplicate = "duplicate" // already imported
+	SMSInvalid   = "invalid"
+)
+
+type SMSResult struct {
+	Index       int                     `json:"index"`
+	Status      string                  `json:"status"`
+	Error       string                  `json:"error,omitempty"`
+	Transaction *models.BankTransaction `json:"transaction,omitempty"`
+	Purchase    *models.Purchase        `json:"purchase,omitempty"`
+	Income      *models.Income          `json:"income,omitempty"`
+}
+
+func smsHash(text string) string {
+	sum := sha1.Sum([]byte(utils.NormalizeText(text)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Import parses the messages; every debit becomes a purchase with status guessed.
+func (s *BankSMSService) Import(userID int, messages []string) ([]SMSResult, error) {
+	results := make([]SMSResult, len(messages))
+	var pending []int // debits that become purchases
+	purchases := make([]*models.Purchase, len(messages))
+	incomes := make([]*models.Income, len(messages))
+	seen := map[string]bool{}
+
+	for i, text := range messages {
+		results[i].Index = i
+		tx, err := banksms.Parse(text)
+		if err != nil {
+			results[i].Status, results[i].Error = SMSInvalid, err.Error()
+			continue
+		}
+		bt := &models.BankTransaction{
+			UserID:  userID,
+			Hash:    smsHash(tx.Raw),
+			Bank:    tx.Bank,
+			Account: tx.Account,
+			Kind:    tx.Kind,
+			Amount:  tx.Amount,
+			Time:    tx.Time,
+			Text:    tx.Raw,
+		}
+		if tx.Balance != nil {
+			bt.HasBalance, bt.Balance = true, *tx.Balance
+		}
+		results[i].Transaction = bt
+
+		var existing models.BankTransaction
+		err = s.DB.Where("user_id = ? AND hash = ?", userID, bt.Hash).First(&existing).Error
+		if err == nil || seen[bt.Hash] {
+			results[i].Status = SMSDuplicate
+			if err == nil {
+				results[i].Transaction = &existing
+			}
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		seen[bt.Hash] = true
+
+		var accountID *uint64
+		if s.Purchase.Accounts != nil {
+			if acc, err := s.Purchase.Accounts.ForBankSMS(userID, tx.Bank, tx.Account); err == nil && acc != nil {
+				accountID = &acc.ID
+			}
+		}
+
+		if tx.Kind != banksms.Debit {
+			results[i].Status = SMSRecorded
+			if s.Incomes != nil {
+				// the kind (salary, transfer, ...) is unknown until the user confirms it
+				in := &models.Income{
+					UserID:     userID,
+					Title:      tx.Description,
+					Amount:     tx.Amount,
+					Kind:       models.IncomeOther,
+					Source:     tx.Description,
+					ReceivedAt: tx.Time,
+					Status:     models.StatusGuessed,
+					SourceText: tx.Raw,
+					AccountID:  accountID,
+				}
+				if in.Title == "" {
+					in.Title = strings.TrimSpace("واریز " + tx.Bank)
+				}
+				if err := s.Incomes.validate(in); err != nil {
+					results[i].Status, results[i].Error = SMSInvalid, err.Error()
+					continue
+				}
+				in.CreatedAt = time.Now().UTC()
+				incomes[i] = in
+			}
+			continue
+		}
+		title := tx.Description
+		var vendor *string
+		if title != "" {
+			v := title
+			vendor = &v
+		} else {
+			title = "برداشت بانکی"
+			if tx.Bank != "" {
+				title += " " + tx.Bank
+			}
+		}
+		when := tx.Time
+		if when == nil {
+			now := time.Now().UTC()
+			when = &now
+		}
+		purchases[i] = &models.Purchase{
+			UserID:        userID,
+			Title:         title,
+			Amount:        tx.Amount,
+			Vendor:        vendor,
+			PurchaseTime:  when,
+			CreatedAt:     time.Now().UTC(),
+			Confidence:    bankSMSConfidence,
+			Status:        models.StatusGuessed,
+			ReviewReasons: "bank_sms",
+			SourceText:    tx.Raw,
+			AccountID:     accountID,
+		}
+		results[i].Status = SMSCreated
+		pending = append(pending, i)
+	}
+
+	s.categorize(userID, purchases, pending)
+	for _, i := range pending {
+		if err := s.Purchase.Normalize(purchases[i]); err != nil {
+			return nil, err
+		}
+		// the payment may already be logged by chat
+		if s.Purchase.Duplicates != nil {
+			if _, err := s.Purchase.Duplicates.Flag(purchases[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range results {
+			r := &results[i]
+			if r.Status != SMSCreated && r.Status != SMSRecorded {
+				continue
+			}
+			if p := purchases[i]; p != nil {
+				if err := tx.Create(p).Error; err != nil {
+					return err
+				}
+				r.Transaction.PurchaseID = &p.ID
+				r.Purchase = p
+			}
+			if in := incomes[i]; in != nil {
+				if err := tx.Create(in).Error; err != nil {
+					return err
+				}
+				r.Transaction.IncomeID = &in.ID
+				r.Income = in
+			}
+			if err := tx.Create(r.Transaction).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range purchases {
+		if p == nil {
+			continue
+		}
+		if s.Purchase.Classifier != nil {
+			s.Purchase.Classifier.Observe(p)
+		}
+		if s.Purchase.Recurring != nil {
+			_ = s.Purchase.Recurring.Attach(p)
+		}
+	}
+	return results, nil
+}
+
+// categorize asks the model for the categories of the new purchases (one call).
+func (s *BankSMSService) categorize(userID int, purchases []*models.Purchase, idx []int) {
+	if s.AI == nil || s.Purchase.Taxonomy == nil || len(idx) == 0 {
+		return
+	}
+	items := make([]CategorizeItem, 0, len(idx))
+	for _, i := range idx {
+		it := CategorizeItem{ID: i, Title: purchases[i].Title, Amount: purchases[i].Amount.String()}
+		if purchases[i].Vendor != nil {
+			it.Vendor = *purchases[i].Vendor
+		}
+		items = append(items, it)
+	}
+	guesses, err := s.AI.CategorizeBatch(s.Purchase.Taxonomy.PromptContext(userID, ""), items)
+	if err != nil {
+		log.Printf("bank sms categorize error: %v", err)
+		return
+	}
+	for _, g := range guesses {
+		if g.ID >= 0 && g.ID < len(purchases) && purchases[g.ID] != nil {
+			purchases[g.ID].Category, purchases[g.ID].Subcategory = g.Category, g.Subcategory
+		}
+	}
+}
+
+// AccountBalance is the last balance reported by the bank for one account.
+type AccountBalance struct {
+	Bank    string       `json:"bank"`
+	Account string       `json:"account"`
+	Balance models.Money `json:"balance"`
+	AsOf    *time.Time   `json:"as_of"`
+	SMSID   uint64       `json:"sms_id"`
+}
+
+// Balances returns the latest known balance of every bank account of the user.
+func (s *BankSMSService) Balances(userID int) ([]AccountBalance, error) {
+	var txs []models.BankTransaction
+	if err := s.DB.Where("user_id = ? AND has_balance = ?", userID, true).
+		Order("sms_time DESC, id DESC").Find(&txs).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	out := []AccountBalance{}
+	for _, t := range txs {
+		k := fmt.Sprintf("%s|%s", t.Bank, strings.TrimSpace(t.Account))
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, AccountBalance{Bank: t.Bank, Account: t.Account, Balance: t.Balance, AsOf: t.Time, SMSID: t.ID})
+	}
+	return out, nil
+}
+
+// Transactions lists the parsed SMS of the user, newest first.
+func (s *BankSMSService) Transactions(userID int, limit int) ([]models.BankTransaction, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var txs []models.BankTransaction
+	err := s.DB.Where("user_id = ?", userID).Order("sms_time DESC, id DESC").Limit(limit).Find(&txs).Error
+	return txs, err
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// how many past periods are replayed to compute the rollover carry
+const maxRolloverPeriods = 12
+
+type BudgetService struct {
+	DB       *gorm.DB
+	Purchase *PurchaseService
+}
+
+func NewBudgetService(db *gorm.DB, ps *PurchaseService) *BudgetService {
+	return &BudgetService{DB: db, Purchase: ps}
+}
+
+var ErrBudgetNotFound = errors.New("budget not found")
+
+// BudgetStatus is a budget evaluated for one period.
+type BudgetStatus struct {
+	Budget      models.Budget `json:"budget"`
+	PeriodStart time.Time     `json:"period_start"`
+	PeriodEnd   time.Time     `json:"period_end"`
+	Carried     models.Money  `json:"carried"` // rollover from previous periods
+	Limit       models.Money  `json:"limit"`   // budget limit + carried
+	Spent       models.Money  `json:"spent"`
+	Remaining   models.Money  `json:"remaining"`
+	Percent     int           `json:"percent"`
+}
+
+func (s *BudgetService) validate(b *models.Budget) error {
+	if b.Period == "" {
+		b.Period = utils.PeriodMonthly
+	}
+	switch b.Period {
+	case utils.PeriodWeekly, utils.PeriodMonthly, utils.PeriodJalaliMonthly:
+	default:
+		return fmt.Errorf("invalid period %q (weekly, monthly, jalali_monthly)", b.Period)
+	}
+	if b.Rollover == "" {
+		b.Rollover = models.RolloverNone
+	}
+	switch b.Rollover {
+	case models.RolloverNone, models.RolloverSurplus, models.RolloverFull:
+	default:
+		return fmt.Errorf("invalid rollover %q (none, surplus, full)", b.Rollover)
+	}
+	if !b.Limit.IsPositive() {
+		return errors.New("budget limit must be positive")
+	}
+	if b.WarnPercent <= 0 || b.WarnPercent > 100 {
+		b.WarnPercent = 80
+	}
+	if b.Category != "" && s.Purchase.Taxonomy != nil {
+		cat, _, err := s.Purchase.Taxonomy.Resolve(b.UserID, b.Category, "")
+		if err != nil {
+			return err
+		}
+		b.Category = cat
+	}
+	return nil
+}
+
+// Upsert creates the budget, or updates the existing one for the same category and period.
+func (s *BudgetService) Upsert(b *models.Budget) error {
+	if err := s.validate(b); err != nil {
+		return err
+	}
+	var existing models.Budget
+	err := s.DB.Where("user_id = ? AND category = ? AND period = ?", b.UserID, b.Category, b.Period).First(&existing).Error
+	if err == nil {
+		b.ID, b.CreatedAt = existing.ID, existing.CreatedAt
+		return s.DB.Save(b).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return s.DB.Create(b).Error
+}
+
+func (s *BudgetService) Get(userID int, id uint64) (*models.Budget, error) {
+	var b models.Budget
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&b).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBudgetNotFound
+		}
+		return nil, err
+	}
+	return &b, nil
+}
+
+func (s *BudgetService) Update(b *models.Budget) error {
+	if err := s.validate(b); err != nil {
+		return err
+	}
+	return s.DB.Save(b).Error
+}
+
+func (s *BudgetService) List(userID int) ([]models.Budget, error) {
+	var items []models.Budget
+	err := s.DB.Where("user_id = ?", userID).Order("category, period").Find(&items).Error
+	return items, err
+}
+
+func (s *BudgetService) Delete(userID int, id uint64) error {
+	res := s.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Budget{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
+
+// spent sums the user's non-rejected spending in the budget's currency for [from, to).
+func (s *BudgetService) spent(b *models.Budget, from, to time.Time) (models.Money, error) {
+	end := to.Add(-time.Nanosecond)
+	pf := models.PurchaseFilter{
+		UserIDs:            []int{b.UserID},
+		FromDate:           &from,
+		ToDate:             &end,
+		IncludeUnconfirmed: true,
+	}
+	if b.Category != "" {
+		pf.Categories = []string{b.Category}
+	}
+	totals, err := s.Purchase.SumAmount(pf)
+	if err != nil {
+		return models.Money{}, err
+	}
+	for _, t := range totals {
+		if t.Currency == b.Limit.Currency {
+			return t, nil
+		}
+	}
+	return models.NewMoney(0, b.Limit.Currency), nil
+}
+
+// Status evaluates a budget for the period containing `at`, including rollover.
+func (s *BudgetService) Status(b *models.Budget, at time.Time) (*BudgetStatus, error) {
+	start, end, err := utils.PeriodBounds(b.Period, at)
+	if err != nil {
+		return nil, err
+	}
+
+	carried := models.NewMoney(0, b.Limit.Currency)
+	if b.Rollover != models.RolloverNone {
+		// replay past periods (oldest first) since the budget was created
+		var periods [][2]time.Time
+		ps, pe := start, end
+		for i := 0; i < maxRolloverPeriods; i++ {
+			if ps, pe, err = utils.PreviousPeriod(b.Period, ps); err != nil {
+				return nil, err
+			}
+			if pe.Before(b.CreatedAt) {
+				break
+			}
+			periods = append([][2]time.Time{{ps, pe}}, periods...)
+		}
+		for _, p := range periods {
+			spent, err := s.spent(b, p[0], p[1])
+			if err != nil {
+				return nil, err
+			}
+			left := b.Limit.Minor + carried.Minor - spent.Minor
+			if b.Rollover == models.RolloverSurplus && left < 0 {
+				left = 0
+			}
+			carried.Minor = left
+		}
+	}
+
+	spent, err := s.spent(b, start, end)
+	if err != nil {
+		return nil, err
+	}
+	limit := models.NewMoney(b.Limit.Minor+carried.Minor, b.Limit.Currency)
+	st := &BudgetStatus{
+		Budget:      *b,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Carried:     carried,
+		Limit:       limit,
+		Spent:       spent,
+		Remaining:   models.NewMoney(limit.Minor-spent.Minor, limit.Currency),
+	}
+	if limit.Minor > 0 {
+		st.Percent = int(spent.Minor * 100 / limit.Minor)
+	} else if spent.Minor > 0 {
+		st.Percent = 100
+	}
+	return st, nil
+}
+
+// StatusAll evaluates every budget of the user for the current periods.
+func (s *BudgetService) StatusAll(userID int, at time.Time) ([]BudgetStatus, error) {
+	budgets, err := s.List(userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]BudgetStatus, 0, len(budgets))
+	for i := range budgets {
+		st, err := s.Status(&budgets[i], at)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *st)
+	}
+	return out, nil
+}
+
+// Evaluate returns budget warnings (Persian) triggered by a newly added purchase; a receipt
+// with items of several categories touches the budget of each.
+func (s *BudgetService) Evaluate(p *models.Purchase) ([]string, error) {
+	split, err := categoryShares(s.DB, []models.Purchase{*p})
+	if err != nil {
+		return nil, err
+	}
+	var categories []string
+	for _, sh := range sharesOf(split, p) {
+		categories = append(categories, sh.Category)
+	}
+	var budgets []models.Budget
+	if err := s.DB.Where("user_id = ? AND (category = '' OR category IN ?) AND limit_currency = ?",
+		p.UserID, categories, p.Amount.Currency).Order("id").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+
+	at := time.Now().UTC()
+	if p.PurchaseTime != nil {
+		at = *p.PurchaseTime
+	}
+
+	var warnings []string
+	for i := range budgets {
+		st, err := s.Status(&budgets[i], at)
+		if err != nil {
+			return warnings, err
+		}
+		name := "کل"
+		if st.Budget.Category != "" {
+			name = st.Budget.Category
+			if s.Purchase.Taxonomy != nil {
+				name = s.Purchase.Taxonomy.Label(p.UserID, name, "fa")
+			}
+		}
+		switch {
+		case st.Percent >= 100:
+			over := models.NewMoney(-st.Remaining.Minor, st.Remaining.Currency)
+			warnings = append(warnings, fmt.Sprintf("از بودجه‌ی %s %s بیشتر خرج کردی (%d٪).", name, over, st.Percent))
+		case st.Percent >= st.Budget.WarnPercent:
+			warnings = append(warnings, fmt.Sprintf("%d٪ از بودجه‌ی %s مصرف شده؛ %s باقی مانده.", st.Percent, name, st.Remaining))
+		}
+	}
+	return warnings, nil
+}
+
+// SetFromAIData creates/updates a budget from the model's "budget" object
+// ({"category", "amount", "currency", "period", "rollover"}).
+func (s *BudgetService) SetFromAIData(userID int, data map[string]interface{}) (*models.Budget, error) {
+	str := func(k string) string {
+		v, _ := data[k].(string)
+		return v
+	}
+	limit, err := toMoney(data["amount"], str("currency"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid budget amount: %w", err)
+	}
+	b := &models.Budget{
+		UserID:   userID,
+		Category: str("category"),
+		Period:   str("period"),
+		Limit:    limit,
+		Rollover: str("rollover"),
+	}
+	if err := s.Upsert(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"log"
+	"sync"
+
+	"example/AI/internal/classifier"
+	"example/AI/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// below this many training rows a model's opinion is ignored
+	classifierMinDocs = 20
+	// user model weight = n / (n + classifierUserPrior)
+	classifierUserPrior = 30.0
+)
+
+// ClassifierService keeps one global and one per-user naive Bayes model, trained from
+// confirmed purchases and updated incrementally as purchases are saved or corrected.
+type ClassifierService struct {
+	DB *gorm.DB
+
+	mu       sync.Mutex
+	global   *classifier.NaiveBayes
+	users    map[int]*classifier.NaiveBayes
+	training bool              // TrainGlobal is rebuilding the global model
+	pending  []classifierEvent // global updates made meanwhile, replayed onto the new model
+}
+
+// classifierEvent is one Learn (or Forget) of the global model for a purchase.
+type classifierEvent struct {
+	id             uint64
+	text, category string
+	forget         bool
+}
+
+func NewClassifierService(db *gorm.DB) *ClassifierService {
+	return &ClassifierService{DB: db, global: classifier.New(), users: map[int]*classifier.NaiveBayes{}}
+}
+
+// purchaseText = what the classifier sees for one purchase
+func purchaseText(p *models.Purchase) string {
+	return p.Title + " " + deref(p.Vendor)
+}
+
+// TrainGlobal (re)builds the global model from every confirmed purchase. Purchases observed
+// while it runs are not lost: they are replayed onto the new model before it is swapped in,
+// except what the rebuild already read (the category it learned per purchase is kept for that).
+func (s *ClassifierService) TrainGlobal() error {
+	s.mu.Lock()
+	s.training, s.pending = true, nil
+	s.mu.Unlock()
+
+	nb := classifier.New()
+	learned := map[uint64]string{}
+	var rows []models.Purchase
+	err := s.DB.Select("id, title, vendor, category").
+		Where("status = ? AND category <> ''", models.StatusConfirmed).
+		FindInBatches(&rows, 1000, func(*gorm.DB, int) error {
+			for i := range rows {
+				nb.Learn(purchaseText(&rows[i]), rows[i].Category)
+				learned[rows[i].ID] = rows[i].Category
+			}
+			return nil
+		}).Error
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.training, s.pending = false, nil
+		return err
+	}
+	for _, e := range s.pending {
+		if e.forget {
+			// only what the rebuild learned can be forgotten
+			if learned[e.id] == e.category {
+				nb.Forget(e.text, e.category)
+				delete(learned, e.id)
+			}
+			continue
+		}
+		if learned[e.id] != e.category {
+			nb.Learn(e.text, e.category)
+			learned[e.id] = e.category
+		}
+	}
+	s.global, s.training, s.pending = nb, false, nil
+	log.Printf("classifier: global model trained on %d purchases", nb.Docs())
+	return nil
+}
+
+// updateGlobal applies a Learn/Forget to the global model (and records it during training).
+func (s *ClassifierService) updateGlobal(e classifierEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.training {
+		s.pending = append(s.pending, e)
+	}
+	if e.forget {
+		s.global.Forget(e.text, e.category)
+	} else {
+		s.global.Learn(e.text, e.category)
+	}
+}
+
+// userModel lazily trains the user's model from their confirmed purchases. The rows are
+// loaded outside the lock, so one user's first purchase doesn't hold up everyone else.
+func (s *ClassifierService) userModel(userID int) *classifier.NaiveBayes {
+	s.mu.Lock()
+	nb, ok := s.users[userID]
+	s.mu.Unlock()
+	if ok {
+		return nb
+	}
+
+	nb = classifier.New()
+	var rows []models.Purchase
+	if err := s.DB.Select("id, title, vendor, category").
+		Where("user_id = ? AND status = ? AND category <> ''", userID, models.StatusConfirmed).
+		Find(&rows).Error; err != nil {
+		log.Printf("classifier: load user %d: %v", userID, err)
+	}
+	for i := range rows {
+		nb.Learn(purchaseText(&rows[i]), rows[i].Category)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.users[userID]; ok {
+		return existing // loaded concurrently
+	}
+	s.users[userID] = nb
+	return nb
+}
+
+func (s *ClassifierService) globalModel() *classifier.NaiveBayes {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.global
+}
+
+// Predict returns the category and its probability for a purchase, blending the user's
+// model with the global one. Returns "" when neither model has enough data.
+func (s *ClassifierService) Predict(p *models.Purchase) (string, float64) {
+	text := purchaseText(p)
+	user, global := s.userModel(p.UserID), s.globalModel()
+
+	var ud, gd map[string]float64
+	if user.Docs() >= classifierMinDocs {
+		ud = user.Distribution(text)
+	}
+	if global.Docs() >= classifierMinDocs {
+		gd = global.Distribution(text)
+	}
+	n := float64(user.Docs())
+	return classifier.Best(classifier.Blend(ud, gd, n/(n+classifierUserPrior)))
+}
+
+// Observe trains both models on a confirmed purchase.
+func (s *ClassifierService) Observe(p *models.Purchase) {
+	if p.Status != models.StatusConfirmed || p.Category == "" {
+		return
+	}
+	text := purchaseText(p)
+	s.userModel(p.UserID).Learn(text, p.Category)
+	s.updateGlobal(classifierEvent{id: p.ID, text: text, category: p.Category})
+}
+
+// Relabel moves a confirmed purchase from its old category to the corrected one.
+func (s *ClassifierService) Relabel(before, after *models.Purchase) {
+	if before.Status == models.StatusConfirmed && before.Category != "" {
+		text := purchaseText(before)
+		s.userModel(before.UserID).Forget(text, before.Category)
+		s.updateGlobal(classifierEvent{id: before.ID, text: text, category: before.Category, forget: true})
+	}
+	s.Observe(after)
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// how many past corrections go into the prompt as few-shot examples
+	fewShotLimit = 5
+	// a vendor corrected to the same category this many times becomes a rule
+	autoRuleAfter = 2
+)
+
+type CorrectionService struct {
+	DB *gorm.DB
+}
+
+func NewCorrectionService(db *gorm.DB) *CorrectionService {
+	return &CorrectionService{DB: db}
+}
+
+var ErrRuleNotFound = errors.New("rule not found")
+
+// Record stores one correction per changed field and learns a vendor rule when the same
+// vendor keeps getting corrected to the same category (or when remember is set).
+func (s *CorrectionService) Record(before, after *models.Purchase, remember bool) ([]models.Correction, error) {
+	changed := [][3]string{}
+	if before.Category != after.Category {
+		changed = append(changed, [3]string{"category", before.Category, after.Category})
+	}
+	if before.Subcategory != after.Subcategory {
+		changed = append(changed, [3]string{"subcategory", before.Subcategory, after.Subcategory})
+	}
+	if deref(before.Vendor) != deref(after.Vendor) {
+		changed = append(changed, [3]string{"vendor", deref(before.Vendor), deref(after.Vendor)})
+	}
+
+	now := time.Now().UTC()
+	var out []models.Correction
+	for _, c := range changed {
+		corr := models.Correction{
+			UserID:         after.UserID,
+			PurchaseID:     after.ID,
+			Field:          c[0],
+			OriginalValue:  c[1],
+			CorrectedValue: c[2],
+			InputText:      after.SourceText,
+			CreatedAt:      now,
+		}
+		if err := s.DB.Create(&corr).Error; err != nil {
+			return out, err
+		}
+		out = append(out, corr)
+	}
+
+	categoryChanged := before.Category != after.Category || before.Subcategory != after.Subcategory
+	if !categoryChanged || after.VendorID == nil {
+		return out, nil
+	}
+	if !remember {
+		var same int64
+		if err := s.DB.Model(&models.Correction{}).
+			Joins("JOIN purchases ON purchases.id = corrections.purchase_id").
+			Where("corrections.user_id = ? AND corrections.field = ? AND corrections.corrected_value = ? AND purchases.vendor_id = ?",
+				after.UserID, "category", after.Category, *after.VendorID).
+			Count(&same).Error; err != nil {
+			return out, err
+		}
+		remember = same >= autoRuleAfter
+	}
+	if remember {
+		_, err := s.upsertVendorRule(after.UserID, *after.VendorID, deref(after.Vendor), after.Category, after.Subcategory)
+		return out, err
+	}
+	return out, nil
+}
+
+func (s *CorrectionService) upsertVendorRule(userID int, vendorID uint64, vendorName, category, subcategory string) (*models.UserRule, error) {
+	var rule models.UserRule
+	err := s.DB.Where("user_id = ? AND field = ? AND vendor_id = ?", userID, models.RuleFieldVendor, vendorID).First(&rule).Error
+	if err == nil {
+		rule.Category, rule.Subcategory = category, subcategory
+		return &rule, s.DB.Save(&rule).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	rule = models.UserRule{
+		UserID:      userID,
+		Field:       models.RuleFieldVendor,
+		Pattern:     utils.NormalizeText(vendorName),
+		VendorID:    &vendorID,
+		Category:    category,
+		Subcategory: subcategory,
+		CreatedAt:   time.Now().UTC(),
+	}
+	return &rule, s.DB.Create(&rule).Error
+}
+
+// ApplyRules overrides the purchase category with the first matching user rule
+// (vendor rules first, then title, then free text). Returns the rule that matched.
+func (s *CorrectionService) ApplyRules(p *models.Purchase) (*models.UserRule, error) {
+	var rules []models.UserRule
+	if err := s.DB.Where("user_id = ?", p.UserID).Order("id DESC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	priority := map[string]int{models.RuleFieldVendor: 0, models.RuleFieldTitle: 1, models.RuleFieldText: 2}
+	sort.SliceStable(rules, func(i, j int) bool { return priority[rules[i].Field] < priority[rules[j].Field] })
+
+	vendor := utils.NormalizeText(deref(p.Vendor))
+	title := utils.NormalizeText(p.Title)
+	text := utils.NormalizeText(p.SourceText)
+
+	for i := range rules {
+		r := &rules[i]
+		matched := false
+		switch r.Field {
+		case models.RuleFieldVendor:
+			matched = (r.VendorID != nil && p.VendorID != nil && *r.VendorID == *p.VendorID) ||
+				(vendor != "" && vendor == r.Pattern)
+		case models.RuleFieldTitle:
+			matched = r.Pattern != "" && strings.Contains(title, r.Pattern)
+		case models.RuleFieldText:
+			matched = r.Pattern != "" && strings.Contains(text, r.Pattern)
+		}
+		if matched {
+			p.Category, p.Subcategory = r.Category, r.Subcategory
+			s.DB.Model(r).UpdateColumn("hits", gorm.Expr("hits + 1"))
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+// PromptContext injects the user's most relevant past corrections as few-shot examples.
+func (s *CorrectionService) PromptContext(userID int, message string) string {
+	var corrections []models.Correction
+	if err := s.DB.Where("user_id = ? AND field IN ?", userID, []string{"category", "vendor"}).
+		Order("id DESC").Limit(200).Find(&corrections).Error; err != nil || len(corrections) == 0 {
+		return ""
+	}
+
+	msgWords := wordSet(message)
+	type scored struct {
+		c     models.Correction
+		score float64
+	}
+	var ranked []scored
+	for _, c := range corrections {
+		score := overlap(msgWords, wordSet(c.InputText))
+		if v := utils.NormalizeText(c.OriginalValue); v != "" && strings.Contains(utils.NormalizeText(message), v) {
+			score += 0.5
+		}
+		if score > 0 {
+			ranked = append(ranked, scored{c, score})
+		}
+	}
+	if len(ranked) == 0 {
+		return ""
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	var b strings.Builder
+	b.WriteString("PAST CORRECTIONS BY THIS USER (apply the corrected value to similar inputs):\n")
+	seen := map[string]bool{}
+	n := 0
+	for _, r := range ranked {
+		key := r.c.Field + "|" + r.c.InputText + "|" + r.c.CorrectedValue
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		fmt.Fprintf(&b, "- input: %q -> %s: %q (model said %q)\n", r.c.InputText, r.c.Field, r.c.CorrectedValue, r.c.OriginalValue)
+		if n++; n >= fewShotLimit {
+			break
+		}
+	}
+	return b.String()
+}
+
+func wordSet(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(utils.NormalizeText(s)) {
+		if len([]rune(w)) > 1 {
+			set[w] = true
+		}
+	}
+	return set
+}
+
+// overlap = Jaccard similarity of two word sets
+func overlap(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for w := range a {
+		if b[w] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (s *CorrectionService) ListRules(userID int) ([]models.UserRule, error) {
+	var rules []models.UserRule
+	err := s.DB.Where("user_id = ?", userID).Order("id DESC").Find(&rules).Error
+	return rules, err
+}
+
+func (s *CorrectionService) CreateRule(rule *models.UserRule) error {
+	switch rule.Field {
+	case models.RuleFieldVendor, models.RuleFieldTitle, models.RuleFieldText:
+	default:
+		return fmt.Errorf("invalid rule field %q", rule.Field)
+	}
+	rule.Pattern = utils.NormalizeText(rule.Pattern)
+	if rule.Pattern == "" || rule.Category == "" {
+		return errors.New("pattern and category are required")
+	}
+	rule.CreatedAt = time.Now().UTC()
+	return s.DB.Create(rule).Error
+}
+
+func (s *CorrectionService) DeleteRule(userID int, id uint64) error {
+	res := s.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.UserRule{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const DefaultDraftTTL = 30 * time.Minute
+
+// titles at least this similar name the same purchase ("پیتزا" / "پیتزای")
+const draftTitleSimilarity = 0.8
+
+// DraftService keeps incomplete purchases around until the user answers the follow-up question.
+type DraftService struct {
+	DB  *gorm.DB
+	TTL time.Duration
+}
+
+func NewDraftService(db *gorm.DB, ttl time.Duration) *DraftService {
+	if ttl <= 0 {
+		ttl = DefaultDraftTTL
+	}
+	return &DraftService{DB: db, TTL: ttl}
+}
+
+// questions = سوال تکمیلی برای هر فیلد ناقص
+var draftQuestions = map[string]string{
+	"amount":       "چقدر پرداخت کردی؟",
+	"title":        "چی خریدی؟",
+	"title,amount": "چی خریدی و چقدر پرداخت کردی؟",
+}
+
+// Save replaces the user's draft with the new partial extraction.
+func (s *DraftService) Save(userID int, data map[string]interface{}, missing []string, input string) (*models.PurchaseDraft, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Join(missing, ",")
+	question, ok := draftQuestions[key]
+	if !ok {
+		question = "لطفا اطلاعات خرید رو کامل کن."
+	}
+
+	now := time.Now().UTC()
+	d := models.PurchaseDraft{
+		UserID:    userID,
+		Data:      string(b),
+		Missing:   key,
+		Question:  question,
+		InputText: input,
+		ExpiresAt: now.Add(s.TTL),
+		CreatedAt: now,
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.PurchaseDraft{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&d).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Active returns the user's unexpired draft (nil if none). Expired drafts are purged.
+func (s *DraftService) Active(userID int) (*models.PurchaseDraft, error) {
+	now := time.Now().UTC()
+	if err := s.DB.Where("user_id = ? AND expires_at < ?", userID, now).Delete(&models.PurchaseDraft{}).Error; err != nil {
+		return nil, err
+	}
+	var d models.PurchaseDraft
+	err := s.DB.Where("user_id = ?", userID).Order("id DESC").First(&d).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (s *DraftService) Discard(d *models.PurchaseDraft) error {
+	return s.DB.Delete(&models.PurchaseDraft{}, d.ID).Error
+}
+
+func draftData(d *models.PurchaseDraft) map[string]interface{} {
+	data := map[string]interface{}{}
+	dec := json.NewDecoder(strings.NewReader(d.Data))
+	dec.UseNumber()
+	_ = dec.Decode(&data)
+	return data
+}
+
+// Answers reports whether the new extraction answers the draft rather than being a purchase
+// of its own: it has neither title nor amount ("از اسنپ بود"), or it fills the missing fields
+// without contradicting the ones the draft already has.
+func (s *DraftService) Answers(d *models.PurchaseDraft, next map[string]interface{}) bool {
+	if isEmptyValue(next["title"]) && isEmptyValue(next["amount"]) {
+		return true
+	}
+	missing := map[string]bool{}
+	for _, f := range strings.Split(d.Missing, ",") {
+		missing[f] = true
+	}
+	known := draftData(d)
+	for _, f := range []string{"title", "amount"} {
+		if missing[f] || isEmptyValue(next[f]) || isEmptyValue(known[f]) {
+			continue
+		}
+		if f == "title" {
+			a, _ := next[f].(string)
+			b, _ := known[f].(string)
+			if !sameTitle(a, b) {
+				return false
+			}
+			continue
+		}
+		a, errA := toFloat64(next[f])
+		b, errB := toFloat64(known[f])
+		if errA != nil || errB != nil || a != b {
+			return false
+		}
+	}
+	return true
+}
+
+// sameTitle reports whether two titles name the same purchase: one is the other with more
+// words ("نان" / "نان بربری"), or they are spelled almost the same.
+func sameTitle(a, b string) bool {
+	a, b = utils.NormalizeText(a), utils.NormalizeText(b)
+	if a == b {
+		return true
+	}
+	if strings.Contains(" "+a+" ", " "+b+" ") || strings.Contains(" "+b+" ", " "+a+" ") {
+		return true
+	}
+	return utils.Similarity(a, b) >= draftTitleSimilarity
+}
+
+// Merge fills the draft with the new extraction: non-empty new values win.
+func (s *DraftService) Merge(d *models.PurchaseDraft, next map[string]interface{}) map[string]interface{} {
+	merged := draftData(d)
+	for k, v := range next {
+		if isEmptyValue(v) {
+			if _, exists := merged[k]; exists {
+				continue
+			}
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case json.Number:
+		f, err := t.Float64()
+		return err != nil || f == 0
+	case float64:
+		return t == 0
+	}
+	return false
+}
+
+// QuickAnswer handles answers that don't need the model: a bare amount ("۲۵۰ هزار تومن")
+// when only the amount is missing, or a short title when only the title is missing.
+func (s *DraftService) QuickAnswer(d *models.PurchaseDraft, message string) (map[string]interface{}, bool) {
+	msg := strings.TrimSpace(message)
+	switch d.Missing {
+	case "amount":
+		value, currency, ok := utils.ParseSpokenAmount(msg)
+		// the answer must be mostly the number, otherwise let the model read it
+		if !ok || len(strings.Fields(msg)) > 4 {
+			return nil, false
+		}
+		data := draftData(d)
+		data["amount"] = value
+		if currency != "" {
+			data["currency"] = currency
+		}
+		return data, true
+	case "title":
+		if msg == "" || len(strings.Fields(msg)) > 4 || strings.ContainsAny(utils.NormalizeDigits(msg), "0123456789") {
+			return nil, false
+		}
+		data := draftData(d)
+		data["title"] = msg
+		return data, true
+	}
+	return nil, false
+}
+
+// PromptContext tells the model about the pending draft so it can merge the answer.
+func (s *DraftService) PromptContext(userID int, _ string) string {
+	d, err := s.Active(userID)
+	if err != nil || d == nil {
+		return ""
+	}
+	return fmt.Sprintf(`PENDING PURCHASE DRAFT:
+The user was asked: %q (missing: %s).
+Already extracted data: %s
+If the new message answers the question, reply with action "add" and the COMPLETE purchase in "data" (draft fields + the answer).`,
+		d.Question, d.Missing, d.Data)
+}
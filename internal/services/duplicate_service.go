@@ -0,0 +1,344 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultDuplicateWindow = 48 * time.Hour
+	// amounts within 1% are considered equal (rounding, fees)
+	duplicateAmountTolerance = 0.01
+	DuplicateThreshold       = 0.8
+	reasonDuplicate          = "possible_duplicate"
+)
+
+// DuplicateService finds purchases that were recorded twice (same amount, close dates,
+// similar vendor/title), e.g. a chat message and the bank SMS of the same payment.
+type DuplicateService struct {
+	DB     *gorm.DB
+	Window time.Duration
+}
+
+func NewDuplicateService(db *gorm.DB, window time.Duration) *DuplicateService {
+	if window <= 0 {
+		window = DefaultDuplicateWindow
+	}
+	return &DuplicateService{DB: db, Window: window}
+}
+
+// DuplicateMatch is a purchase and the older one it probably duplicates.
+type DuplicateMatch struct {
+	Purchase models.Purchase `json:"purchase"`
+	Original models.Purchase `json:"original"`
+	Score    float64         `json:"score"`
+}
+
+// a bank SMS without merchant only has a generic title, so the title says nothing
+func genericTitle(p *models.Purchase) bool {
+	return (p.Vendor == nil || *p.Vendor == "") && strings.Contains(p.ReviewReasons, "bank_sms")
+}
+
+// duplicateScore rates how likely a and b are the same payment (0 = not at all).
+func (s *DuplicateService) duplicateScore(a, b *models.Purchase) float64 {
+	if a.UserID != b.UserID || a.Amount.Currency != b.Amount.Currency || a.Amount.Minor <= 0 || b.Amount.Minor <= 0 {
+		return 0
+	}
+	diff := math.Abs(float64(a.Amount.Minor - b.Amount.Minor))
+	ratio := diff / math.Max(float64(a.Amount.Minor), float64(b.Amount.Minor))
+	if ratio > duplicateAmountTolerance {
+		return 0
+	}
+	amountScore := 1.0
+	if diff > 0 {
+		amountScore = 0.8
+	}
+
+	gap := purchaseDate(a).Sub(purchaseDate(b))
+	if gap < 0 {
+		gap = -gap
+	}
+	if gap > s.Window {
+		return 0
+	}
+	dateScore := 1 - float64(gap)/float64(s.Window+24*time.Hour)
+
+	va, vb := vendorName(a.Vendor), vendorName(b.Vendor)
+	ta, tb := utils.NormalizeText(a.Title), utils.NormalizeText(b.Title)
+	var textScore float64
+	switch {
+	case a.VendorID != nil && b.VendorID != nil && *a.VendorID == *b.VendorID:
+		textScore = 1
+	case va != "" && vb != "":
+		textScore = utils.Similarity(utils.NormalizeText(va), utils.NormalizeText(vb))
+		if textScore < 0.6 {
+			return 0 // two different shops
+		}
+	default:
+		textScore = utils.Similarity(ta, tb)
+		if va != "" {
+			textScore = math.Max(textScore, utils.Similarity(utils.NormalizeText(va), tb))
+		}
+		if vb != "" {
+			textScore = math.Max(textScore, utils.Similarity(ta, utils.NormalizeText(vb)))
+		}
+		if (va == "") != (vb == "") || genericTitle(a) || genericTitle(b) {
+			textScore = math.Max(textScore, 0.5)
+		}
+	}
+	return round2(0.45*amountScore + 0.25*dateScore + 0.3*textScore)
+}
+
+// Candidates loads the user's countable purchases between from and to (inclusive window).
+func (s *DuplicateService) Candidates(userID int, from, to time.Time) ([]models.Purchase, error) {
+	var items []models.Purchase
+	err := s.DB.Where("user_id = ? AND status <> ? AND basis <> ? AND purchase_time >= ? AND purchase_time <= ?",
+		userID, models.StatusRejected, models.BasisAccrual, from.Add(-s.Window), to.Add(s.Window)).
+		Order("purchase_time, id").Find(&items).Error
+	return items, err
+}
+
+// MatchIn returns the best duplicate of p among candidates (nil when none reaches the threshold).
+func (s *DuplicateService) MatchIn(p *models.Purchase, candidates []models.Purchase) (*models.Purchase, float64) {
+	var best *models.Purchase
+	bestScore := 0.0
+	for i := range candidates {
+		c := &candidates[i]
+		if c.ID == p.ID || (p.ID != 0 && c.DuplicateOf != nil && *c.DuplicateOf == p.ID) {
+			continue
+		}
+		if sc := s.duplicateScore(p, c); sc >= DuplicateThreshold && sc > bestScore {
+			best, bestScore = c, sc
+		}
+	}
+	return best, bestScore
+}
+
+// Find looks for an existing purchase that p (new, not yet saved) duplicates.
+func (s *DuplicateService) Find(p *models.Purchase) (*models.Purchase, float64, error) {
+	at := purchaseDate(p)
+	candidates, err := s.Candidates(p.UserID, at, at)
+	if err != nil {
+		return nil, 0, err
+	}
+	best, score := s.MatchIn(p, candidates)
+	return best, score, nil
+}
+
+// Flag marks p as a possible duplicate of the best match, so it waits in review.
+// Returns the original (nil when p looks unique).
+func (s *DuplicateService) Flag(p *models.Purchase) (*models.Purchase, error) {
+	if p.DuplicateCleared {
+		return nil, nil
+	}
+	orig, _, err := s.Find(p)
+	if err != nil || orig == nil {
+		return nil, err
+	}
+	id := orig.ID
+	p.DuplicateOf = &id
+	p.Status = models.StatusGuessed
+	p.ReviewReasons = addReason(p.ReviewReasons, reasonDuplicate)
+	return orig, nil
+}
+
+func addReason(reasons, r string) string {
+	if reasons == "" {
+		return r
+	}
+	for _, x := range strings.Split(reasons, ",") {
+		if x == r {
+			return reasons
+		}
+	}
+	return reasons + "," + r
+}
+
+func removeReason(reasons, r string) string {
+	var out []string
+	for _, x := range strings.Split(reasons, ",") {
+		if x != "" && x != r {
+			out = append(out, x)
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+// DuplicateWarning is the user-facing note for a flagged purchase.
+func DuplicateWarning(id uint64) string {
+	return fmt.Sprintf("possible duplicate of #%d", id)
+}
+
+// List returns the user's purchases flagged as duplicates, with their originals.
+func (s *DuplicateService) List(userID int) ([]DuplicateMatch, error) {
+	var items []models.Purchase
+	if err := s.DB.Where("user_id = ? AND duplicate_of IS NOT NULL AND status <> ?", userID, models.StatusRejected).
+		Order("purchase_time DESC, id DESC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	out := make([]DuplicateMatch, 0, len(items))
+	for _, p := range items {
+		var orig models.Purchase
+		if err := s.DB.First(&orig, *p.DuplicateOf).Error; err != nil {
+			continue
+		}
+		out = append(out, DuplicateMatch{Purchase: p, Original: orig, Score: s.duplicateScore(&p, &orig)})
+	}
+	return out, nil
+}
+
+func (s *DuplicateService) get(userID int, id uint64) (*models.Purchase, error) {
+	var p models.Purchase
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPurchaseNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Keep confirms that a flagged purchase is not a duplicate; it is not flagged again.
+func (s *DuplicateService) Keep(userID int, id uint64) (*models.Purchase, error) {
+	p, err := s.get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	p.DuplicateOf, p.DuplicateCleared = nil, true
+	p.ReviewReasons = removeReason(p.ReviewReasons, reasonDuplicate)
+	if p.ReviewReasons == "" && p.Status == models.StatusGuessed {
+		p.Status = models.StatusConfirmed
+	}
+	err = s.DB.Model(p).Select("duplicate_of", "duplicate_cleared", "review_reasons", "status").Updates(p).Error
+	return p, err
+}
+
+// Merge folds the duplicate into the original (into = 0 means its DuplicateOf): the original
+// gets the fields it lacks, links to the duplicate move over, and the duplicate is rejected.
+func (s *DuplicateService) Merge(userID int, id, into uint64) (*models.Purchase, error) {
+	dup, err := s.get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if into == 0 {
+		if dup.DuplicateOf == nil {
+			return nil, errors.New("purchase is not flagged as a duplicate; give the purchase to merge into")
+		}
+		into = *dup.DuplicateOf
+	}
+	if into == id {
+		return nil, errors.New("cannot merge a purchase into itself")
+	}
+	orig, err := s.get(userID, into)
+	if err != nil {
+		return nil, err
+	}
+
+	if orig.Vendor == nil && dup.Vendor != nil {
+		orig.Vendor, orig.VendorID = dup.Vendor, dup.VendorID
+	}
+	if (orig.Category == "" || orig.Category == models.OtherCategory) && dup.Category != "" {
+		orig.Category, orig.Subcategory = dup.Category, dup.Subcategory
+	}
+	if orig.Necessity == "" {
+		orig.Necessity = dup.Necessity
+	}
+	if orig.EmotionalTone == "" {
+		orig.EmotionalTone = dup.EmotionalTone
+	}
+	if orig.ReasonGuess == "" {
+		orig.ReasonGuess = dup.ReasonGuess
+	}
+	if orig.RecurringSeriesID == nil {
+		orig.RecurringSeriesID = dup.RecurringSeriesID
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(orig).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(dup).Updates(map[string]interface{}{
+			"status":         models.StatusRejected,
+			"duplicate_of":   orig.ID,
+			"review_reasons": addReason(dup.ReviewReasons, "merged"),
+		}).Error; err != nil {
+			return err
+		}
+		// rows that pointed at the duplicate now point at the original
+		for _, m := range []interface{}{&models.BankTransaction{}, &models.ImportRow{}, &models.ScheduledOccurrence{}, &models.StatementLine{}, &models.Refund{}} {
+			if err := tx.Model(m).Where("purchase_id = ?", dup.ID).Update("purchase_id", orig.ID).Error; err != nil {
+				return err
+			}
+		}
+		if dup.RefundedMinor > 0 {
+			if err := tx.Model(&models.Purchase{}).Where("id = ?", orig.ID).
+				Update("refunded_minor", gorm.Expr("refunded_minor + ?", dup.RefundedMinor)).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Purchase{}).Where("duplicate_of = ? AND status <> ?", dup.ID, models.StatusRejected).
+			Update("duplicate_of", orig.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orig, nil
+}
+
+// Scan flags existing duplicates of the user in [from, to): the newer purchase of each pair
+// is marked as a possible duplicate of the older one.
+func (s *DuplicateService) Scan(userID int, from, to time.Time) ([]DuplicateMatch, error) {
+	items, err := s.Candidates(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		di, dj := purchaseDate(&items[i]), purchaseDate(&items[j])
+		if !di.Equal(dj) {
+			return di.Before(dj)
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	var out []DuplicateMatch
+	for j := range items {
+		p := &items[j]
+		at := purchaseDate(p)
+		if p.DuplicateOf != nil || p.DuplicateCleared || at.Before(from) || !at.Before(to) {
+			continue
+		}
+		var best *models.Purchase
+		bestScore := 0.0
+		for i := j - 1; i >= 0 && at.Sub(purchaseDate(&items[i])) <= s.Window; i-- {
+			o := &items[i]
+			if o.DuplicateOf != nil {
+				continue // compare against originals only
+			}
+			if sc := s.duplicateScore(p, o); sc >= DuplicateThreshold && sc > bestScore {
+				best, bestScore = o, sc
+			}
+		}
+		if best == nil {
+			continue
+		}
+		id := best.ID
+		// same as Flag: out of the totals until reviewed
+		p.DuplicateOf = &id
+		p.Status = models.StatusGuessed
+		p.ReviewReasons = addReason(p.ReviewReasons, reasonDuplicate)
+		if err := s.DB.Model(p).Select("duplicate_of", "review_reasons", "status").Updates(p).Error; err != nil {
+			return nil, err
+		}
+		out = append(out, DuplicateMatch{Purchase: *p, Original: *best, Score: bestScore})
+	}
+	return out, nil
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+)
+
+// impulse = low necessity bought in an excited or stressed mood
+var impulseTones = map[string]bool{"excited": true, "stressed": true}
+
+const emotionTopTriggers = 10
+
+// words that say nothing about the trigger (fa + en)
+var triggerStopwords = map[string]bool{
+	"برای": true, "این": true, "که": true, "از": true, "به": true, "با": true, "در": true, "را": true,
+	"است": true, "بود": true, "کاربر": true, "خرید": true, "احتمالا": true, "شاید": true, "یک": true, "هم": true,
+	"و": true, "یا": true, "تا": true, "چون": true, "the": true, "and": true, "for": true, "with": true,
+	"user": true, "purchase": true, "probably": true, "maybe": true, "because": true, "was": true, "this": true,
+}
+
+// EmotionService aggregates EmotionalTone / Necessity / ReasonGuess of purchases.
+type EmotionService struct {
+	Purchase *PurchaseService
+}
+
+func NewEmotionService(ps *PurchaseService) *EmotionService {
+	return &EmotionService{Purchase: ps}
+}
+
+type EmotionCell struct {
+	Tone      string       `json:"emotional_tone"`
+	Necessity string       `json:"necessity"`
+	Count     int          `json:"count"`
+	Total     models.Money `json:"total"`
+}
+
+type ImpulseScore struct {
+	Currency      string       `json:"currency"`
+	ImpulseAmount models.Money `json:"impulse_amount"`
+	TotalAmount   models.Money `json:"total_amount"`
+	ImpulseCount  int          `json:"impulse_count"`
+	Score         float64      `json:"score"` // % of spending that was impulsive
+}
+
+type Trigger struct {
+	Word     string       `json:"word"`
+	Count    int          `json:"count"`
+	Total    models.Money `json:"total"`
+	Examples []string     `json:"examples"`
+}
+
+type EmotionTrend struct {
+	Period  string                  `json:"period"`
+	Start   time.Time               `json:"start"`
+	Impulse ImpulseScore            `json:"impulse"`
+	ByTone  map[string]models.Money `json:"by_tone"`
+}
+
+type EmotionReport struct {
+	Crosstab []EmotionCell  `json:"crosstab"`
+	Impulse  []ImpulseScore `json:"impulse"` // one per currency
+	Triggers []Trigger      `json:"triggers"`
+	Trends   []EmotionTrend `json:"trends"`
+	Period   string         `json:"period"`
+}
+
+func normLabel(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+func isImpulse(p *models.Purchase) bool {
+	return normLabel(p.Necessity) == "low" && impulseTones[normLabel(p.EmotionalTone)]
+}
+
+func impulseScore(impulse, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(impulse)*1000/float64(total)) / 10
+}
+
+// Report cross-tabulates tone × necessity, computes the impulse score, the top triggers of
+// impulsive purchases (from reason_guess) and the trend per period.
+func (s *EmotionService) Report(filter models.PurchaseFilter, period string) (*EmotionReport, error) {
+	if !utils.ValidPeriod(period) {
+		period = utils.PeriodMonthly
+	}
+	purchases, err := s.Purchase.Query(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	type cellKey struct{ tone, necessity, currency string }
+	cells := map[cellKey]*EmotionCell{}
+	type amounts struct {
+		impulse, total int64
+		count          int
+	}
+	byCurrency := map[string]*amounts{}
+	type trendKey struct{ label, currency string }
+	trends := map[trendKey]*EmotionTrend{}
+	trendTotals := map[trendKey]*amounts{}
+	type triggerAcc struct {
+		count    int
+		total    map[string]int64
+		examples []string
+	}
+	triggers := map[string]*triggerAcc{}
+
+	for i := range purchases {
+		p := &purchases[i]
+		cur := p.Amount.Currency
+		tone, necessity := normLabel(p.EmotionalTone), normLabel(p.Necessity)
+		impulse := isImpulse(p)
+
+		ck := cellKey{tone, necessity, cur}
+		if cells[ck] == nil {
+			cells[ck] = &EmotionCell{Tone: tone, Necessity: necessity, Total: models.NewMoney(0, cur)}
+		}
+		cells[ck].Count++
+		cells[ck].Total.Minor += p.Net().Minor
+
+		if byCurrency[cur] == nil {
+			byCurrency[cur] = &amounts{}
+		}
+		byCurrency[cur].total += p.Net().Minor
+
+		start, _, _ := utils.PeriodBounds(period, purchaseDate(p))
+		tk := trendKey{utils.PeriodLabel(period, start), cur}
+		if trends[tk] == nil {
+			trends[tk] = &EmotionTrend{Period: tk.label, Start: start, ByTone: map[string]models.Money{}}
+			trendTotals[tk] = &amounts{}
+		}
+		m := trends[tk].ByTone[tone]
+		trends[tk].ByTone[tone] = models.NewMoney(m.Minor+p.Net().Minor, cur)
+		trendTotals[tk].total += p.Net().Minor
+
+		if !impulse {
+			continue
+		}
+		byCurrency[cur].impulse += p.Net().Minor
+		byCurrency[cur].count++
+		trendTotals[tk].impulse += p.Net().Minor
+		trendTotals[tk].count++
+
+		seen := map[string]bool{}
+		for _, w := range strings.Fields(utils.NormalizeText(p.ReasonGuess)) {
+			if len([]rune(w)) < 3 || triggerStopwords[w] || seen[w] {
+				continue
+			}
+			seen[w] = true
+			t := triggers[w]
+			if t == nil {
+				t = &triggerAcc{total: map[string]int64{}}
+				triggers[w] = t
+			}
+			t.count++
+			t.total[cur] += p.Net().Minor
+			if len(t.examples) < 3 {
+				t.examples = append(t.examples, p.ReasonGuess)
+			}
+		}
+	}
+
+	report := &EmotionReport{Period: period}
+	for _, c := range cells {
+		report.Crosstab = append(report.Crosstab, *c)
+	}
+	sort.Slice(report.Crosstab, func(i, j int) bool {
+		a, b := report.Crosstab[i], report.Crosstab[j]
+		if a.Total.Currency != b.Total.Currency {
+			return a.Total.Currency < b.Total.Currency
+		}
+		return a.Total.Minor > b.Total.Minor
+	})
+
+	for cur, a := range byCurrency {
+		report.Impulse = append(report.Impulse, ImpulseScore{
+			Currency:      cur,
+			ImpulseAmount: models.NewMoney(a.impulse, cur),
+			TotalAmount:   models.NewMoney(a.total, cur),
+			ImpulseCount:  a.count,
+			Score:         impulseScore(a.impulse, a.total),
+		})
+	}
+	sort.Slice(report.Impulse, func(i, j int) bool { return report.Impulse[i].Currency < report.Impulse[j].Currency })
+
+	for w, t := range triggers {
+		// main currency of the trigger = the one with most spending
+		var best string
+		for cur, v := range t.total {
+			if best == "" || v > t.total[best] {
+				best = cur
+			}
+		}
+		report.Triggers = append(report.Triggers, Trigger{Word: w, Count: t.count, Total: models.NewMoney(t.total[best], best), Examples: t.examples})
+	}
+	sort.Slice(report.Triggers, func(i, j int) bool {
+		if report.Triggers[i].Count != report.Triggers[j].Count {
+			return report.Triggers[i].Count > report.Triggers[j].Count
+		}
+		return report.Triggers[i].Word < report.Triggers[j].Word
+	})
+	if len(report.Triggers) > emotionTopTriggers {
+		report.Triggers = report.Triggers[:emotionTopTriggers]
+	}
+
+	for tk, t := range trends {
+		a := trendTotals[tk]
+		t.Impulse = ImpulseScore{
+			Currency:      tk.currency,
+			ImpulseAmount: models.NewMoney(a.impulse, tk.currency),
+			TotalAmount:   models.NewMoney(a.total, tk.currency),
+			ImpulseCount:  a.count,
+			Score:         impulseScore(a.impulse, a.total),
+		}
+		report.Trends = append(report.Trends, *t)
+	}
+	sort.Slice(report.Trends, func(i, j int) bool {
+		if !report.Trends[i].Start.Equal(report.Trends[j].Start) {
+			return report.Trends[i].Start.Before(report.Trends[j].Start)
+		}
+		return report.Trends[i].Impulse.Currency < report.Trends[j].Impulse.Currency
+	})
+	return report, nil
+}
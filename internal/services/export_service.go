@@ -0,0 +1,363 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"example/AI/internal/ledger"
+	"example/AI/internal/models"
+	"example/AI/internal/report"
+	"example/AI/internal/utils"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	ExportCSV    = "csv"
+	ExportXLSX   = "xlsx"
+	ExportNDJSON = "ndjson"
+	ExportPDF    = "pdf"
+	// plain-text accounting journals
+	ExportBeancount = ledger.Beancount
+	ExportHledger   = ledger.Hledger
+)
+
+// fonts tried for the PDF report when REPORT_FONT_PATH is not set
+var defaultReportFonts = []string{
+	"assets/fonts/Vazirmatn-Regular.ttf",
+	"/usr/share/fonts/truetype/vazirmatn/Vazirmatn-Regular.ttf",
+	"/usr/share/fonts/truetype/noto/NotoNaskhArabic-Regular.ttf",
+	"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+}
+
+// ExportService writes purchases out of the system: streamed CSV/XLSX/NDJSON and a PDF report.
+type ExportService struct {
+	Purchase *PurchaseService
+	FontPath string // TTF with Persian glyphs for the PDF report ("" = English fallback)
+}
+
+func NewExportService(ps *PurchaseService, fontPath string) *ExportService {
+	if fontPath == "" {
+		for _, p := range defaultReportFonts {
+			if _, err := os.Stat(p); err == nil {
+				fontPath = p
+				break
+			}
+		}
+	}
+	return &ExportService{Purchase: ps, FontPath: fontPath}
+}
+
+func ValidExportFormat(format string) bool {
+	switch format {
+	case ExportCSV, ExportXLSX, ExportNDJSON, ExportPDF, ExportBeancount, ExportHledger:
+		return true
+	}
+	return false
+}
+
+// ExportContentType is the MIME type of an export format.
+func ExportContentType(format string) string {
+	switch format {
+	case ExportCSV:
+		return "text/csv; charset=utf-8"
+	case ExportXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case ExportNDJSON:
+		return "application/x-ndjson"
+	case ExportPDF:
+		return "application/pdf"
+	case ExportBeancount, ExportHledger:
+		return "text/plain; charset=utf-8"
+	}
+	return "application/octet-stream"
+}
+
+// ExportExtension is the file extension of an export format.
+func ExportExtension(format string) string {
+	switch format {
+	case ExportBeancount:
+		return "beancount"
+	case ExportHledger:
+		return "journal"
+	}
+	return format
+}
+
+var exportColumns = []string{"id", "date", "jalali_date", "title", "amount", "currency", "category", "category_label",
+	"subcategory", "vendor", "status", "necessity", "emotional_tone", "confidence", "user_id", "refunded"}
+
+// each walks the filtered purchases in date order without loading them all at once.
+func (s *ExportService) each(filter models.PurchaseFilter, fn func(p *models.Purchase) error) error {
+	rows, err := applyFilter(s.Purchase.DB.Model(&models.Purchase{}), filter).
+		Order("purchases.purchase_time, purchases.id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.Purchase
+		if err := s.Purchase.DB.ScanRows(rows, &p); err != nil {
+			return err
+		}
+		if err := fn(&p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// categoryLabel caches Persian labels per user/category.
+func (s *ExportService) categoryLabel() func(userID int, key string) string {
+	cache := map[string]string{}
+	return func(userID int, key string) string {
+		if s.Purchase.Taxonomy == nil || key == "" {
+			return key
+		}
+		k := fmt.Sprintf("%d|%s", userID, key)
+		if l, ok := cache[k]; ok {
+			return l
+		}
+		l := s.Purchase.Taxonomy.Label(userID, key, "fa")
+		cache[k] = l
+		return l
+	}
+}
+
+func exportRecord(p *models.Purchase, label string) []string {
+	date, jdate := "", ""
+	if p.PurchaseTime != nil {
+		date = p.PurchaseTime.Format("2006-01-02")
+		jy, jm, jd := utils.ToJalali(*p.PurchaseTime)
+		jdate = fmt.Sprintf("%04d/%02d/%02d", jy, jm, jd)
+	}
+	vendor := ""
+	if p.Vendor != nil {
+		vendor = *p.Vendor
+	}
+	return []string{strconv.FormatUint(p.ID, 10), date, jdate, p.Title, p.Amount.Decimal(), p.Amount.Currency,
+		p.Category, label, p.Subcategory, vendor, p.Status, p.Necessity, p.EmotionalTone,
+		strconv.FormatFloat(p.Confidence, 'f', 2, 64), strconv.Itoa(p.UserID), p.Refunded().Decimal()}
+}
+
+// Stream writes the filtered purchases as CSV, XLSX or NDJSON.
+func (s *ExportService) Stream(w io.Writer, format string, filter models.PurchaseFilter) error {
+	label := s.categoryLabel()
+	switch format {
+	case ExportCSV:
+		// BOM so that Excel opens Persian text as UTF-8
+		if _, err := w.Write([]byte("\xef\xbb\xbf")); err != nil {
+			return err
+		}
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportColumns); err != nil {
+			return err
+		}
+		err := s.each(filter, func(p *models.Purchase) error {
+			return cw.Write(exportRecord(p, label(p.UserID, p.Category)))
+		})
+		if err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case ExportNDJSON:
+		enc := json.NewEncoder(w)
+		return s.each(filter, func(p *models.Purchase) error { return enc.Encode(p) })
+
+	case ExportXLSX:
+		return s.streamXLSX(w, filter, label)
+
+	case ExportBeancount, ExportHledger:
+		return s.each(filter, func(p *models.Purchase) error {
+			txs := []ledger.Transaction{ledger.FromPurchase(p, "")}
+			// refunds are reversing entries, so journal balances match the net totals
+			if p.Refunded().IsPositive() {
+				var refunds []models.Refund
+				if err := s.Purchase.DB.Where("purchase_id = ?", p.ID).Order("id").Find(&refunds).Error; err != nil {
+					return err
+				}
+				for i := range refunds {
+					txs = append(txs, ledger.FromRefund(&refunds[i], p, ""))
+				}
+			}
+			return ledger.Write(w, format, txs...)
+		})
+	}
+	return fmt.Errorf("unsupported export format %q", format)
+}
+
+func (s *ExportService) streamXLSX(w io.Writer, filter models.PurchaseFilter, label func(int, string) string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+	const sheet = "Sheet1"
+	rtl := true
+	if err := f.SetSheetView(sheet, 0, &excelize.ViewOptions{RightToLeft: &rtl}); err != nil {
+		return err
+	}
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return err
+	}
+	bold, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+	header := make([]interface{}, len(exportColumns))
+	for i, c := range exportColumns {
+		header[i] = excelize.Cell{StyleID: bold, Value: c}
+	}
+	if err := sw.SetRow("A1", header); err != nil {
+		return err
+	}
+
+	row := 2
+	err = s.each(filter, func(p *models.Purchase) error {
+		rec := exportRecord(p, label(p.UserID, p.Category))
+		values := make([]interface{}, len(rec))
+		for i, v := range rec {
+			values[i] = v
+		}
+		values[0] = p.ID
+		// amounts as numbers so that they can be summed in the sheet
+		if exp := models.CurrencyExponent(p.Amount.Currency); exp == 0 {
+			values[4], values[15] = p.Amount.Minor, p.RefundedMinor
+		} else {
+			values[4] = float64(p.Amount.Minor) / math.Pow10(exp)
+			values[15] = float64(p.RefundedMinor) / math.Pow10(exp)
+		}
+		values[13] = p.Confidence
+		cellRef, _ := excelize.CoordinatesToCellName(1, row)
+		row++
+		return sw.SetRow(cellRef, values)
+	})
+	if err != nil {
+		return err
+	}
+	if err := sw.Flush(); err != nil {
+		return err
+	}
+	return f.Write(w)
+}
+
+// Report renders the PDF report of the period (monthly, jalali_monthly, ...) containing `at`.
+func (s *ExportService) Report(w io.Writer, userID int, period string, at time.Time) error {
+	if period == "" {
+		period = utils.PeriodMonthly
+	}
+	start, end, err := utils.PeriodBounds(period, at)
+	if err != nil {
+		return err
+	}
+	last := end.Add(-time.Nanosecond)
+	items, err := s.Purchase.Query(models.PurchaseFilter{UserIDs: []int{userID}, FromDate: &start, ToDate: &last})
+	if err != nil {
+		return err
+	}
+	fa := s.FontPath != ""
+	jalali := fa || period == utils.PeriodJalaliMonthly
+
+	d := &report.Monthly{Title: "Spending report", Count: len(items)}
+	if fa {
+		d.Title = "گزارش هزینه‌ها"
+	}
+	dateLabel := func(t time.Time) string {
+		if jalali {
+			jy, jm, jd := utils.ToJalali(t)
+			return fmt.Sprintf("%04d/%02d/%02d", jy, jm, jd)
+		}
+		return t.Format("2006-01-02")
+	}
+	d.Period = dateLabel(start) + " - " + dateLabel(last)
+
+	// totals per currency; the breakdowns use the currency with the largest total
+	byCurrency := map[string]int64{}
+	for _, p := range items {
+		byCurrency[p.Amount.Currency] += p.Net().Minor
+	}
+	primary := ""
+	for c, v := range byCurrency {
+		d.Totals = append(d.Totals, models.NewMoney(v, c))
+		if primary == "" || v > byCurrency[primary] || (v == byCurrency[primary] && c < primary) {
+			primary = c
+		}
+	}
+	sort.Slice(d.Totals, func(i, j int) bool { return d.Totals[i].Minor > d.Totals[j].Minor })
+
+	prevStart, prevEnd, err := utils.PreviousPeriod(period, start)
+	if err == nil {
+		prevLast := prevEnd.Add(-time.Nanosecond)
+		d.Previous, _ = s.Purchase.SumAmount(models.PurchaseFilter{UserIDs: []int{userID}, FromDate: &prevStart, ToDate: &prevLast})
+	}
+
+	days := int(math.Round(end.Sub(start).Hours() / 24))
+	if days < 1 {
+		days = 1
+	}
+	d.DailyAvg = models.NewMoney(byCurrency[primary]/int64(days), primary)
+
+	label := s.categoryLabel()
+	split, err := categoryShares(s.Purchase.DB, items)
+	if err != nil {
+		return err
+	}
+	catTotals := map[string]int64{}
+	daily := make([]int64, days)
+	var top []models.Purchase
+	for _, p := range items {
+		if p.Amount.Currency != primary {
+			continue
+		}
+		for _, sh := range sharesOf(split, &p) {
+			catTotals[sh.Category] += sh.Amount.Minor
+		}
+		if i := int(startOfDay(purchaseDate(&p)).Sub(start).Hours() / 24); i >= 0 && i < days {
+			daily[i] += p.Net().Minor
+		}
+		top = append(top, p)
+	}
+	for cat, v := range catTotals {
+		name := cat
+		if fa {
+			name = label(userID, cat)
+		}
+		if name == "" {
+			name = "-"
+		}
+		d.Categories = append(d.Categories, report.Line{Label: name, Amount: models.NewMoney(v, primary),
+			Percent: round2(float64(v) * 100 / float64(byCurrency[primary]))})
+	}
+	sort.Slice(d.Categories, func(i, j int) bool { return d.Categories[i].Amount.Minor > d.Categories[j].Amount.Minor })
+
+	for i, v := range daily {
+		day := start.AddDate(0, 0, i)
+		l := strconv.Itoa(day.Day())
+		if jalali {
+			_, _, jd := utils.ToJalali(day)
+			l = strconv.Itoa(jd)
+		}
+		d.Daily = append(d.Daily, report.Line{Label: l, Amount: models.NewMoney(v, primary)})
+	}
+
+	sort.SliceStable(top, func(i, j int) bool { return top[i].Net().Minor > top[j].Net().Minor })
+	if len(top) > 10 {
+		top = top[:10]
+	}
+	for _, p := range top {
+		cat := p.Category
+		if fa {
+			cat = label(userID, cat)
+		}
+		d.Top = append(d.Top, report.TopPurchase{Date: dateLabel(purchaseDate(&p)), Title: p.Title, Category: cat, Amount: p.Net()})
+	}
+
+	return report.WriteMonthlyPDF(w, d, s.FontPath)
+}
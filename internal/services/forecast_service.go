@@ -0,0 +1,304 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	forecastHistoryDays = 90
+	forecastAlpha       = 0.3 // exponential smoothing factor
+	// z for the 80% prediction interval
+	forecastIntervalZ    = 1.2816
+	ForecastIntervalProb = 0.8
+)
+
+// ForecastService projects spending to the end of a period: what was spent so far, plus known
+// recurring/scheduled charges, plus the variable spending expected from the recent daily series
+// (weekday-seasonal exponential smoothing).
+type ForecastService struct {
+	DB        *gorm.DB
+	Recurring *RecurringService
+	Schedules *ScheduleService
+	// optional, unpaid installment dues count as known charges
+	Installments *InstallmentService
+}
+
+func NewForecastService(db *gorm.DB, rs *RecurringService, ss *ScheduleService) *ForecastService {
+	return &ForecastService{DB: db, Recurring: rs, Schedules: ss}
+}
+
+type CategoryForecast struct {
+	Category         string       `json:"category"` // "" = all categories
+	SpentSoFar       models.Money `json:"spent_so_far"`
+	KnownRecurring   models.Money `json:"known_recurring"`   // recurring/scheduled charges still to come
+	ExpectedVariable models.Money `json:"expected_variable"` // projected from the daily series
+	Point            models.Money `json:"point"`
+	Low              models.Money `json:"low"`
+	High             models.Money `json:"high"`
+}
+
+type Forecast struct {
+	Period        string             `json:"period"`
+	PeriodStart   time.Time          `json:"period_start"`
+	PeriodEnd     time.Time          `json:"period_end"`
+	AsOf          time.Time          `json:"as_of"`
+	RemainingDays int                `json:"remaining_days"`
+	Interval      float64            `json:"interval"` // coverage of low..high
+	Categories    []CategoryForecast `json:"categories"`
+	Totals        []CategoryForecast `json:"totals"` // one per currency
+}
+
+// smoothDaily forecasts the remaining days of a daily series. history[i] is the spending of
+// historyStart+i days. Returns the expected sum over `days` and its standard deviation.
+func smoothDaily(history []float64, historyStart time.Time, days []time.Time) (float64, float64) {
+	if len(history) == 0 || len(days) == 0 {
+		return 0, 0
+	}
+
+	// weekday seasonal factors (only with at least two full weeks of data)
+	factors := [7]float64{1, 1, 1, 1, 1, 1, 1}
+	mean := 0.0
+	for _, v := range history {
+		mean += v
+	}
+	mean /= float64(len(history))
+	if len(history) >= 14 && mean > 0 {
+		var sums [7]float64
+		var counts [7]int
+		for i, v := range history {
+			wd := historyStart.AddDate(0, 0, i).Weekday()
+			sums[wd] += v
+			counts[wd]++
+		}
+		for wd := range factors {
+			if counts[wd] > 0 {
+				factors[wd] = sums[wd] / float64(counts[wd]) / mean
+			}
+		}
+	}
+
+	// simple exponential smoothing of the deseasonalized series
+	level := mean
+	var sqErr float64
+	for i, v := range history {
+		f := factors[historyStart.AddDate(0, 0, i).Weekday()]
+		if f == 0 {
+			continue
+		}
+		err := v - level*f
+		sqErr += err * err
+		level += forecastAlpha * (v/f - level)
+	}
+	sigma := math.Sqrt(sqErr / float64(len(history)))
+
+	total := 0.0
+	for _, d := range days {
+		total += level * factors[d.Weekday()]
+	}
+	return total, sigma * math.Sqrt(float64(len(days)))
+}
+
+// Forecast projects the user's spending for the period containing `at`
+// (monthly, jalali_monthly, weekly, ...). categories limits the result when not empty.
+func (s *ForecastService) Forecast(userID int, period string, at time.Time, categories []string) (*Forecast, error) {
+	if period == "" {
+		period = utils.PeriodMonthly
+	}
+	start, end, err := utils.PeriodBounds(period, at.UTC())
+	if err != nil {
+		return nil, err
+	}
+	today := startOfDay(at)
+	tomorrow := today.AddDate(0, 0, 1)
+	historyStart := today.AddDate(0, 0, -forecastHistoryDays)
+	if start.Before(historyStart) {
+		historyStart = start
+	}
+
+	// money that leaves the wallet: installment payments, not the full price
+	q := s.DB.Where("user_id = ? AND status <> ? AND basis <> ? AND purchase_time >= ? AND purchase_time < ?",
+		userID, models.StatusRejected, models.BasisAccrual, historyStart, tomorrow)
+	if len(categories) > 0 {
+		q = q.Where("(category IN ? OR id IN (?))", categories,
+			s.DB.Model(&models.PurchaseItem{}).Select("purchase_id").Where("category IN ?", categories))
+	}
+	var purchases []models.Purchase
+	if err := q.Order("purchase_time").Find(&purchases).Error; err != nil {
+		return nil, err
+	}
+	split, err := categoryShares(s.DB, purchases)
+	if err != nil {
+		return nil, err
+	}
+
+	// purchases made by the scheduler are known charges, not variable spending
+	var scheduledIDs []uint64
+	if err := s.DB.Model(&models.ScheduledOccurrence{}).
+		Joins("JOIN scheduled_purchases ON scheduled_purchases.id = scheduled_occurrences.scheduled_purchase_id").
+		Where("scheduled_purchases.user_id = ? AND scheduled_occurrences.purchase_id IS NOT NULL AND scheduled_occurrences.date >= ?", userID, startOfDay(historyStart)).
+		Pluck("scheduled_occurrences.purchase_id", &scheduledIDs).Error; err != nil {
+		return nil, err
+	}
+	scheduled := map[uint64]bool{}
+	for _, id := range scheduledIDs {
+		scheduled[id] = true
+	}
+
+	type key struct{ category, currency string }
+	type acc struct {
+		spent   int64
+		known   int64
+		daily   map[time.Time]float64
+		firstAt time.Time
+	}
+	accs := map[key]*acc{}
+	get := func(k key) *acc {
+		if accs[k] == nil {
+			accs[k] = &acc{daily: map[time.Time]float64{}, firstAt: today}
+		}
+		return accs[k]
+	}
+	add := func(k key, p *models.Purchase, amount int64) {
+		a := get(k)
+		d := startOfDay(purchaseDate(p))
+		if !d.Before(start) {
+			a.spent += amount
+		}
+		if d.Before(a.firstAt) {
+			a.firstAt = d
+		}
+		if p.RecurringSeriesID == nil && p.InstallmentPlanID == nil && !scheduled[p.ID] && d.Before(today) {
+			a.daily[d] += float64(amount)
+		}
+	}
+	wanted := map[string]bool{}
+	for _, c := range categories {
+		wanted[c] = true
+	}
+	for i := range purchases {
+		p := &purchases[i]
+		// mixed purchases count per item category
+		for _, sh := range sharesOf(split, p) {
+			if len(wanted) > 0 && !wanted[sh.Category] {
+				continue
+			}
+			add(key{sh.Category, sh.Amount.Currency}, p, sh.Amount.Minor)
+			add(key{"", sh.Amount.Currency}, p, sh.Amount.Minor)
+		}
+	}
+
+	// known charges still to come this period
+	var upcoming []UpcomingCharge
+	scheduleKeys := map[string]bool{}
+	if s.Schedules != nil {
+		items, err := s.Schedules.Upcoming(userID, tomorrow, end)
+		if err != nil {
+			return nil, err
+		}
+		upcoming = append(upcoming, items...)
+		for _, it := range items {
+			scheduleKeys[seriesKey(&models.Purchase{Title: it.Title, VendorID: it.VendorID})] = true
+		}
+	}
+	if s.Recurring != nil {
+		items, err := s.Recurring.Upcoming(userID, tomorrow, end)
+		if err != nil {
+			return nil, err
+		}
+		seriesKeys, err := s.seriesKeys(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			if !scheduleKeys[seriesKeys[it.SeriesID]] { // already counted via its schedule
+				upcoming = append(upcoming, it)
+			}
+		}
+	}
+	if s.Installments != nil {
+		items, err := s.Installments.Upcoming(userID, tomorrow, end)
+		if err != nil {
+			return nil, err
+		}
+		upcoming = append(upcoming, items...)
+	}
+	for _, u := range upcoming {
+		if len(wanted) > 0 && !wanted[u.Category] {
+			continue
+		}
+		get(key{u.Category, u.Amount.Currency}).known += u.Amount.Minor
+		get(key{"", u.Amount.Currency}).known += u.Amount.Minor
+	}
+
+	var remaining []time.Time
+	for d := tomorrow; d.Before(end); d = d.AddDate(0, 0, 1) {
+		remaining = append(remaining, d)
+	}
+
+	out := &Forecast{
+		Period:        period,
+		PeriodStart:   start,
+		PeriodEnd:     end,
+		AsOf:          at.UTC(),
+		RemainingDays: len(remaining),
+		Interval:      ForecastIntervalProb,
+	}
+	for k, a := range accs {
+		// history from the first purchase of the series (or the lookback start), up to yesterday
+		from := a.firstAt
+		if from.Before(historyStart) {
+			from = historyStart
+		}
+		var history []float64
+		for d := from; d.Before(today); d = d.AddDate(0, 0, 1) {
+			history = append(history, a.daily[d])
+		}
+		expected, sd := smoothDaily(history, from, remaining)
+
+		base := a.spent + a.known
+		point := base + int64(math.Round(expected))
+		low := base + int64(math.Round(math.Max(0, expected-forecastIntervalZ*sd)))
+		high := base + int64(math.Round(expected+forecastIntervalZ*sd))
+		cf := CategoryForecast{
+			Category:         k.category,
+			SpentSoFar:       models.NewMoney(a.spent, k.currency),
+			KnownRecurring:   models.NewMoney(a.known, k.currency),
+			ExpectedVariable: models.NewMoney(int64(math.Round(expected)), k.currency),
+			Point:            models.NewMoney(point, k.currency),
+			Low:              models.NewMoney(low, k.currency),
+			High:             models.NewMoney(high, k.currency),
+		}
+		if k.category == "" {
+			out.Totals = append(out.Totals, cf)
+		} else {
+			out.Categories = append(out.Categories, cf)
+		}
+	}
+	sort.Slice(out.Categories, func(i, j int) bool {
+		if out.Categories[i].Point.Currency != out.Categories[j].Point.Currency {
+			return out.Categories[i].Point.Currency < out.Categories[j].Point.Currency
+		}
+		return out.Categories[i].Point.Minor > out.Categories[j].Point.Minor
+	})
+	sort.Slice(out.Totals, func(i, j int) bool { return out.Totals[i].Point.Currency < out.Totals[j].Point.Currency })
+	return out, nil
+}
+
+func (s *ForecastService) seriesKeys(userID int) (map[uint64]string, error) {
+	var items []models.RecurringSeries
+	if err := s.DB.Select("id, series_key").Where("user_id = ?", userID).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[uint64]string, len(items))
+	for _, it := range items {
+		out[it.ID] = it.Key
+	}
+	return out, nil
+}
@@ -0,0 +1,659 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxImportRows          = 20000
+	DefaultImportBatchSize = 200
+	importCategorizeBatch  = 25
+)
+
+var ErrImportNotFound = errors.New("import not found")
+
+// ImportFields are the purchase fields a column can be mapped to.
+var ImportFields = []string{"date", "title", "amount", "currency", "category", "subcategory", "vendor"}
+
+// header names recognized per field (normalized with utils.NormalizeText)
+var importHeaderNames = map[string][]string{
+	"date":        {"date", "purchase time", "purchase date", "day", "time", "تاریخ", "زمان", "روز"},
+	"title":       {"title", "description", "desc", "item", "name", "details", "عنوان", "شرح", "توضیحات", "کالا", "نام", "بابت"},
+	"amount":      {"amount", "price", "cost", "sum", "total", "debit", "withdrawal", "مبلغ", "قیمت", "هزینه", "برداشت", "بدهکار", "مبلغ ریال", "مبلغ تومان"},
+	"currency":    {"currency", "ارز", "واحد", "واحد پول"},
+	"category":    {"category", "type", "group", "دسته", "دسته بندی", "گروه", "نوع"},
+	"subcategory": {"subcategory", "sub category", "زیر دسته", "زیردسته"},
+	"vendor":      {"vendor", "merchant", "store", "shop", "payee", "فروشنده", "فروشگاه", "مغازه", "پذیرنده"},
+}
+
+// ImportService parses spreadsheet exports into purchases: upload -> preview -> commit.
+type ImportService struct {
+	DB       *gorm.DB
+	Purchase *PurchaseService
+	AI       *AIService // optional, categorizes rows without a category
+}
+
+func NewImportService(db *gorm.DB, ps *PurchaseService, ai *AIService) *ImportService {
+	return &ImportService{DB: db, Purchase: ps, AI: ai}
+}
+
+// ImportPreview is a job with (a page of) its rows and the column mapping.
+type ImportPreview struct {
+	Job     *models.ImportJob  `json:"job"`
+	Headers []string           `json:"headers"`
+	Mapping map[string]string  `json:"mapping"` // field -> header
+	Rows    []models.ImportRow `json:"rows"`
+}
+
+// readTable reads the first sheet of an XLSX file or a CSV (delimiter , ; or tab).
+func readTable(fileName string, data []byte) (string, [][]string, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if ext == ".xlsx" || (ext != ".csv" && ext != ".tsv" && ext != ".txt" && bytes.HasPrefix(data, []byte("PK"))) {
+		f, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid xlsx: %w", err)
+		}
+		defer f.Close()
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return "", nil, errors.New("xlsx has no sheets")
+		}
+		rows, err := f.GetRows(sheets[0])
+		return "xlsx", rows, err
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // BOM
+	firstLine, _ := bufio.NewReader(bytes.NewReader(data)).ReadString('\n')
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = ','
+	best := strings.Count(firstLine, ",")
+	for _, d := range []rune{';', '\t'} {
+		if n := strings.Count(firstLine, string(d)); n > best {
+			r.Comma, best = d, n
+		}
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	var rows [][]string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		rows = append(rows, rec)
+	}
+	return "csv", rows, nil
+}
+
+func cell(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// parseImportAmount reads "1,250,000", "۱٬۲۵۰٬۰۰۰ ریال", "1.250.000" or "-50000".
+func parseImportAmount(raw, currency string) (models.Money, error) {
+	s := utils.NormalizeDigits(raw)
+	if strings.Count(s, ".") > 1 {
+		s = strings.ReplaceAll(s, ".", "")
+	}
+	value, cur, ok := utils.ParseSpokenAmount(s)
+	if !ok {
+		return models.Money{}, fmt.Errorf("invalid amount %q", raw)
+	}
+	if cur != "" {
+		currency = cur
+	}
+	return models.ParseMoney(value, currency)
+}
+
+// DetectMapping guesses the column of each field from the headers, then from the values.
+func DetectMapping(headers []string, sample [][]string) map[string]int {
+	mapping := map[string]int{}
+	used := map[int]bool{}
+	for _, field := range ImportFields {
+		for i, h := range headers {
+			if used[i] {
+				continue
+			}
+			n := utils.NormalizeText(h)
+			for _, name := range importHeaderNames[field] {
+				if n == utils.NormalizeText(name) {
+					mapping[field], used[i] = i, true
+					break
+				}
+			}
+			if _, ok := mapping[field]; ok {
+				break
+			}
+		}
+	}
+
+	// no recognizable header: pick the column where most values parse
+	guess := func(field string, parses func(string) bool) {
+		if _, ok := mapping[field]; ok || len(sample) == 0 {
+			return
+		}
+		bestCol, bestHits := -1, 0
+		for i := range headers {
+			if used[i] {
+				continue
+			}
+			hits := 0
+			for _, row := range sample {
+				if parses(cell(row, i)) {
+					hits++
+				}
+			}
+			if hits > bestHits && hits*2 >= len(sample) {
+				bestCol, bestHits = i, hits
+			}
+		}
+		if bestCol >= 0 {
+			mapping[field], used[bestCol] = bestCol, true
+		}
+	}
+	guess("date", func(v string) bool { _, err := utils.ParseDate(v); return err == nil })
+	guess("amount", func(v string) bool { _, err := parseImportAmount(v, ""); return v != "" && err == nil })
+	guess("title", func(v string) bool { _, err := parseImportAmount(v, ""); return v != "" && err != nil })
+	return mapping
+}
+
+// ResolveMapping turns a user mapping (field -> header name or column index) into indexes.
+func ResolveMapping(headers []string, raw map[string]interface{}) (map[string]int, error) {
+	mapping := map[string]int{}
+	valid := map[string]bool{}
+	for _, f := range ImportFields {
+		valid[f] = true
+	}
+	for field, v := range raw {
+		if !valid[field] {
+			return nil, fmt.Errorf("unknown field %q (%s)", field, strings.Join(ImportFields, ", "))
+		}
+		switch t := v.(type) {
+		case nil:
+			continue
+		case float64:
+			mapping[field] = int(t)
+		case json.Number:
+			n, err := strconv.Atoi(t.String())
+			if err != nil {
+				return nil, fmt.Errorf("invalid column for %s", field)
+			}
+			mapping[field] = n
+		case string:
+			if t == "" {
+				continue
+			}
+			found := false
+			for i, h := range headers {
+				if utils.NormalizeText(h) == utils.NormalizeText(t) {
+					mapping[field], found = i, true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("column %q not found", t)
+			}
+		default:
+			return nil, fmt.Errorf("invalid column for %s", field)
+		}
+		if mapping[field] < 0 || mapping[field] >= len(headers) {
+			return nil, fmt.Errorf("column %d out of range for %s", mapping[field], field)
+		}
+	}
+	return mapping, nil
+}
+
+// parseRow validates one line with the mapping.
+func (s *ImportService) parseRow(userID int, row *models.ImportRow, cells []string, mapping map[string]int, currency string) {
+	get := func(field string) string {
+		i, ok := mapping[field]
+		if !ok {
+			return ""
+		}
+		return cell(cells, i)
+	}
+	var errs []string
+	row.Title, row.Category, row.Subcategory = get("title"), get("category"), get("subcategory")
+	row.Vendor, row.PurchaseTime, row.DuplicateOf = nil, nil, nil
+	if v := get("vendor"); v != "" {
+		row.Vendor = &v
+		if row.Title == "" {
+			row.Title = v
+		}
+	}
+	if row.Title == "" {
+		errs = append(errs, "title: missing")
+	}
+
+	if v := get("date"); v == "" {
+		errs = append(errs, "date: missing")
+	} else if d, err := utils.ParseDate(v); err != nil {
+		errs = append(errs, "date: "+err.Error())
+	} else {
+		row.PurchaseTime = &d
+	}
+
+	cur := currency
+	if v := get("currency"); v != "" {
+		cur = v
+	}
+	if v := get("amount"); v == "" {
+		errs = append(errs, "amount: missing")
+	} else if m, err := parseImportAmount(v, cur); err != nil {
+		errs = append(errs, "amount: "+err.Error())
+	} else if !m.IsPositive() {
+		errs = append(errs, "amount: must not be zero")
+	} else {
+		row.Amount = m
+	}
+
+	if row.Category != "" && s.Purchase.Taxonomy != nil {
+		if cat, sub, err := s.Purchase.Taxonomy.Resolve(userID, row.Category, row.Subcategory); err == nil {
+			row.Category, row.Subcategory = cat, sub
+		}
+	}
+
+	row.Errors = strings.Join(errs, "; ")
+	row.Status = models.RowValid
+	if len(errs) > 0 {
+		row.Status = models.RowInvalid
+	}
+}
+
+// markDuplicates flags rows that probably duplicate an existing purchase (see DuplicateService)
+// or an earlier row of the file.
+func (s *ImportService) markDuplicates(userID int, rows []models.ImportRow) error {
+	var minDate, maxDate *time.Time
+	for i := range rows {
+		if d := rows[i].PurchaseTime; rows[i].Status == models.RowValid && d != nil {
+			if minDate == nil || d.Before(*minDate) {
+				minDate = d
+			}
+			if maxDate == nil || d.After(*maxDate) {
+				maxDate = d
+			}
+		}
+	}
+	if minDate == nil {
+		return nil
+	}
+	var existing []models.Purchase
+	if s.Purchase.Duplicates != nil {
+		var err error
+		if existing, err = s.Purchase.Duplicates.Candidates(userID, *minDate, *maxDate); err != nil {
+			return err
+		}
+	}
+
+	seen := map[string]int{}
+	for i := range rows {
+		r := &rows[i]
+		if r.Status != models.RowValid {
+			continue
+		}
+		if len(existing) > 0 {
+			p := &models.Purchase{UserID: userID, Title: r.Title, Amount: r.Amount, Vendor: r.Vendor, PurchaseTime: r.PurchaseTime}
+			if orig, _ := s.Purchase.Duplicates.MatchIn(p, existing); orig != nil {
+				id := orig.ID
+				r.Status, r.DuplicateOf = models.RowDuplicate, &id
+				r.Errors = DuplicateWarning(id)
+				continue
+			}
+		}
+		fileKey := fmt.Sprintf("%s|%d|%s|%s", r.PurchaseTime.Format("2006-01-02"), r.Amount.Minor, r.Amount.Currency, utils.NormalizeText(r.Title))
+		if prev, ok := seen[fileKey]; ok {
+			r.Status = models.RowDuplicate
+			r.Errors = fmt.Sprintf("same as row %d", prev)
+			continue
+		}
+		seen[fileKey] = r.RowNum
+	}
+	return nil
+}
+
+func countRows(job *models.ImportJob, rows []models.ImportRow) {
+	job.Total, job.Valid, job.Invalid, job.Duplicates = len(rows), 0, 0, 0
+	for _, r := range rows {
+		switch r.Status {
+		case models.RowValid:
+			job.Valid++
+		case models.RowInvalid:
+			job.Invalid++
+		case models.RowDuplicate:
+			job.Duplicates++
+		}
+	}
+}
+
+// Upload parses a file into a preview job. mapping may be nil (auto-detect).
+func (s *ImportService) Upload(userID int, fileName string, data []byte, mapping map[string]interface{}, currency string) (*ImportPreview, error) {
+	format, table, err := readTable(fileName, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(table) < 2 {
+		return nil, errors.New("file needs a header row and at least one data row")
+	}
+	if len(table)-1 > MaxImportRows {
+		return nil, fmt.Errorf("too many rows (max %d)", MaxImportRows)
+	}
+	headers, body := table[0], table[1:]
+
+	cols := DetectMapping(headers, body[:min(len(body), 20)])
+	if len(mapping) > 0 {
+		if cols, err = ResolveMapping(headers, mapping); err != nil {
+			return nil, err
+		}
+	}
+
+	jobCurrency, err := models.ParseCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	job := &models.ImportJob{
+		UserID:   userID,
+		FileName: fileName,
+		Format:   format,
+		Status:   models.ImportPreview,
+		Currency: jobCurrency,
+	}
+	hb, _ := json.Marshal(headers)
+	mb, _ := json.Marshal(cols)
+	job.Headers, job.Mapping = string(hb), string(mb)
+
+	rows := make([]models.ImportRow, 0, len(body))
+	for i, cells := range body {
+		if strings.TrimSpace(strings.Join(cells, "")) == "" {
+			continue // empty line
+		}
+		raw, _ := json.Marshal(cells)
+		row := models.ImportRow{RowNum: i + 2, Raw: string(raw)}
+		s.parseRow(userID, &row, cells, cols, job.Currency)
+		rows = append(rows, row)
+	}
+	if err := s.markDuplicates(userID, rows); err != nil {
+		return nil, err
+	}
+	countRows(job, rows)
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		for i := range rows {
+			rows[i].JobID = job.ID
+		}
+		return tx.CreateInBatches(rows, DefaultImportBatchSize).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.preview(job, rows, "", 100), nil
+}
+
+func (s *ImportService) getJob(userID int, id uint64) (*models.ImportJob, error) {
+	var job models.ImportJob
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImportNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *ImportService) preview(job *models.ImportJob, rows []models.ImportRow, status string, limit int) *ImportPreview {
+	var headers []string
+	var cols map[string]int
+	_ = json.Unmarshal([]byte(job.Headers), &headers)
+	_ = json.Unmarshal([]byte(job.Mapping), &cols)
+	mapping := map[string]string{}
+	for field, i := range cols {
+		if i >= 0 && i < len(headers) {
+			mapping[field] = headers[i]
+		}
+	}
+	out := &ImportPreview{Job: job, Headers: headers, Mapping: mapping, Rows: []models.ImportRow{}}
+	for _, r := range rows {
+		if status != "" && r.Status != status {
+			continue
+		}
+		if limit > 0 && len(out.Rows) >= limit {
+			break
+		}
+		out.Rows = append(out.Rows, r)
+	}
+	return out
+}
+
+// Preview returns the job with its rows (optionally only one status).
+func (s *ImportService) Preview(userID int, id uint64, status string, limit int) (*ImportPreview, error) {
+	job, err := s.getJob(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	var rows []models.ImportRow
+	if err := s.DB.Where("job_id = ?", job.ID).Order("row_num").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	return s.preview(job, rows, status, limit), nil
+}
+
+// Remap re-parses the stored rows of a job with a new mapping / default currency.
+func (s *ImportService) Remap(userID int, id uint64, mapping map[string]interface{}, currency string) (*ImportPreview, error) {
+	job, err := s.getJob(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.ImportPreview {
+		return nil, fmt.Errorf("import is %s", job.Status)
+	}
+	var headers []string
+	_ = json.Unmarshal([]byte(job.Headers), &headers)
+	cols, err := ResolveMapping(headers, mapping)
+	if err != nil {
+		return nil, err
+	}
+	if currency != "" {
+		if job.Currency, err = models.ParseCurrency(currency); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []models.ImportRow
+	if err := s.DB.Where("job_id = ?", job.ID).Order("row_num").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		var cells []string
+		_ = json.Unmarshal([]byte(rows[i].Raw), &cells)
+		s.parseRow(userID, &rows[i], cells, cols, job.Currency)
+	}
+	if err := s.markDuplicates(userID, rows); err != nil {
+		return nil, err
+	}
+	countRows(job, rows)
+	mb, _ := json.Marshal(cols)
+	job.Mapping = string(mb)
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(job).Error; err != nil {
+			return err
+		}
+		for i := range rows {
+			if err := tx.Save(&rows[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.preview(job, rows, "", 100), nil
+}
+
+type ImportCommitOptions struct {
+	IncludeDuplicates bool // import rows flagged as duplicates too
+	Categorize        bool // send rows without a category to the AI categorizer
+	BatchSize         int
+}
+
+// categorize fills the category of uncategorized rows with the AI, in batches.
+func (s *ImportService) categorize(userID int, rows []models.ImportRow) {
+	if s.AI == nil || s.Purchase.Taxonomy == nil {
+		return
+	}
+	allowed := s.Purchase.Taxonomy.PromptContext(userID, "")
+	var pending []int
+	flush := func() {
+		items := make([]CategorizeItem, 0, len(pending))
+		for _, i := range pending {
+			it := CategorizeItem{ID: i, Title: rows[i].Title, Amount: rows[i].Amount.String()}
+			if rows[i].Vendor != nil {
+				it.Vendor = *rows[i].Vendor
+			}
+			items = append(items, it)
+		}
+		pending = pending[:0]
+		guesses, err := s.AI.CategorizeBatch(allowed, items)
+		if err != nil {
+			log.Printf("import categorize error: %v", err)
+			return
+		}
+		for _, g := range guesses {
+			if g.ID >= 0 && g.ID < len(rows) && rows[g.ID].Category == "" {
+				rows[g.ID].Category, rows[g.ID].Subcategory = g.Category, g.Subcategory
+			}
+		}
+	}
+	for i := range rows {
+		if rows[i].Category != "" {
+			continue
+		}
+		pending = append(pending, i)
+		if len(pending) == importCategorizeBatch {
+			flush()
+		}
+	}
+	if len(pending) > 0 {
+		flush()
+	}
+}
+
+// Commit turns the valid rows into purchases, one transaction per batch. Rows of a failed
+// batch stay pending, so the commit can be retried.
+func (s *ImportService) Commit(userID int, id uint64, opts ImportCommitOptions) (*models.ImportJob, error) {
+	job, err := s.getJob(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == models.ImportCommitted || job.Status == models.ImportReconciled {
+		return nil, fmt.Errorf("import already %s", job.Status)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultImportBatchSize
+	}
+
+	statuses := []string{models.RowValid}
+	if opts.IncludeDuplicates {
+		statuses = append(statuses, models.RowDuplicate)
+	}
+	var rows []models.ImportRow
+	if err := s.DB.Where("job_id = ? AND status IN ?", job.ID, statuses).Order("row_num").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if opts.Categorize {
+		s.categorize(userID, rows)
+	}
+
+	// imported rows land on the default account, like purchases added by message
+	defaults := models.Purchase{UserID: userID}
+	s.Purchase.assignAccount(&defaults, "")
+
+	now := time.Now().UTC()
+	for start := 0; start < len(rows); start += opts.BatchSize {
+		batch := rows[start:min(start+opts.BatchSize, len(rows))]
+		purchases := make([]models.Purchase, len(batch))
+		for i := range batch {
+			r := &batch[i]
+			purchases[i] = models.Purchase{
+				UserID:       userID,
+				Title:        r.Title,
+				Amount:       r.Amount,
+				Category:     r.Category,
+				Subcategory:  r.Subcategory,
+				Vendor:       r.Vendor,
+				PurchaseTime: r.PurchaseTime,
+				AccountID:    defaults.AccountID,
+				CreatedAt:    now,
+				Confidence:   1,
+				Status:       models.StatusConfirmed,
+				SourceText:   fmt.Sprintf("import #%d row %d", job.ID, r.RowNum),
+			}
+			if r.DuplicateOf != nil {
+				// imported anyway: stays out of the totals until reviewed
+				purchases[i].DuplicateOf = r.DuplicateOf
+				purchases[i].Status = models.StatusGuessed
+				purchases[i].ReviewReasons = reasonDuplicate
+			}
+			if err := s.Purchase.Normalize(&purchases[i]); err != nil {
+				return nil, err
+			}
+		}
+
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&purchases).Error; err != nil {
+				return err
+			}
+			for i := range batch {
+				if err := tx.Model(&batch[i]).Updates(map[string]interface{}{
+					"status":      models.RowImported,
+					"purchase_id": purchases[i].ID,
+					"category":    purchases[i].Category,
+					"subcategory": purchases[i].Subcategory,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Model(job).Update("imported", gorm.Expr("imported + ?", len(batch))).Error
+		})
+		if err != nil {
+			job.Status, job.Error = models.ImportFailed, fmt.Sprintf("rows %d-%d: %v", batch[0].RowNum, batch[len(batch)-1].RowNum, err)
+			_ = s.DB.Model(job).Updates(map[string]interface{}{"status": job.Status, "error": job.Error}).Error
+			return job, err
+		}
+		job.Imported += len(batch)
+		if s.Purchase.Classifier != nil {
+			for i := range purchases {
+				s.Purchase.Classifier.Observe(&purchases[i])
+			}
+		}
+	}
+
+	job.Status, job.Error = models.ImportCommitted, ""
+	return job, s.DB.Model(job).Updates(map[string]interface{}{"status": job.Status, "error": ""}).Error
+}
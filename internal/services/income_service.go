@@ -0,0 +1,295 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// max periods in one cash-flow report
+const maxCashFlowPeriods = 36
+
+// IncomeService keeps incomes and compares them with spending (cash flow, savings rate).
+type IncomeService struct {
+	DB       *gorm.DB
+	Purchase *PurchaseService
+	Accounts *AccountService // optional, links incomes to accounts
+}
+
+func NewIncomeService(db *gorm.DB, ps *PurchaseService) *IncomeService {
+	return &IncomeService{DB: db, Purchase: ps}
+}
+
+var ErrIncomeNotFound = errors.New("income not found")
+
+// CashFlowLine is one currency of a cash-flow period.
+type CashFlowLine struct {
+	Currency    string       `json:"currency"`
+	Income      models.Money `json:"income"`
+	Expenses    models.Money `json:"expenses"`
+	Net         models.Money `json:"net"`
+	SavingsRate *float64     `json:"savings_rate"` // net / income in percent, nil without income
+}
+
+type CashFlowPeriod struct {
+	Label string         `json:"label"`
+	Start time.Time      `json:"start"`
+	End   time.Time      `json:"end"`
+	Lines []CashFlowLine `json:"lines"`
+}
+
+func validIncomeKind(kind string) bool {
+	for _, k := range models.IncomeKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *IncomeService) validate(in *models.Income) error {
+	in.Title = strings.TrimSpace(in.Title)
+	if !in.Amount.IsPositive() {
+		return errors.New("income amount must be positive")
+	}
+	in.Kind = strings.ToLower(strings.TrimSpace(in.Kind))
+	if in.Kind == "" {
+		in.Kind = models.IncomeOther
+	}
+	if !validIncomeKind(in.Kind) {
+		return fmt.Errorf("invalid kind %q (%s)", in.Kind, strings.Join(models.IncomeKinds, ", "))
+	}
+	if in.Title == "" {
+		in.Title = in.Kind
+	}
+	if in.Status == "" {
+		in.Status = models.StatusConfirmed
+	}
+	if in.ReceivedAt == nil {
+		now := time.Now().UTC()
+		in.ReceivedAt = &now
+	}
+	return nil
+}
+
+func (s *IncomeService) Create(in *models.Income) error {
+	if err := s.validate(in); err != nil {
+		return err
+	}
+	if in.CreatedAt.IsZero() {
+		in.CreatedAt = time.Now().UTC()
+	}
+	return s.DB.Create(in).Error
+}
+
+// CreateFromAIData saves an income from the model's "income" object
+// ({"title", "amount", "currency", "kind", "source", "received_at"}).
+func (s *IncomeService) CreateFromAIData(userID int, data map[string]interface{}, sourceText string) (*models.Income, error) {
+	str := func(k string) string {
+		v, _ := data[k].(string)
+		return strings.TrimSpace(v)
+	}
+	amount, err := toMoney(data["amount"], str("currency"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid income amount: %w", err)
+	}
+	in := &models.Income{
+		UserID:     userID,
+		Title:      str("title"),
+		Amount:     amount,
+		Kind:       str("kind"),
+		Source:     str("source"),
+		SourceText: sourceText,
+	}
+	if !validIncomeKind(strings.ToLower(in.Kind)) {
+		in.Kind = models.IncomeOther
+	}
+	if raw := str("received_at"); raw != "" {
+		if t, err := utils.ParseDate(raw); err == nil {
+			in.ReceivedAt = &t
+		}
+	}
+	if s.Accounts != nil {
+		if acc, err := s.Accounts.Resolve(userID, str("account")); err == nil && acc != nil {
+			in.AccountID = &acc.ID
+		}
+	}
+	if err := s.Create(in); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// applyIncomeFilter uses the users, dates and status of a PurchaseFilter.
+func applyIncomeFilter(db *gorm.DB, filter models.PurchaseFilter) *gorm.DB {
+	if len(filter.UserIDs) > 0 {
+		db = db.Where("incomes.user_id IN ?", filter.UserIDs)
+	}
+	if filter.FromDate != nil {
+		db = db.Where("incomes.received_at >= ?", *filter.FromDate)
+	}
+	if filter.ToDate != nil {
+		db = db.Where("incomes.received_at <= ?", *filter.ToDate)
+	}
+	if filter.IncludeUnconfirmed {
+		db = db.Where("incomes.status <> ?", models.StatusRejected)
+	} else {
+		db = db.Where("incomes.status = ?", models.StatusConfirmed)
+	}
+	return db
+}
+
+func (s *IncomeService) List(filter models.PurchaseFilter) ([]models.Income, error) {
+	var items []models.Income
+	err := applyIncomeFilter(s.DB.Model(&models.Income{}), filter).
+		Order("received_at desc").Find(&items).Error
+	return items, err
+}
+
+// Sum returns one total per currency.
+func (s *IncomeService) Sum(filter models.PurchaseFilter) ([]models.Money, error) {
+	type Row struct {
+		Currency string
+		Total    int64
+	}
+	var rows []Row
+	if err := applyIncomeFilter(s.DB.Model(&models.Income{}), filter).
+		Select("amount_currency AS currency, SUM(amount_minor) AS total").
+		Group("amount_currency").
+		Order("amount_currency").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	totals := make([]models.Money, 0, len(rows))
+	for _, r := range rows {
+		totals = append(totals, models.NewMoney(r.Total, r.Currency))
+	}
+	return totals, nil
+}
+
+// SetStatus confirms (or rejects) an income, e.g. one guessed from a bank SMS.
+func (s *IncomeService) SetStatus(userID int, id uint64, status string) (*models.Income, error) {
+	var in models.Income
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&in).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIncomeNotFound
+		}
+		return nil, err
+	}
+	in.Status = status
+	if err := s.DB.Model(&in).Update("status", status).Error; err != nil {
+		return nil, err
+	}
+	return &in, nil
+}
+
+func (s *IncomeService) Delete(userID int, id uint64) error {
+	res := s.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Income{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrIncomeNotFound
+	}
+	return nil
+}
+
+// CashFlow compares income and spending per period for the filter's users, dates and status.
+// Without dates it covers the last 6 periods.
+func (s *IncomeService) CashFlow(filter models.PurchaseFilter, period string) ([]CashFlowPeriod, error) {
+	if period == "" {
+		period = utils.PeriodMonthly
+	}
+	if !utils.ValidPeriod(period) {
+		return nil, fmt.Errorf("invalid period %q", period)
+	}
+	to := time.Now().UTC()
+	if filter.ToDate != nil {
+		to = *filter.ToDate
+	}
+	start, _, err := utils.PeriodBounds(period, to)
+	if err != nil {
+		return nil, err
+	}
+	if filter.FromDate != nil {
+		if start, _, err = utils.PeriodBounds(period, *filter.FromDate); err != nil {
+			return nil, err
+		}
+	} else {
+		for i := 0; i < 5; i++ {
+			if start, _, err = utils.PreviousPeriod(period, start); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var out []CashFlowPeriod
+	for ps := start; !ps.After(to) && len(out) < maxCashFlowPeriods; {
+		_, pe, err := utils.PeriodBounds(period, ps)
+		if err != nil {
+			return nil, err
+		}
+		pf := filter
+		from, end := ps, pe.Add(-time.Nanosecond)
+		pf.FromDate, pf.ToDate = &from, &end
+		lines, err := s.cashFlowLines(pf)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, CashFlowPeriod{Label: utils.PeriodLabel(period, ps), Start: ps, End: pe, Lines: lines})
+		ps = pe
+	}
+	return out, nil
+}
+
+// cashFlowLines is income - spending per currency for one range.
+func (s *IncomeService) cashFlowLines(pf models.PurchaseFilter) ([]CashFlowLine, error) {
+	income, err := s.Sum(pf)
+	if err != nil {
+		return nil, err
+	}
+	// categories/amounts only narrow the spending side
+	expenses, err := s.Purchase.SumAmount(pf)
+	if err != nil {
+		return nil, err
+	}
+	byCur := map[string]*CashFlowLine{}
+	var order []string
+	line := func(cur string) *CashFlowLine {
+		l, ok := byCur[cur]
+		if !ok {
+			l = &CashFlowLine{Currency: cur, Income: models.NewMoney(0, cur), Expenses: models.NewMoney(0, cur)}
+			byCur[cur] = l
+			order = append(order, cur)
+		}
+		return l
+	}
+	for _, m := range income {
+		line(m.Currency).Income = m
+	}
+	for _, m := range expenses {
+		line(m.Currency).Expenses = m
+	}
+	lines := make([]CashFlowLine, 0, len(order))
+	for _, cur := range order {
+		l := byCur[cur]
+		l.Net = models.NewMoney(l.Income.Minor-l.Expenses.Minor, cur)
+		if l.Income.Minor > 0 {
+			rate := round2(float64(l.Net.Minor) * 100 / float64(l.Income.Minor))
+			l.SavingsRate = &rate
+		}
+		lines = append(lines, *l)
+	}
+	return lines, nil
+}
+
+// Summary is the cash flow of the whole range of the filter (for the analysis payload).
+func (s *IncomeService) Summary(filter models.PurchaseFilter) ([]CashFlowLine, error) {
+	return s.cashFlowLines(filter)
+}
@@ -0,0 +1,560 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/schedule"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// most dues one plan can have (30 years of monthly payments)
+const maxInstallments = 360
+
+var (
+	ErrPlanNotFound = errors.New("installment plan not found")
+	ErrPlanPaid     = errors.New("installment plan is already paid off")
+)
+
+// InstallmentService keeps installment plans, their dues and the remaining debt.
+type InstallmentService struct {
+	DB       *gorm.DB
+	Purchase *PurchaseService
+
+	mu sync.Mutex // one payment at a time (auto-pay vs. API)
+}
+
+func NewInstallmentService(db *gorm.DB, ps *PurchaseService) *InstallmentService {
+	return &InstallmentService{DB: db, Purchase: ps}
+}
+
+// InstallmentStatus is a plan with its dues and where the payments stand.
+type InstallmentStatus struct {
+	Plan      models.InstallmentPlan  `json:"plan"`
+	Dues      []models.InstallmentDue `json:"dues"`
+	Paid      models.Money            `json:"paid"`
+	Remaining models.Money            `json:"remaining"`
+	NextDue   *models.InstallmentDue  `json:"next_due"`
+	Overdue   int                     `json:"overdue"` // unpaid dues before today
+}
+
+// InstallmentDebt is what is still owed, per currency.
+type InstallmentDebt struct {
+	Remaining []models.Money      `json:"remaining"`
+	Overdue   []models.Money      `json:"overdue"`
+	Plans     []InstallmentStatus `json:"plans"`
+}
+
+// prepare validates a plan, fills the defaults and maps category/vendor like normal purchases.
+func (s *InstallmentService) prepare(plan *models.InstallmentPlan) (*schedule.Rule, error) {
+	plan.Title = strings.TrimSpace(plan.Title)
+	if plan.Title == "" {
+		return nil, errors.New("title is required")
+	}
+	if !plan.Total.IsPositive() {
+		return nil, errors.New("total must be positive")
+	}
+	if plan.DownPayment.Currency == "" || plan.DownPayment.IsZero() {
+		plan.DownPayment = models.NewMoney(plan.DownPayment.Minor, plan.Total.Currency)
+	}
+	if plan.DownPayment.Currency != plan.Total.Currency {
+		return nil, errors.New("down payment and total must be in the same currency")
+	}
+	if plan.DownPayment.Minor < 0 || plan.DownPayment.Minor >= plan.Total.Minor {
+		return nil, errors.New("down payment must be less than the total")
+	}
+	if plan.Count < 1 || plan.Count > maxInstallments {
+		return nil, fmt.Errorf("count must be between 1 and %d", maxInstallments)
+	}
+	if plan.PurchasedAt.IsZero() {
+		plan.PurchasedAt = time.Now().UTC()
+	}
+	if plan.Rule == "" {
+		plan.Rule = "FREQ=MONTHLY"
+	}
+	rule, err := schedule.Parse(plan.Rule)
+	if err != nil {
+		return nil, err
+	}
+	rule.Count, rule.Until = 0, nil
+	if plan.FirstDue.IsZero() {
+		// one period after the purchase (same Jalali day for JMONTHLY)
+		next, ok := rule.Next(plan.PurchasedAt, startOfDay(plan.PurchasedAt).AddDate(0, 0, 1))
+		if !ok {
+			return nil, errors.New("schedule has no date after the purchase")
+		}
+		plan.FirstDue = next
+	}
+	plan.FirstDue = plan.FirstDue.UTC().Truncate(24 * time.Hour)
+	rule.Count = plan.Count
+	plan.Rule = rule.String()
+	if plan.Status == "" {
+		plan.Status = models.PlanActive
+	}
+	return rule, nil
+}
+
+// dues splits what is left after the down payment into Count dues; the remainder of the
+// division goes to the last one.
+func (s *InstallmentService) dues(plan *models.InstallmentPlan, rule *schedule.Rule) ([]models.InstallmentDue, error) {
+	var dates []time.Time
+	rule.Each(plan.FirstDue, func(d time.Time) bool {
+		dates = append(dates, d)
+		return true
+	})
+	if len(dates) != plan.Count {
+		return nil, fmt.Errorf("schedule gives %d dates for %d installments", len(dates), plan.Count)
+	}
+	cur := plan.Total.Currency
+	rest := plan.Total.Minor - plan.DownPayment.Minor
+	each := rest / int64(plan.Count)
+	var out []models.InstallmentDue
+	if plan.DownPayment.IsPositive() {
+		out = append(out, models.InstallmentDue{Seq: 0, DueDate: startOfDay(plan.PurchasedAt), Amount: plan.DownPayment})
+	}
+	for i, d := range dates {
+		amount := each
+		if i == len(dates)-1 {
+			amount = rest - each*int64(plan.Count-1)
+		}
+		out = append(out, models.InstallmentDue{Seq: i + 1, DueDate: d, Amount: models.NewMoney(amount, cur)})
+	}
+	return out, nil
+}
+
+// Create saves the plan with its full-price purchase and dues; the down payment is paid
+// right away, and with AutoPay every due date that already passed too.
+func (s *InstallmentService) Create(plan *models.InstallmentPlan) (*InstallmentStatus, error) {
+	rule, err := s.prepare(plan)
+	if err != nil {
+		return nil, err
+	}
+	bought := plan.PurchasedAt
+	p := &models.Purchase{
+		UserID:       plan.UserID,
+		Title:        plan.Title,
+		Amount:       plan.Total,
+		Category:     plan.Category,
+		Subcategory:  plan.Subcategory,
+		Vendor:       plan.Vendor,
+		PurchaseTime: &bought,
+		CreatedAt:    time.Now().UTC(),
+		ReasonGuess:  "خرید اقساطی",
+		Confidence:   1,
+		Status:       models.StatusConfirmed,
+		SourceText:   plan.SourceText,
+		AccountID:    plan.AccountID,
+		Basis:        models.BasisAccrual,
+	}
+	if err := s.Purchase.Normalize(p); err != nil {
+		return nil, err
+	}
+	plan.Category, plan.Subcategory, plan.Vendor, plan.VendorID = p.Category, p.Subcategory, p.Vendor, p.VendorID
+	dues, err := s.dues(plan, rule)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(plan).Error; err != nil {
+			return err
+		}
+		p.InstallmentPlanID = &plan.ID
+		if err := tx.Create(p).Error; err != nil {
+			return err
+		}
+		plan.PurchaseID = &p.ID
+		if err := tx.Model(plan).Update("purchase_id", p.ID).Error; err != nil {
+			return err
+		}
+		for i := range dues {
+			dues[i].PlanID, dues[i].CreatedAt = plan.ID, time.Now().UTC()
+		}
+		return tx.Create(&dues).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.Purchase.Classifier != nil {
+		s.Purchase.Classifier.Observe(p)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range dues {
+		d := &dues[i]
+		if d.Seq == 0 || (plan.AutoPay && !d.DueDate.After(time.Now().UTC())) {
+			if err := s.pay(plan, d, d.DueDate); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s.Status(plan.UserID, plan.ID)
+}
+
+// FromAIData creates a plan from the model's "installment" object
+// ({"title", "total", "down_payment", "currency", "count", "rrule", "first_due", "purchase_time",
+// "category", "subcategory", "vendor", "account", "auto_pay"}).
+func (s *InstallmentService) FromAIData(userID int, data map[string]interface{}, source string) (*InstallmentStatus, error) {
+	str := func(k string) string {
+		v, _ := data[k].(string)
+		return strings.TrimSpace(v)
+	}
+	total, err := toMoney(data["total"], str("currency"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid total: %w", err)
+	}
+	plan := &models.InstallmentPlan{
+		UserID:      userID,
+		Title:       str("title"),
+		Total:       total,
+		Category:    str("category"),
+		Subcategory: str("subcategory"),
+		Rule:        str("rrule"),
+		SourceText:  source,
+	}
+	if v, ok := data["down_payment"]; ok && v != nil {
+		if m, err := toMoney(v, total.Currency); err == nil {
+			plan.DownPayment = m
+		}
+	}
+	if v, ok := data["count"]; ok {
+		if f, err := toFloat64(v); err == nil {
+			plan.Count = int(math.Round(f))
+		}
+	}
+	if v, ok := data["auto_pay"].(bool); ok {
+		plan.AutoPay = v
+	}
+	if v := str("vendor"); v != "" {
+		plan.Vendor = &v
+	}
+	for key, dst := range map[string]*time.Time{"purchase_time": &plan.PurchasedAt, "first_due": &plan.FirstDue} {
+		if raw := str(key); raw != "" {
+			if d, err := utils.ParseDate(raw); err == nil {
+				*dst = d
+			}
+		}
+	}
+	if s.Purchase.Accounts != nil {
+		if acc, err := s.Purchase.Accounts.Resolve(userID, str("account")); err == nil && acc != nil {
+			plan.AccountID = &acc.ID
+		}
+	}
+	return s.Create(plan)
+}
+
+func (s *InstallmentService) get(userID int, id uint64) (*models.InstallmentPlan, error) {
+	var plan models.InstallmentPlan
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanNotFound
+		}
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// Status returns one plan with its dues and the paid/remaining amounts.
+func (s *InstallmentService) Status(userID int, id uint64) (*InstallmentStatus, error) {
+	plan, err := s.get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	var dues []models.InstallmentDue
+	if err := s.DB.Where("plan_id = ?", plan.ID).Order("seq").Find(&dues).Error; err != nil {
+		return nil, err
+	}
+	return planStatus(*plan, dues, time.Now().UTC()), nil
+}
+
+func planStatus(plan models.InstallmentPlan, dues []models.InstallmentDue, now time.Time) *InstallmentStatus {
+	cur := plan.Total.Currency
+	st := &InstallmentStatus{Plan: plan, Dues: dues, Paid: models.NewMoney(0, cur), Remaining: models.NewMoney(0, cur)}
+	today := startOfDay(now)
+	for i := range dues {
+		d := &dues[i]
+		if d.PaidAt != nil {
+			st.Paid.Minor += d.Amount.Minor
+			continue
+		}
+		st.Remaining.Minor += d.Amount.Minor
+		if st.NextDue == nil {
+			st.NextDue = d
+		}
+		if d.DueDate.Before(today) {
+			st.Overdue++
+		}
+	}
+	return st
+}
+
+// List returns the user's plans (only the unpaid ones unless all is set), newest first.
+func (s *InstallmentService) List(userID int, all bool) ([]InstallmentStatus, error) {
+	q := s.DB.Where("user_id = ?", userID)
+	if !all {
+		q = q.Where("status = ?", models.PlanActive)
+	}
+	var plans []models.InstallmentPlan
+	if err := q.Order("purchased_at DESC, id DESC").Find(&plans).Error; err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return []InstallmentStatus{}, nil
+	}
+	ids := make([]uint64, len(plans))
+	for i, p := range plans {
+		ids[i] = p.ID
+	}
+	var dues []models.InstallmentDue
+	if err := s.DB.Where("plan_id IN ?", ids).Order("plan_id, seq").Find(&dues).Error; err != nil {
+		return nil, err
+	}
+	byPlan := map[uint64][]models.InstallmentDue{}
+	for _, d := range dues {
+		byPlan[d.PlanID] = append(byPlan[d.PlanID], d)
+	}
+	now := time.Now().UTC()
+	out := make([]InstallmentStatus, 0, len(plans))
+	for _, p := range plans {
+		out = append(out, *planStatus(p, byPlan[p.ID], now))
+	}
+	return out, nil
+}
+
+// Debt sums what is still owed (and overdue) on the user's active plans.
+func (s *InstallmentService) Debt(userID int) (*InstallmentDebt, error) {
+	plans, err := s.List(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	remaining, overdue := map[string]int64{}, map[string]int64{}
+	today := startOfDay(time.Now().UTC())
+	for _, st := range plans {
+		cur := st.Remaining.Currency
+		remaining[cur] += st.Remaining.Minor
+		for _, d := range st.Dues {
+			if d.PaidAt == nil && d.DueDate.Before(today) {
+				overdue[cur] += d.Amount.Minor
+			}
+		}
+	}
+	toList := func(m map[string]int64) []models.Money {
+		out := []models.Money{}
+		for cur, v := range m {
+			if v != 0 {
+				out = append(out, models.NewMoney(v, cur))
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Currency < out[j].Currency })
+		return out
+	}
+	return &InstallmentDebt{Remaining: toList(remaining), Overdue: toList(overdue), Plans: plans}, nil
+}
+
+// pay marks one due as paid and books the payment as a cash-basis purchase. Caller holds s.mu.
+func (s *InstallmentService) pay(plan *models.InstallmentPlan, d *models.InstallmentDue, at time.Time) error {
+	title := fmt.Sprintf("%s - قسط %d از %d", plan.Title, d.Seq, plan.Count)
+	if d.Seq == 0 {
+		title = plan.Title + " - پیش‌پرداخت"
+	}
+	p := &models.Purchase{
+		UserID:            plan.UserID,
+		Title:             title,
+		Amount:            d.Amount,
+		Category:          plan.Category,
+		Subcategory:       plan.Subcategory,
+		Vendor:            plan.Vendor,
+		VendorID:          plan.VendorID,
+		PurchaseTime:      &at,
+		CreatedAt:         time.Now().UTC(),
+		Necessity:         "high",
+		ReasonGuess:       "قسط",
+		Confidence:        1,
+		Status:            models.StatusConfirmed,
+		SourceText:        plan.SourceText,
+		AccountID:         plan.AccountID,
+		InstallmentPlanID: &plan.ID,
+		Basis:             models.BasisCash,
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(p).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&models.InstallmentDue{}).Where("id = ? AND paid_at IS NULL", d.ID).
+			Updates(map[string]interface{}{"paid_at": at, "purchase_id": p.ID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("installment is already paid")
+		}
+		d.PaidAt, d.PurchaseID = &at, &p.ID
+
+		var left int64
+		if err := tx.Model(&models.InstallmentDue{}).Where("plan_id = ? AND paid_at IS NULL", plan.ID).Count(&left).Error; err != nil {
+			return err
+		}
+		if left == 0 {
+			plan.Status = models.PlanPaid
+			return tx.Model(plan).Update("status", models.PlanPaid).Error
+		}
+		return nil
+	})
+}
+
+// Pay pays the due with the given seq, or the next unpaid one when seq is 0.
+func (s *InstallmentService) Pay(userID int, id uint64, seq int, at *time.Time) (*InstallmentStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	plan, err := s.get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Status == models.PlanPaid {
+		return nil, ErrPlanPaid
+	}
+	q := s.DB.Where("plan_id = ? AND paid_at IS NULL", plan.ID)
+	if seq > 0 {
+		q = q.Where("seq = ?", seq)
+	}
+	var d models.InstallmentDue
+	if err := q.Order("seq").First(&d).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("installment %d is not due or already paid", seq)
+		}
+		return nil, err
+	}
+	when := time.Now().UTC()
+	if at != nil {
+		when = *at
+	}
+	if err := s.pay(plan, &d, when); err != nil {
+		return nil, err
+	}
+	return s.Status(userID, id)
+}
+
+// PayFromAIData pays the next due of the plan named in the model's "installment" object
+// ({"title", "paid_at"}); with one active plan the title may be empty.
+func (s *InstallmentService) PayFromAIData(userID int, data map[string]interface{}) (*InstallmentStatus, error) {
+	title, _ := data["title"].(string)
+	plans, err := s.List(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	var match *models.InstallmentPlan
+	best := 0.0
+	hint := utils.NormalizeText(title)
+	for i := range plans {
+		p := &plans[i].Plan
+		if hint == "" {
+			if len(plans) == 1 {
+				match = p
+			}
+			break
+		}
+		name := utils.NormalizeText(p.Title)
+		sc := utils.Similarity(hint, name)
+		if strings.Contains(name, hint) || strings.Contains(hint, name) {
+			sc = 1
+		}
+		if sc > best && sc >= 0.6 {
+			match, best = p, sc
+		}
+	}
+	if match == nil {
+		return nil, ErrPlanNotFound
+	}
+	var at *time.Time
+	if raw, _ := data["paid_at"].(string); raw != "" {
+		if d, err := utils.ParseDate(raw); err == nil {
+			at = &d
+		}
+	}
+	return s.Pay(userID, match.ID, 0, at)
+}
+
+// PayDue pays the dues of auto-pay plans whose date has come.
+func (s *InstallmentService) PayDue(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var dues []models.InstallmentDue
+	if err := s.DB.Joins("JOIN installment_plans ON installment_plans.id = installment_dues.plan_id").
+		Where("installment_plans.auto_pay = ? AND installment_plans.status = ? AND installment_dues.paid_at IS NULL AND installment_dues.due_date <= ?",
+			true, models.PlanActive, now).
+		Order("installment_dues.plan_id, installment_dues.seq").Find(&dues).Error; err != nil {
+		return 0, err
+	}
+	plans := map[uint64]*models.InstallmentPlan{}
+	n := 0
+	var errs []error
+	for i := range dues {
+		d := &dues[i]
+		plan := plans[d.PlanID]
+		if plan == nil {
+			plan = &models.InstallmentPlan{}
+			if err := s.DB.First(plan, d.PlanID).Error; err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			plans[d.PlanID] = plan
+		}
+		if err := s.pay(plan, d, d.DueDate); err != nil {
+			errs = append(errs, fmt.Errorf("plan %d: %w", plan.ID, err))
+			continue
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
+
+// Start pays auto-pay dues in the background: once right away (catch-up) and then every interval.
+func (s *InstallmentService) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSchedulerInterval
+	}
+	run := func() {
+		n, err := s.PayDue(time.Now().UTC())
+		if err != nil {
+			log.Printf("installments error: %v", err)
+		}
+		if n > 0 {
+			log.Printf("installments: %d dues paid", n)
+		}
+	}
+	go func() {
+		run()
+		for range time.Tick(interval) {
+			run()
+		}
+	}()
+}
+
+// Upcoming lists the unpaid dues of active plans in [from, to) for the forecast.
+func (s *InstallmentService) Upcoming(userID int, from, to time.Time) ([]UpcomingCharge, error) {
+	plans, err := s.List(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	var out []UpcomingCharge
+	for _, st := range plans {
+		for _, d := range st.Dues {
+			if d.PaidAt != nil || d.DueDate.Before(from) || !d.DueDate.Before(to) {
+				continue
+			}
+			out = append(out, UpcomingCharge{PlanID: st.Plan.ID, Title: st.Plan.Title, Vendor: st.Plan.Vendor,
+				VendorID: st.Plan.VendorID, Category: st.Plan.Category, Amount: d.Amount, Date: d.DueDate})
+		}
+	}
+	return out, nil
+}
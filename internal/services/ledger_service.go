@@ -0,0 +1,179 @@
+package services
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"example/AI/internal/ledger"
+	"example/AI/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// LedgerService imports beancount/hledger journals. Exporting is done by ExportService.
+type LedgerService struct {
+	DB       *gorm.DB
+	Purchase *PurchaseService
+}
+
+func NewLedgerService(db *gorm.DB, ps *PurchaseService) *LedgerService {
+	return &LedgerService{DB: db, Purchase: ps}
+}
+
+type LedgerImportResult struct {
+	Created   int      `json:"created"`
+	Flagged   int      `json:"flagged"` // created, but possible duplicates
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Skipped   int      `json:"skipped"` // no expense posting (income, transfers, ...)
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ledgerSource marks purchases created from a journal entry without a purchase-id, so that
+// importing the same file again does not create them twice.
+func ledgerSource(t ledger.Transaction, account string, amount models.Money) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s|%s|%s|%s|%d|%s",
+		t.Date.Format("2006-01-02"), t.Payee, t.Narration, account, amount.Minor, amount.Currency)))
+	return "ledger:" + hex.EncodeToString(sum[:])
+}
+
+// Import applies a journal: entries carrying a purchase-id update that purchase, the
+// others create new purchases. Importing the same journal twice changes nothing.
+func (s *LedgerService) Import(userID int, r io.Reader) (*LedgerImportResult, error) {
+	txs, errs := ledger.Parse(r)
+	res := &LedgerImportResult{}
+	for _, err := range errs {
+		res.Errors = append(res.Errors, err.Error())
+	}
+
+	for _, t := range txs {
+		account, amount, err := t.Expense()
+		if err != nil {
+			res.Skipped++
+			continue
+		}
+		category, subcategory, _ := ledger.CategoryOf(account)
+		title := t.Narration
+		if title == "" {
+			title = t.Payee
+		}
+		var vendor *string
+		if t.Payee != "" {
+			v := t.Payee
+			vendor = &v
+		}
+		date := t.Date
+
+		// existing purchase: by purchase-id, else by the source marker of a previous import
+		var existing *models.Purchase
+		source := ledgerSource(t, account, amount)
+		if raw, ok := t.Meta[ledger.PurchaseIDKey]; ok {
+			id, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("line %d: invalid %s %q", t.Line, ledger.PurchaseIDKey, raw))
+				continue
+			}
+			if existing, err = s.Purchase.Get(userID, id); err != nil && !errors.Is(err, ErrPurchaseNotFound) {
+				return nil, err
+			}
+		}
+		if existing == nil {
+			var p models.Purchase
+			err := s.DB.Where("user_id = ? AND source_text = ?", userID, source).First(&p).Error
+			if err == nil {
+				existing = &p
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+		}
+
+		if existing == nil {
+			p := &models.Purchase{
+				UserID:       userID,
+				Title:        title,
+				Amount:       amount,
+				Category:     category,
+				Subcategory:  subcategory,
+				Vendor:       vendor,
+				PurchaseTime: &date,
+				CreatedAt:    time.Now().UTC(),
+				Confidence:   1,
+				Status:       models.StatusConfirmed,
+				SourceText:   source,
+			}
+			if t.Flag == ledger.FlagPending {
+				p.Status = models.StatusGuessed
+			}
+			if err := s.Purchase.Normalize(p); err != nil {
+				res.Errors = append(res.Errors, fmt.Sprintf("line %d: %v", t.Line, err))
+				continue
+			}
+			if s.Purchase.Duplicates != nil {
+				if _, err := s.Purchase.Duplicates.Flag(p); err != nil {
+					return nil, err
+				}
+			}
+			if err := s.DB.Create(p).Error; err != nil {
+				return nil, err
+			}
+			res.Created++
+			if p.DuplicateOf != nil {
+				res.Flagged++
+			}
+			continue
+		}
+
+		// compare with a normalized copy so that unchanged entries stay unchanged
+		want := *existing
+		want.Title, want.Amount, want.Category, want.Subcategory, want.Vendor = title, amount, category, subcategory, vendor
+		if err := s.Purchase.Normalize(&want); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("line %d: %v", t.Line, err))
+			continue
+		}
+		updates := map[string]interface{}{}
+		if want.Title != existing.Title {
+			updates["title"] = want.Title
+		}
+		if want.Amount != existing.Amount {
+			updates["amount_minor"], updates["amount_currency"] = want.Amount.Minor, want.Amount.Currency
+		}
+		if want.Category != existing.Category || want.Subcategory != existing.Subcategory {
+			updates["category"], updates["subcategory"] = want.Category, want.Subcategory
+		}
+		if vendorName(want.Vendor) != vendorName(existing.Vendor) {
+			updates["vendor"], updates["vendor_id"] = want.Vendor, want.VendorID
+		}
+		if existing.PurchaseTime == nil || !sameDay(*existing.PurchaseTime, date) {
+			updates["purchase_time"] = date
+		}
+		// clearing an entry in the books confirms a guessed purchase
+		if t.Flag == ledger.FlagCleared && existing.Status == models.StatusGuessed {
+			updates["status"] = models.StatusConfirmed
+		}
+		if len(updates) == 0 {
+			res.Unchanged++
+			continue
+		}
+		if err := s.DB.Model(existing).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		res.Updated++
+	}
+	return res, nil
+}
+
+func vendorName(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.UTC().Format("2006-01-02") == b.UTC().Format("2006-01-02")
+}
@@ -0,0 +1,570 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// max periods in one price/inflation report
+	maxPricePeriods = 36
+	// item names at least this similar are the same item ("شیر کم چرب" / "شیر کمچرب")
+	priceItemSimilarity = 0.85
+)
+
+// staple items recognized in purchase titles and receipt lines, so that "نان بربری",
+// "نون سنگک" and "bread" are all "bread". Other receipt lines are grouped by their name.
+var stapleItems = map[string][]string{
+	"bread":     {"نان", "نون", "بربری", "سنگک", "لواش", "تافتون", "bread", "baguette"},
+	"milk":      {"شیر", "milk"},
+	"eggs":      {"تخم مرغ", "تخممرغ", "egg", "eggs"},
+	"rice":      {"برنج", "rice"},
+	"chicken":   {"مرغ", "chicken"},
+	"meat":      {"گوشت", "meat", "beef", "lamb"},
+	"cheese":    {"پنیر", "cheese"},
+	"yogurt":    {"ماست", "yogurt"},
+	"oil":       {"روغن", "cooking oil"},
+	"sugar":     {"شکر", "قند", "sugar"},
+	"tea":       {"چای", "tea"},
+	"fuel":      {"بنزین", "گازوئیل", "سوخت", "fuel", "petrol", "gasoline"},
+	"potato":    {"سیب زمینی", "potato", "potatoes"},
+	"onion":     {"پیاز", "onion", "onions"},
+	"tomato":    {"گوجه", "گوجه فرنگی", "tomato", "tomatoes"},
+	"water":     {"اب معدنی", "mineral water"},
+	"detergent": {"مایع ظرفشویی", "پودر لباسشویی", "detergent"},
+}
+
+// staple words, longest first so that "تخم مرغ" wins over "مرغ"
+var stapleWords = func() []struct{ word, key string } {
+	var out []struct{ word, key string }
+	for key, words := range stapleItems {
+		for _, w := range words {
+			out = append(out, struct{ word, key string }{utils.NormalizeText(w), key})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i].word) != len(out[j].word) {
+			return len(out[i].word) > len(out[j].word)
+		}
+		return out[i].word < out[j].word
+	})
+	return out
+}()
+
+// unit words dropped from item names and mapped to a base unit (with the factor to it)
+var priceUnits = map[string]struct {
+	unit   string
+	factor float64
+}{
+	"kg": {"kg", 1}, "kilo": {"kg", 1}, "کیلو": {"kg", 1}, "کیلوگرم": {"kg", 1},
+	"g": {"kg", 0.001}, "gr": {"kg", 0.001}, "گرم": {"kg", 0.001},
+	"l": {"l", 1}, "lit": {"l", 1}, "liter": {"l", 1}, "litre": {"l", 1}, "لیتر": {"l", 1},
+	"ml": {"l", 0.001}, "میلی لیتر": {"l", 0.001}, "سی سی": {"l", 0.001},
+	"pcs": {"pcs", 1}, "pc": {"pcs", 1}, "piece": {"pcs", 1}, "عدد": {"pcs", 1}, "تا": {"pcs", 1}, "دونه": {"pcs", 1},
+	"pack": {"pack", 1}, "بسته": {"pack", 1},
+}
+
+// PriceService tracks the unit prices of the things a user buys repeatedly and turns them
+// into a personal inflation index.
+type PriceService struct {
+	DB       *gorm.DB
+	Purchase *PurchaseService
+}
+
+func NewPriceService(db *gorm.DB, ps *PurchaseService) *PriceService {
+	return &PriceService{DB: db, Purchase: ps}
+}
+
+// PricePoint is the average unit price of an item at one vendor in one period.
+type PricePoint struct {
+	Period    string       `json:"period"`
+	Start     time.Time    `json:"start"`
+	Vendor    string       `json:"vendor"`
+	UnitPrice models.Money `json:"unit_price"`
+	Quantity  float64      `json:"quantity"`
+	Count     int          `json:"count"`
+}
+
+// ItemPrices is the price history of one normalized item.
+type ItemPrices struct {
+	Item   string       `json:"item"` // normalized key, e.g. "bread"
+	Name   string       `json:"name"` // the name used most often
+	Unit   string       `json:"unit"`
+	Spent  models.Money `json:"spent"`
+	Change *float64     `json:"change"` // % change of the average unit price, first to last period
+	Points []PricePoint `json:"points"`
+}
+
+// InflationPoint is the personal price index of one period.
+type InflationPoint struct {
+	Period string    `json:"period"`
+	Start  time.Time `json:"start"`
+	Index  float64   `json:"index"`  // first period = 100
+	Change *float64  `json:"change"` // % against the previous period, nil without comparable items
+	Items  int       `json:"items"`  // items priced in this period and before
+}
+
+// BasketItem is the weight of an item in the user's basket.
+type BasketItem struct {
+	Item   string       `json:"item"`
+	Unit   string       `json:"unit"`
+	Spent  models.Money `json:"spent"`
+	Weight float64      `json:"weight"` // % of the basket (within its currency)
+}
+
+type InflationReport struct {
+	Period string           `json:"period"`
+	Points []InflationPoint `json:"points"`
+	Total  *float64         `json:"total"` // % over the whole range
+	Basket []BasketItem     `json:"basket"`
+}
+
+// priceObs is one bought quantity of an item.
+type priceObs struct {
+	key, name, unit, vendor string
+	currency                string
+	quantity                float64
+	amount                  int64
+	at                      time.Time
+}
+
+// itemKey normalizes an item name: staple items map to their key, otherwise quantities and
+// unit words are dropped. staple reports a staple match.
+func itemKey(name string) (key string, staple bool) {
+	n := utils.NormalizeText(utils.NormalizeDigits(name))
+	padded := " " + n + " "
+	for _, sw := range stapleWords {
+		if strings.Contains(padded, " "+sw.word+" ") {
+			return sw.key, true
+		}
+	}
+	var words []string
+	for _, w := range strings.Fields(n) {
+		if _, isUnit := priceUnits[w]; isUnit || strings.IndexFunc(w, func(r rune) bool { return r < '0' || r > '9' }) < 0 {
+			continue
+		}
+		words = append(words, w)
+	}
+	return strings.Join(words, " "), false
+}
+
+// words of each staple's names, to check that a title is nothing but the item
+var stapleNameWords = func() map[string]map[string]bool {
+	out := map[string]map[string]bool{}
+	for key, names := range stapleItems {
+		out[key] = map[string]bool{}
+		for _, n := range names {
+			for _, w := range strings.Fields(utils.NormalizeText(n)) {
+				out[key][w] = true
+			}
+		}
+	}
+	return out
+}()
+
+// titleItem reads a purchase title that is a quantity of one staple item ("۳۰ لیتر بنزین",
+// "2 kg rice"). Without a quantity and unit the amount may be any quantity, and a title with
+// other words ("شیر و ماست", "روغن موتور") is not (only) the item, so both are rejected.
+func titleItem(title string) (key, unit string, qty float64, ok bool) {
+	words := strings.Fields(utils.NormalizeText(utils.NormalizeDigits(title)))
+	var rawUnit string
+	var rest []string
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		if i+1 < len(words) {
+			if _, isUnit := priceUnits[w+" "+words[i+1]]; isUnit && rawUnit == "" {
+				rawUnit = w + " " + words[i+1]
+				i++
+				continue
+			}
+		}
+		if _, isUnit := priceUnits[w]; isUnit && rawUnit == "" {
+			rawUnit = w
+			continue
+		}
+		if q, err := strconv.ParseFloat(w, 64); err == nil && qty == 0 {
+			qty = q
+			continue
+		}
+		rest = append(rest, w)
+	}
+	if qty <= 0 || rawUnit == "" || len(rest) == 0 {
+		return "", "", 0, false
+	}
+	key, staple := itemKey(strings.Join(rest, " "))
+	if !staple {
+		return "", "", 0, false
+	}
+	for _, w := range rest {
+		if !stapleNameWords[key][w] {
+			return "", "", 0, false
+		}
+	}
+	unit, qty = baseUnit(rawUnit, qty)
+	return key, unit, qty, true
+}
+
+// baseUnit maps a unit to kg / l / pcs / pack and converts the quantity.
+func baseUnit(unit string, qty float64) (string, float64) {
+	if qty <= 0 {
+		qty = 1
+	}
+	u := utils.NormalizeText(unit)
+	if u == "" {
+		return "pcs", qty
+	}
+	if b, ok := priceUnits[u]; ok {
+		return b.unit, qty * b.factor
+	}
+	return u, qty
+}
+
+// observations collects the priced quantities of the filter's range: every receipt line, and
+// purchases without lines whose title is a quantity of one staple item ("۳۰ لیتر بنزین"). Installments and
+// refunds are not price changes, so full prices of confirmed purchases are used.
+func (s *PriceService) observations(filter models.PurchaseFilter) ([]priceObs, error) {
+	filter.Basis, filter.IncludeUnconfirmed = models.BasisAccrual, false
+	filter.Categories, filter.MinAmount, filter.MaxAmount = nil, nil, nil
+	purchases, err := s.Purchase.Query(filter)
+	if err != nil {
+		return nil, err
+	}
+	items, err := itemsByPurchase(s.DB, purchases)
+	if err != nil {
+		return nil, err
+	}
+	var out []priceObs
+	for i := range purchases {
+		p := &purchases[i]
+		at, vendor := purchaseDate(p), vendorName(p.Vendor)
+		if lines := items[p.ID]; len(lines) > 0 {
+			for _, it := range lines {
+				key, _ := itemKey(it.Name)
+				if key == "" || !it.Amount.IsPositive() {
+					continue
+				}
+				unit, qty := baseUnit(it.Unit, it.Quantity)
+				out = append(out, priceObs{key: key, name: it.Name, unit: unit, vendor: vendor,
+					currency: it.Amount.Currency, quantity: qty, amount: it.Amount.Minor, at: at})
+			}
+			continue
+		}
+		if key, unit, qty, ok := titleItem(p.Title); ok {
+			out = append(out, priceObs{key: key, name: p.Title, unit: unit, vendor: vendor,
+				currency: p.Amount.Currency, quantity: qty, amount: p.Amount.Minor, at: at})
+		}
+	}
+	mergeSimilarItems(out)
+	return out, nil
+}
+
+// mergeSimilarItems gives near-identical item names one key (the first one seen).
+func mergeSimilarItems(obs []priceObs) {
+	var keys []string
+	canon := map[string]string{}
+	for i := range obs {
+		k := obs[i].key
+		if c, ok := canon[k]; ok {
+			obs[i].key = c
+			continue
+		}
+		c := k
+		if _, staple := stapleItems[k]; !staple {
+			for _, other := range keys {
+				if _, staple := stapleItems[other]; !staple && utils.Similarity(k, other) >= priceItemSimilarity {
+					c = other
+					break
+				}
+			}
+		}
+		if c == k {
+			keys = append(keys, k)
+		}
+		canon[k] = c
+		obs[i].key = c
+	}
+}
+
+// priceRange is the periods of the filter's range (default: the last 12 periods).
+func priceRange(filter models.PurchaseFilter, period string) (models.PurchaseFilter, []time.Time, error) {
+	if period == "" {
+		period = utils.PeriodMonthly
+	}
+	if !utils.ValidPeriod(period) {
+		return filter, nil, fmt.Errorf("invalid period %q", period)
+	}
+	to := time.Now().UTC()
+	if filter.ToDate != nil {
+		to = *filter.ToDate
+	}
+	start, _, err := utils.PeriodBounds(period, to)
+	if err != nil {
+		return filter, nil, err
+	}
+	if filter.FromDate != nil {
+		if start, _, err = utils.PeriodBounds(period, *filter.FromDate); err != nil {
+			return filter, nil, err
+		}
+	} else {
+		for i := 0; i < 11; i++ {
+			if start, _, err = utils.PreviousPeriod(period, start); err != nil {
+				return filter, nil, err
+			}
+		}
+	}
+	var starts []time.Time
+	var end time.Time
+	for ps := start; !ps.After(to) && len(starts) < maxPricePeriods; ps = end {
+		if _, end, err = utils.PeriodBounds(period, ps); err != nil {
+			return filter, nil, err
+		}
+		starts = append(starts, ps)
+	}
+	if len(starts) == 0 {
+		return filter, nil, errors.New("empty range")
+	}
+	from, until := starts[0], end.Add(-time.Nanosecond)
+	filter.FromDate, filter.ToDate = &from, &until
+	return filter, starts, nil
+}
+
+// periodIndex returns the index of the period containing t.
+func periodIndex(starts []time.Time, t time.Time) int {
+	i := sort.Search(len(starts), func(i int) bool { return starts[i].After(t) }) - 1
+	if i < 0 {
+		return 0
+	}
+	return i
+}
+
+type priceSeriesKey struct{ key, unit, currency string }
+
+type pricePointKey struct {
+	period int
+	vendor string
+}
+
+type priceAcc struct {
+	amount int64
+	qty    float64
+	count  int
+}
+
+func (a *priceAcc) add(o priceObs) {
+	a.amount += o.amount
+	a.qty += o.quantity
+	a.count++
+}
+
+func (a *priceAcc) unitPrice() float64 { return float64(a.amount) / a.qty }
+
+// priceSeries is everything bought of one item (in one unit and currency).
+type priceSeries struct {
+	key     priceSeriesKey
+	spent   int64
+	periods map[int]*priceAcc
+	points  map[pricePointKey]*priceAcc
+	names   map[string]int
+}
+
+// groupPrices puts the observations into per-item series, in the order first seen.
+func groupPrices(obs []priceObs, starts []time.Time) []*priceSeries {
+	byKey := map[priceSeriesKey]*priceSeries{}
+	var out []*priceSeries
+	for _, o := range obs {
+		k := priceSeriesKey{o.key, o.unit, o.currency}
+		sr := byKey[k]
+		if sr == nil {
+			sr = &priceSeries{key: k, periods: map[int]*priceAcc{}, points: map[pricePointKey]*priceAcc{}, names: map[string]int{}}
+			byKey[k] = sr
+			out = append(out, sr)
+		}
+		pi := periodIndex(starts, o.at)
+		if sr.periods[pi] == nil {
+			sr.periods[pi] = &priceAcc{}
+		}
+		sr.periods[pi].add(o)
+		pk := pricePointKey{pi, o.vendor}
+		if sr.points[pk] == nil {
+			sr.points[pk] = &priceAcc{}
+		}
+		sr.points[pk].add(o)
+		sr.spent += o.amount
+		sr.names[o.name]++
+	}
+	return out
+}
+
+// name is the item name used most often.
+func (sr *priceSeries) name() string {
+	best, n := "", 0
+	for name, c := range sr.names {
+		if c > n || c == n && name < best {
+			best, n = name, c
+		}
+	}
+	return best
+}
+
+// Prices returns the unit price history of the items bought in at least two periods (or of
+// the items matching item, however often), per vendor and period, biggest spending first.
+func (s *PriceService) Prices(filter models.PurchaseFilter, period, item, vendor string) ([]ItemPrices, error) {
+	if period == "" {
+		period = utils.PeriodMonthly
+	}
+	filter, starts, err := priceRange(filter, period)
+	if err != nil {
+		return nil, err
+	}
+	obs, err := s.observations(filter)
+	if err != nil {
+		return nil, err
+	}
+	wanted := ""
+	if item != "" {
+		wanted, _ = itemKey(item)
+	}
+	vendor = utils.NormalizeText(vendor)
+	var kept []priceObs
+	for _, o := range obs {
+		if wanted != "" && !strings.Contains(o.key, wanted) {
+			continue
+		}
+		if vendor != "" && !strings.Contains(utils.NormalizeText(o.vendor), vendor) {
+			continue
+		}
+		kept = append(kept, o)
+	}
+
+	out := []ItemPrices{}
+	for _, sr := range groupPrices(kept, starts) {
+		if wanted == "" && len(sr.periods) < 2 {
+			continue // bought once: nothing to track
+		}
+		cur := sr.key.currency
+		ip := ItemPrices{Item: sr.key.key, Name: sr.name(), Unit: sr.key.unit, Spent: models.NewMoney(sr.spent, cur)}
+		first, last := -1, -1
+		for pi := range sr.periods {
+			if first < 0 || pi < first {
+				first = pi
+			}
+			if pi > last {
+				last = pi
+			}
+		}
+		if first != last {
+			change := round2((sr.periods[last].unitPrice()/sr.periods[first].unitPrice() - 1) * 100)
+			ip.Change = &change
+		}
+		for pk, acc := range sr.points {
+			ip.Points = append(ip.Points, PricePoint{
+				Period:    utils.PeriodLabel(period, starts[pk.period]),
+				Start:     starts[pk.period],
+				Vendor:    pk.vendor,
+				UnitPrice: models.NewMoney(int64(math.Round(acc.unitPrice())), cur),
+				Quantity:  round2(acc.qty),
+				Count:     acc.count,
+			})
+		}
+		sort.Slice(ip.Points, func(i, j int) bool {
+			if !ip.Points[i].Start.Equal(ip.Points[j].Start) {
+				return ip.Points[i].Start.Before(ip.Points[j].Start)
+			}
+			return ip.Points[i].Vendor < ip.Points[j].Vendor
+		})
+		out = append(out, ip)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Spent.Minor > out[j].Spent.Minor })
+	return out, nil
+}
+
+// Inflation is a chained price index of the user's own basket: each period, the unit price of
+// every item is compared with its last earlier price, and the changes are averaged with the
+// item's share of the user's spending on tracked items as weight. Only the currency with the
+// most tracked spending is used.
+func (s *PriceService) Inflation(filter models.PurchaseFilter, period string) (*InflationReport, error) {
+	if period == "" {
+		period = utils.PeriodMonthly
+	}
+	filter, starts, err := priceRange(filter, period)
+	if err != nil {
+		return nil, err
+	}
+	obs, err := s.observations(filter)
+	if err != nil {
+		return nil, err
+	}
+	byCurrency := map[string]int64{}
+	for _, o := range obs {
+		byCurrency[o.currency] += o.amount
+	}
+	primary := ""
+	for cur, v := range byCurrency {
+		if primary == "" || v > byCurrency[primary] || v == byCurrency[primary] && cur < primary {
+			primary = cur
+		}
+	}
+
+	var series []*priceSeries
+	var total int64
+	for _, sr := range groupPrices(obs, starts) {
+		if sr.key.currency == primary {
+			series = append(series, sr)
+			total += sr.spent
+		}
+	}
+
+	rep := &InflationReport{Period: period, Points: []InflationPoint{}, Basket: []BasketItem{}}
+	lastPrice := map[*priceSeries]float64{}
+	index := 100.0
+	compared := false
+	for pi, start := range starts {
+		pt := InflationPoint{Period: utils.PeriodLabel(period, start), Start: start}
+		var sumW, sumWR float64
+		for _, sr := range series {
+			acc := sr.periods[pi]
+			if acc == nil {
+				continue
+			}
+			if prev, ok := lastPrice[sr]; ok && prev > 0 {
+				w := float64(sr.spent)
+				sumW += w
+				sumWR += w * acc.unitPrice() / prev
+				pt.Items++
+			}
+		}
+		if sumW > 0 {
+			change := sumWR/sumW - 1
+			index *= 1 + change
+			c := round2(change * 100)
+			pt.Change, compared = &c, true
+		}
+		pt.Index = round2(index)
+		for _, sr := range series {
+			if acc := sr.periods[pi]; acc != nil {
+				lastPrice[sr] = acc.unitPrice()
+			}
+		}
+		rep.Points = append(rep.Points, pt)
+	}
+	if compared {
+		t := round2(index - 100)
+		rep.Total = &t
+	}
+	for _, sr := range series {
+		rep.Basket = append(rep.Basket, BasketItem{Item: sr.key.key, Unit: sr.key.unit,
+			Spent: models.NewMoney(sr.spent, primary), Weight: round2(float64(sr.spent) * 100 / float64(total))})
+	}
+	sort.SliceStable(rep.Basket, func(i, j int) bool { return rep.Basket[i].Weight > rep.Basket[j].Weight })
+	return rep, nil
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ids per IN list (SQL Server allows ~2100 parameters per statement)
+const itemsQueryChunk = 1000
+
+// CategoryShare is the part of a purchase's net amount that belongs to one category.
+type CategoryShare struct {
+	Category    string       `json:"category"`
+	Subcategory string       `json:"subcategory"`
+	Amount      models.Money `json:"amount"`
+}
+
+// CategoryTotal is the spending of one category in one currency.
+type CategoryTotal struct {
+	Category string       `json:"category"`
+	Amount   models.Money `json:"amount"`
+}
+
+// prepareItem validates a line item of p: fills the line total from quantity × unit price
+// (or the other way round), the purchase currency and canonical categories.
+func (s *PurchaseService) prepareItem(p *models.Purchase, it *models.PurchaseItem) error {
+	it.Name = strings.TrimSpace(it.Name)
+	if it.Name == "" {
+		return errors.New("item name is required")
+	}
+	if it.Quantity <= 0 {
+		it.Quantity = 1
+	}
+	cur := p.Amount.Currency
+	if it.Amount.Currency == "" {
+		it.Amount.Currency = cur
+	}
+	if it.UnitPrice.Currency == "" {
+		it.UnitPrice.Currency = cur
+	}
+	if it.Amount.Currency != cur || it.UnitPrice.Currency != cur {
+		return fmt.Errorf("item %q is not in %s", it.Name, cur)
+	}
+	if it.Amount.IsZero() && it.UnitPrice.IsPositive() {
+		it.Amount = models.NewMoney(int64(math.Round(float64(it.UnitPrice.Minor)*it.Quantity)), cur)
+	}
+	if it.UnitPrice.IsZero() && it.Amount.IsPositive() {
+		it.UnitPrice = models.NewMoney(int64(math.Round(float64(it.Amount.Minor)/it.Quantity)), cur)
+	}
+	if !it.Amount.IsPositive() {
+		return fmt.Errorf("item %q has no amount", it.Name)
+	}
+	it.Unit = strings.ToLower(strings.TrimSpace(it.Unit))
+	if it.Category != "" && s.Taxonomy != nil {
+		cat, sub, err := s.Taxonomy.Resolve(p.UserID, it.Category, it.Subcategory)
+		if err != nil {
+			return err
+		}
+		it.Category, it.Subcategory = cat, sub
+	}
+	it.PurchaseID, it.UserID = p.ID, p.UserID
+	return nil
+}
+
+// itemsFromAIData reads the model's "items" array
+// ([{"name", "quantity", "unit", "unit_price", "amount", "category", "subcategory"}]).
+func itemsFromAIData(raw interface{}, currency string) []models.PurchaseItem {
+	list, _ := raw.([]interface{})
+	var out []models.PurchaseItem
+	for _, v := range list {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		str := func(k string) string {
+			s, _ := m[k].(string)
+			return strings.TrimSpace(s)
+		}
+		it := models.PurchaseItem{
+			Name:        str("name"),
+			Unit:        str("unit"),
+			Category:    str("category"),
+			Subcategory: str("subcategory"),
+		}
+		if q, err := toFloat64(m["quantity"]); err == nil {
+			it.Quantity = q
+		}
+		if price, err := toMoney(m["unit_price"], currency); err == nil {
+			it.UnitPrice = price
+		}
+		if amount, err := toMoney(m["amount"], currency); err == nil {
+			it.Amount = amount
+		}
+		out = append(out, it)
+	}
+	return out
+}
+
+// itemsTotal is the sum of the line totals (quantity × unit price where the total is missing).
+func itemsTotal(items []models.PurchaseItem) int64 {
+	var sum int64
+	for _, it := range items {
+		if it.Amount.IsZero() && it.UnitPrice.IsPositive() {
+			q := it.Quantity
+			if q <= 0 {
+				q = 1
+			}
+			sum += int64(math.Round(float64(it.UnitPrice.Minor) * q))
+		} else if it.Amount.IsPositive() {
+			sum += it.Amount.Minor
+		}
+	}
+	return sum
+}
+
+// applyItems keeps the valid items of a new purchase (model output is taken leniently) and,
+// when the purchase has no real category, takes the category of its biggest item.
+func (s *PurchaseService) applyItems(p *models.Purchase, items []models.PurchaseItem) []models.PurchaseItem {
+	if len(items) == 0 {
+		return nil
+	}
+	var kept []models.PurchaseItem
+	biggest := -1
+	for i := range items {
+		it := items[i]
+		if err := s.prepareItem(p, &it); err != nil {
+			continue
+		}
+		kept = append(kept, it)
+		if it.Category != "" && (biggest < 0 || it.Amount.Minor > kept[biggest].Amount.Minor) {
+			biggest = len(kept) - 1
+		}
+	}
+	if biggest >= 0 && (p.Category == "" || p.Category == models.OtherCategory) {
+		p.Category, p.Subcategory = kept[biggest].Category, kept[biggest].Subcategory
+	}
+	return kept
+}
+
+// saveItems stores the items of a just created purchase (in the purchase's transaction).
+func saveItems(tx *gorm.DB, p *models.Purchase, items []models.PurchaseItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	for i := range items {
+		items[i].PurchaseID, items[i].UserID, items[i].CreatedAt = p.ID, p.UserID, now
+	}
+	return tx.Create(&items).Error
+}
+
+// Items returns the line items of a purchase.
+func (s *PurchaseService) Items(userID int, purchaseID uint64) ([]models.PurchaseItem, error) {
+	items := []models.PurchaseItem{}
+	err := s.DB.Where("purchase_id = ? AND user_id = ?", purchaseID, userID).Order("id").Find(&items).Error
+	return items, err
+}
+
+// SetItems replaces the line items of a purchase; an empty list removes them.
+func (s *PurchaseService) SetItems(userID int, purchaseID uint64, items []models.PurchaseItem) ([]models.PurchaseItem, error) {
+	p, err := s.Get(userID, purchaseID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].ID = 0
+		if err := s.prepareItem(p, &items[i]); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i+1, err)
+		}
+		items[i].CreatedAt = time.Now().UTC()
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("purchase_id = ?", p.ID).Delete(&models.PurchaseItem{}).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		return tx.Create(&items).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []models.PurchaseItem{}
+	}
+	return items, nil
+}
+
+// itemsByPurchase loads the line items of the purchases, grouped by purchase.
+func itemsByPurchase(db *gorm.DB, purchases []models.Purchase) (map[uint64][]models.PurchaseItem, error) {
+	out := map[uint64][]models.PurchaseItem{}
+	ids := make([]uint64, len(purchases))
+	for i, p := range purchases {
+		ids[i] = p.ID
+	}
+	for start := 0; start < len(ids); start += itemsQueryChunk {
+		end := min(start+itemsQueryChunk, len(ids))
+		var items []models.PurchaseItem
+		if err := db.Where("purchase_id IN ?", ids[start:end]).Order("id").Find(&items).Error; err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			out[it.PurchaseID] = append(out[it.PurchaseID], it)
+		}
+	}
+	return out, nil
+}
+
+// categoryShares splits the purchases that have line items over the items' categories, in
+// proportion to the line totals, so the shares add up to the purchase's net amount (discounts,
+// tax and refunds are spread the same way). Purchases without items are not in the map.
+func categoryShares(db *gorm.DB, purchases []models.Purchase) (map[uint64][]CategoryShare, error) {
+	out := map[uint64][]CategoryShare{}
+	byPurchase, err := itemsByPurchase(db, purchases)
+	if err != nil {
+		return nil, err
+	}
+	for i := range purchases {
+		p := &purchases[i]
+		items := byPurchase[p.ID]
+		var weight int64
+		for _, it := range items {
+			if it.Amount.Currency == p.Amount.Currency {
+				weight += it.Amount.Minor
+			}
+		}
+		if weight <= 0 {
+			continue
+		}
+		net := p.Net()
+		idx := map[string]int{}
+		var shares []CategoryShare
+		var given, seen int64
+		for _, it := range items {
+			if it.Amount.Currency != p.Amount.Currency {
+				continue
+			}
+			seen += it.Amount.Minor
+			// cumulative rounding: the shares always add up to the net amount
+			upto := int64(math.Round(float64(net.Minor) * float64(seen) / float64(weight)))
+			part := upto - given
+			given = upto
+			cat, sub := it.Category, it.Subcategory
+			if cat == "" {
+				cat, sub = p.Category, p.Subcategory
+			}
+			k := cat + "|" + sub
+			if j, ok := idx[k]; ok {
+				shares[j].Amount.Minor += part
+				continue
+			}
+			idx[k] = len(shares)
+			shares = append(shares, CategoryShare{Category: cat, Subcategory: sub, Amount: models.NewMoney(part, net.Currency)})
+		}
+		out[p.ID] = shares
+	}
+	return out, nil
+}
+
+// sharesOf returns the category shares of p: its items' categories, or its own category.
+func sharesOf(split map[uint64][]CategoryShare, p *models.Purchase) []CategoryShare {
+	if shares, ok := split[p.ID]; ok {
+		return shares
+	}
+	return []CategoryShare{{Category: p.Category, Subcategory: p.Subcategory, Amount: p.Net()}}
+}
+
+// itemCategoryExpr is the category an item counts under: its own, or its purchase's.
+const itemCategoryExpr = "COALESCE(NULLIF(purchase_items.category, ''), purchases.category)"
+
+// CategoryTotals is the spending per category (item categories where a purchase has items),
+// biggest first. With filter.Categories only those categories are counted. Items share the
+// purchase's net amount in proportion to their line totals, as in categoryShares.
+func (s *PurchaseService) CategoryTotals(filter models.PurchaseFilter) ([]CategoryTotal, error) {
+	cats := filter.Categories
+	filter.Categories = nil
+	type row struct {
+		Category string
+		Currency string
+		Total    float64
+	}
+
+	// purchases with items: one share per item
+	weights := s.DB.Model(&models.PurchaseItem{}).
+		Select("purchase_id, amount_currency AS currency, SUM(amount_minor) AS total").
+		Group("purchase_id, amount_currency")
+	itemsDB := applyFilter(s.DB.Model(&models.Purchase{}), filter).
+		Joins("JOIN purchase_items ON purchase_items.purchase_id = purchases.id AND purchase_items.amount_currency = purchases.amount_currency").
+		Joins("JOIN (?) AS item_weights ON item_weights.purchase_id = purchases.id AND item_weights.currency = purchases.amount_currency AND item_weights.total > 0", weights)
+	if len(cats) > 0 {
+		itemsDB = itemsDB.Where(itemCategoryExpr+" IN ?", cats)
+	}
+	var rows []row
+	if err := itemsDB.Select(itemCategoryExpr + " AS category, purchases.amount_currency AS currency, " +
+		"SUM(CAST(purchase_items.amount_minor AS FLOAT) * (purchases.amount_minor - purchases.refunded_minor) / item_weights.total) AS total").
+		Group(itemCategoryExpr + ", purchases.amount_currency").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	// purchases without items count under their own category
+	hasItems := s.DB.Model(&models.PurchaseItem{}).Select("1").
+		Where("purchase_items.purchase_id = purchases.id AND purchase_items.amount_currency = purchases.amount_currency AND purchase_items.amount_minor > 0")
+	plainDB := applyFilter(s.DB.Model(&models.Purchase{}), filter).Where("NOT EXISTS (?)", hasItems)
+	if len(cats) > 0 {
+		plainDB = plainDB.Where("purchases.category IN ?", cats)
+	}
+	var plain []row
+	if err := plainDB.Select("purchases.category AS category, purchases.amount_currency AS currency, " +
+		"SUM(CAST(purchases.amount_minor - purchases.refunded_minor AS FLOAT)) AS total").
+		Group("purchases.category, purchases.amount_currency").
+		Scan(&plain).Error; err != nil {
+		return nil, err
+	}
+
+	type key struct{ category, currency string }
+	totals := map[key]float64{}
+	for _, r := range append(rows, plain...) {
+		totals[key{r.Category, r.Currency}] += r.Total
+	}
+	out := make([]CategoryTotal, 0, len(totals))
+	for k, v := range totals {
+		out = append(out, CategoryTotal{Category: k.category, Amount: models.NewMoney(int64(math.Round(v)), k.currency)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Amount.Minor != out[j].Amount.Minor {
+			return out[i].Amount.Minor > out[j].Amount.Minor
+		}
+		return out[i].Category < out[j].Category
+	})
+	return out, nil
+}
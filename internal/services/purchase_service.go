@@ -1,9 +1,12 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"example/AI/internal/models"
@@ -15,10 +18,41 @@ import (
 type PurchaseService struct {
 	Repo *store.PurchaseRepo
 	DB   *gorm.DB // یا مستقیم *gorm.DB اگر داری
+
+	// optional collaborators (nil = feature disabled)
+	Taxonomy    *TaxonomyService
+	Vendors     *VendorService
+	Corrections *CorrectionService
+	Classifier  *ClassifierService
+	Recurring   *RecurringService
+	Duplicates  *DuplicateService
+	Accounts    *AccountService
+	Budgets     *BudgetService // warnings for purchases created in the background (schedules)
+
+	// below this model confidence a purchase is saved as "guessed"
+	ReviewThreshold float64
+}
+
+const (
+	DefaultReviewThreshold = 0.5
+	// the local classifier "strongly disagrees" above this probability
+	ClassifierDisagreeProb = 0.8
+)
+
+var ErrPurchaseNotFound = errors.New("purchase not found")
+
+// IncompletePurchaseError is returned when the extraction lacks required fields;
+// the caller can keep the data as a draft and ask for the rest.
+type IncompletePurchaseError struct {
+	Missing []string // "title", "amount"
+}
+
+func (e *IncompletePurchaseError) Error() string {
+	return "invalid purchase: missing " + strings.Join(e.Missing, " and ")
 }
 
 func NewPurchaseService(repo *store.PurchaseRepo) *PurchaseService {
-	return &PurchaseService{Repo: repo, DB: repo.DB}
+	return &PurchaseService{Repo: repo, DB: repo.DB, ReviewThreshold: DefaultReviewThreshold}
 }
 
 func toFloat64(v interface{}) (float64, error) {
@@ -31,6 +65,8 @@ func toFloat64(v interface{}) (float64, error) {
 		return float64(t), nil
 	case int64:
 		return float64(t), nil
+	case json.Number:
+		return t.Float64()
 	case string:
 		// try parse
 		return strconv.ParseFloat(t, 64)
@@ -39,22 +75,47 @@ func toFloat64(v interface{}) (float64, error) {
 	}
 }
 
-func (s *PurchaseService) CreateFromAIData(userID int, aiData map[string]interface{}) (*models.Purchase, error) {
+// toMoney converts an AI/JSON amount to Money without going through float64 when possible.
+func toMoney(v interface{}, currency string) (models.Money, error) {
+	switch t := v.(type) {
+	case json.Number:
+		return models.ParseMoney(t.String(), currency)
+	case string:
+		return models.ParseMoney(t, currency)
+	case int:
+		return models.ParseMoney(strconv.Itoa(t), currency)
+	default:
+		f, err := toFloat64(v)
+		if err != nil {
+			return models.Money{}, err
+		}
+		return models.MoneyFromFloat(f, currency)
+	}
+}
+
+// CreateFromAIData saves a purchase from the model's extracted data.
+// sourceText is the user's original message (kept for corrections/learning).
+func (s *PurchaseService) CreateFromAIData(userID int, aiData map[string]interface{}, sourceText string) (*models.Purchase, error) {
 	// Map fields with safe conversions and defaults
 	title := ""
 	if v, ok := aiData["title"].(string); ok {
 		title = v
 	}
-	var amount float64
-	if v, ok := aiData["amount"]; ok {
-		if f, err := toFloat64(v); err == nil {
-			amount = f
-		}
-	}
 	currency := ""
 	if v, ok := aiData["currency"].(string); ok {
 		currency = v
 	}
+	// an unknown currency is an error, not a missing amount
+	currency, err := models.ParseCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	amount := models.NewMoney(0, currency)
+	if v, ok := aiData["amount"]; ok {
+		if m, err := toMoney(v, currency); err == nil {
+			amount = m
+		}
+	}
 	category := ""
 	if v, ok := aiData["category"].(string); ok {
 		category = v
@@ -102,11 +163,28 @@ func (s *PurchaseService) CreateFromAIData(userID int, aiData map[string]interfa
 		}
 	}
 
+	// receipt lines; a missing total is their sum
+	items := itemsFromAIData(aiData["items"], currency)
+	if len(items) > 0 && !amount.IsPositive() {
+		amount = models.NewMoney(itemsTotal(items), amount.Currency)
+	}
+
+	// basic validation
+	var missing []string
+	if title == "" {
+		missing = append(missing, "title")
+	}
+	if !amount.IsPositive() {
+		missing = append(missing, "amount")
+	}
+	if len(missing) > 0 {
+		return nil, &IncompletePurchaseError{Missing: missing}
+	}
+
 	p := &models.Purchase{
 		UserID:        userID,
 		Title:         title,
 		Amount:        amount,
-		Currency:      currency,
 		Category:      category,
 		Subcategory:   subcategory,
 		Vendor:        vendor,
@@ -116,53 +194,296 @@ func (s *PurchaseService) CreateFromAIData(userID int, aiData map[string]interfa
 		EmotionalTone: emotionalTone,
 		ReasonGuess:   reasonGuess,
 		Confidence:    confidence,
-		Status:        "confirmed",
+		Status:        models.StatusConfirmed,
+		SourceText:    sourceText,
 	}
-
-	// basic validation
-	if p.Title == "" || p.Amount <= 0 {
-		return nil, errors.New("invalid purchase: missing title or amount")
+	if err := s.Normalize(p); err != nil {
+		return nil, err
 	}
+	items = s.applyItems(p, items)
+	// "با کارت ملت" -> the user's account, otherwise the default one
+	hint, _ := aiData["account"].(string)
+	s.assignAccount(p, hint)
 
-	if err := s.Repo.Create(p); err != nil {
+	// cross-check with the local classifier; send doubtful purchases to review
+	if s.Classifier != nil {
+		p.ClassifierCategory, p.ClassifierConfidence = s.Classifier.Predict(p)
+	}
+	if reasons := s.reviewSignals(p); len(reasons) > 0 {
+		p.Status = models.StatusGuessed
+		p.ReviewReasons = strings.Join(reasons, ",")
+	}
+	// a receipt is saved whole or not at all
+	if err := s.DB.Transaction(func(tx *gorm.DB) error { return s.insert(tx, p, items) }); err != nil {
 		return nil, err
 	}
+	s.afterCreate(p)
 	return p, nil
 }
 
-func (s *PurchaseService) Query(filter models.PurchaseFilter) ([]models.Purchase, error) {
-	if s.DB == nil {
-		return nil, errors.New("database is not initialized")
+// assignAccount puts p on the account the hint names, or on the user's default account.
+func (s *PurchaseService) assignAccount(p *models.Purchase, hint string) {
+	if s.Accounts == nil || p.AccountID != nil {
+		return
+	}
+	if acc, err := s.Accounts.Resolve(p.UserID, hint); err == nil && acc != nil {
+		p.AccountID = &acc.ID
 	}
+}
 
-	db := s.DB.Model(&models.Purchase{})
+// insert saves a new purchase and its line items in tx, with the steps every new purchase
+// goes through: the default account and the duplicate check.
+func (s *PurchaseService) insert(tx *gorm.DB, p *models.Purchase, items []models.PurchaseItem) error {
+	s.assignAccount(p, "")
+	// said twice / already imported from the bank: keep it, but out of the totals until reviewed
+	if s.Duplicates != nil {
+		if _, err := s.Duplicates.Flag(p); err != nil {
+			return err
+		}
+	}
+	if err := store.NewPurchaseRepo(tx).Create(p); err != nil {
+		return err
+	}
+	return saveItems(tx, p, items)
+}
 
+// afterCreate runs once a new purchase is committed: the classifier learns it and it joins
+// its recurring series.
+func (s *PurchaseService) afterCreate(p *models.Purchase) {
+	if s.Classifier != nil {
+		s.Classifier.Observe(p)
+	}
+	if s.Recurring != nil {
+		_ = s.Recurring.Attach(p)
+	}
+}
+
+// Normalize maps a new purchase onto the user's data: vendor aliases/fuzzy match, canonical
+// taxonomy keys, the vendor's default category and the user's rules.
+func (s *PurchaseService) Normalize(p *models.Purchase) error {
+	// normalize vendor (aliases + fuzzy match)
+	var matchedVendor *models.Vendor
+	if p.Vendor != nil && *p.Vendor != "" && s.Vendors != nil {
+		if v, err := s.Vendors.Match(p.UserID, *p.Vendor); err == nil {
+			p.Vendor, p.VendorID, matchedVendor = &v.Name, &v.ID, v
+		}
+	}
+
+	// map model output to canonical taxonomy keys
+	if s.Taxonomy != nil {
+		cat, sub, err := s.Taxonomy.Resolve(p.UserID, p.Category, p.Subcategory)
+		if err != nil {
+			return err
+		}
+		p.Category, p.Subcategory = cat, sub
+	}
+	// vendor default category when the model couldn't tell
+	if matchedVendor != nil && matchedVendor.DefaultCategory != "" && (p.Category == "" || p.Category == models.OtherCategory) {
+		p.Category, p.Subcategory = matchedVendor.DefaultCategory, matchedVendor.DefaultSubcategory
+	}
+
+	// user rules ("Snapp -> transport") win over the model
+	if s.Corrections != nil {
+		if _, err := s.Corrections.ApplyRules(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyFilter = همان زنجیره فیلتر برای Query و همه‌ی aggregation ها
+func applyFilter(db *gorm.DB, filter models.PurchaseFilter) *gorm.DB {
 	// user_ids
 	if len(filter.UserIDs) > 0 {
-		db = db.Where("user_id IN ?", filter.UserIDs)
+		db = db.Where("purchases.user_id IN ?", filter.UserIDs)
 	}
 
-	// categories
+	// categories (of the purchase or of one of its items)
 	if len(filter.Categories) > 0 {
-		db = db.Where("category IN ?", filter.Categories)
+		items := db.Session(&gorm.Session{NewDB: true}).Model(&models.PurchaseItem{}).
+			Select("purchase_id").Where("category IN ?", filter.Categories)
+		db = db.Where("(purchases.category IN ? OR purchases.id IN (?))", filter.Categories, items)
 	}
 
 	// date range
 	if filter.FromDate != nil {
-		db = db.Where("purchase_time >= ?", *filter.FromDate)
+		db = db.Where("purchases.purchase_time >= ?", *filter.FromDate)
 	}
 	if filter.ToDate != nil {
-		db = db.Where("purchase_time <= ?", *filter.ToDate)
+		db = db.Where("purchases.purchase_time <= ?", *filter.ToDate)
 	}
 
-	// amount range
+	// status: rejected never counts, unconfirmed only on request
+	if filter.IncludeUnconfirmed {
+		db = db.Where("purchases.status <> ?", models.StatusRejected)
+	} else {
+		db = db.Where("purchases.status = ?", models.StatusConfirmed)
+	}
+
+	// amount range (only comparable within the same currency)
 	if filter.MinAmount != nil {
-		db = db.Where("amount >= ?", *filter.MinAmount)
+		db = db.Where("purchases.amount_currency = ? AND purchases.amount_minor >= ?", filter.MinAmount.Currency, filter.MinAmount.Minor)
 	}
 	if filter.MaxAmount != nil {
-		db = db.Where("amount <= ?", *filter.MaxAmount)
+		db = db.Where("purchases.amount_currency = ? AND purchases.amount_minor <= ?", filter.MaxAmount.Currency, filter.MaxAmount.Minor)
+	}
+
+	// installments: the full price or the payments, never both
+	if filter.Basis == models.BasisCash {
+		db = db.Where("purchases.basis <> ?", models.BasisAccrual)
+	} else {
+		db = db.Where("purchases.basis <> ?", models.BasisCash)
 	}
 
+	return db
+}
+
+// reviewSignals lists the reasons a new purchase should be reviewed before it counts.
+func (s *PurchaseService) reviewSignals(p *models.Purchase) []string {
+	var reasons []string
+	if p.Confidence < s.ReviewThreshold {
+		reasons = append(reasons, "low_confidence")
+	}
+	if p.ClassifierCategory != "" && p.ClassifierCategory != p.Category && p.ClassifierConfidence >= ClassifierDisagreeProb {
+		reasons = append(reasons, "classifier_disagrees")
+	}
+	if p.Category == "" || p.Category == models.OtherCategory {
+		reasons = append(reasons, "uncategorized")
+	}
+	if p.PurchaseTime != nil && p.PurchaseTime.After(time.Now().Add(24*time.Hour)) {
+		reasons = append(reasons, "future_date")
+	}
+	return reasons
+}
+
+func (s *PurchaseService) Get(userID int, id uint64) (*models.Purchase, error) {
+	var p models.Purchase
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPurchaseNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+// PurchaseCorrection = فیلدهایی که کاربر اصلاح می‌کند (nil = بدون تغییر)
+type PurchaseCorrection struct {
+	Title       *string
+	Amount      *models.Money
+	Category    *string
+	Subcategory *string
+	Vendor      *string
+	AccountID   *uint64 // 0 = no account
+	Remember    bool    // also create a rule for this vendor
+}
+
+func (c PurchaseCorrection) IsEmpty() bool {
+	return c.Title == nil && c.Amount == nil && c.Category == nil && c.Subcategory == nil && c.Vendor == nil && c.AccountID == nil
+}
+
+// Correct applies a user's fix to category/vendor and records it so the system learns from it.
+func (s *PurchaseService) Correct(userID int, id uint64, in PurchaseCorrection) (*models.Purchase, []models.Correction, error) {
+	p, err := s.Get(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	before := *p
+
+	if in.Title != nil {
+		if *in.Title == "" {
+			return nil, nil, errors.New("title can't be empty")
+		}
+		p.Title = *in.Title
+	}
+	if in.Amount != nil {
+		if !in.Amount.IsPositive() {
+			return nil, nil, errors.New("amount must be positive")
+		}
+		p.Amount = *in.Amount
+	}
+
+	if in.Vendor != nil {
+		p.Vendor, p.VendorID = nil, nil
+		if *in.Vendor != "" {
+			name := *in.Vendor
+			p.Vendor = &name
+			if s.Vendors != nil {
+				v, err := s.Vendors.Match(userID, name)
+				if err != nil {
+					return nil, nil, err
+				}
+				p.Vendor, p.VendorID = &v.Name, &v.ID
+			}
+		}
+	}
+
+	if in.AccountID != nil {
+		p.AccountID = nil
+		if *in.AccountID != 0 {
+			if s.Accounts == nil {
+				return nil, nil, errors.New("accounts are not enabled")
+			}
+			acc, err := s.Accounts.Get(userID, *in.AccountID)
+			if err != nil {
+				return nil, nil, err
+			}
+			p.AccountID = &acc.ID
+		}
+	}
+
+	if in.Category != nil || in.Subcategory != nil {
+		cat, sub := p.Category, ""
+		if in.Category != nil {
+			cat = *in.Category
+		}
+		if in.Subcategory != nil {
+			sub = *in.Subcategory
+		}
+		if s.Taxonomy != nil {
+			if cat, sub, err = s.Taxonomy.Resolve(userID, cat, sub); err != nil {
+				return nil, nil, err
+			}
+		}
+		p.Category, p.Subcategory = cat, sub
+	}
+
+	if err := s.DB.Model(p).Select("title", "amount_minor", "amount_currency", "category", "subcategory", "vendor", "vendor_id", "account_id").Updates(p).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var corrections []models.Correction
+	if s.Corrections != nil {
+		if corrections, err = s.Corrections.Record(&before, p, in.Remember); err != nil {
+			return p, corrections, err
+		}
+	}
+	if s.Classifier != nil && before.Category != p.Category {
+		s.Classifier.Relabel(&before, p)
+	}
+	return p, corrections, nil
+}
+
+// CanonicalizeFilter maps filter categories (labels, synonyms, other languages) to taxonomy keys.
+func (s *PurchaseService) CanonicalizeFilter(userID int, filter *models.PurchaseFilter) error {
+	if s.Taxonomy == nil || len(filter.Categories) == 0 {
+		return nil
+	}
+	keys, err := s.Taxonomy.CanonicalKeys(userID, filter.Categories)
+	if err != nil {
+		return err
+	}
+	filter.Categories = keys
+	return nil
+}
+
+func (s *PurchaseService) Query(filter models.PurchaseFilter) ([]models.Purchase, error) {
+	if s.DB == nil {
+		return nil, errors.New("database is not initialized")
+	}
+
+	db := applyFilter(s.DB.Model(&models.Purchase{}), filter)
+
 	var res []models.Purchase
 	if err := db.Order("purchase_time desc").Find(&res).Error; err != nil {
 		return nil, err
@@ -171,35 +492,52 @@ func (s *PurchaseService) Query(filter models.PurchaseFilter) ([]models.Purchase
 	return res, nil
 }
 
-func (s *PurchaseService) SumAmount(filter models.PurchaseFilter) (float64, error) {
-	db := s.DB.Model(&models.Purchase{})
-	// same filter chain as Query...
-	var total float64
-	if err := db.Select("SUM(amount)").Scan(&total).Error; err != nil {
-		return 0, err
+// SumAmount returns one total per currency (amounts in different currencies are never added up).
+func (s *PurchaseService) SumAmount(filter models.PurchaseFilter) ([]models.Money, error) {
+	// a category filter only counts the matching items of mixed purchases
+	if len(filter.Categories) > 0 {
+		cats, err := s.CategoryTotals(filter)
+		if err != nil {
+			return nil, err
+		}
+		byCurrency := map[string]int64{}
+		for _, c := range cats {
+			byCurrency[c.Amount.Currency] += c.Amount.Minor
+		}
+		totals := make([]models.Money, 0, len(byCurrency))
+		for cur, v := range byCurrency {
+			totals = append(totals, models.NewMoney(v, cur))
+		}
+		sort.Slice(totals, func(i, j int) bool { return totals[i].Currency < totals[j].Currency })
+		return totals, nil
 	}
-	return total, nil
-}
 
-func (s *PurchaseService) TopCategory(filter models.PurchaseFilter) (string, float64, error) {
-	db := s.DB.Model(&models.Purchase{})
-	// filter chain
+	db := applyFilter(s.DB.Model(&models.Purchase{}), filter)
 
 	type Row struct {
-		Category string
-		Total    float64
+		Currency string
+		Total    int64
 	}
 	var rows []Row
+	if err := db.Select("purchases.amount_currency AS currency, SUM(purchases.amount_minor - purchases.refunded_minor) AS total").
+		Group("amount_currency").
+		Order("amount_currency").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
 
-	db.Select("category, SUM(amount) as total").
-		Group("category").
-		Order("total DESC").
-		Limit(1).
-		Scan(&rows)
-
-	if len(rows) == 0 {
-		return "", 0, nil
+	totals := make([]models.Money, 0, len(rows))
+	for _, r := range rows {
+		totals = append(totals, models.NewMoney(r.Total, r.Currency))
 	}
+	return totals, nil
+}
 
-	return rows[0].Category, rows[0].Total, nil
+// TopCategory is the category with the most spending (item categories count for mixed purchases).
+func (s *PurchaseService) TopCategory(filter models.PurchaseFilter) (string, models.Money, error) {
+	cats, err := s.CategoryTotals(filter)
+	if err != nil || len(cats) == 0 {
+		return "", models.Money{}, err
+	}
+	return cats[0].Category, cats[0].Amount, nil
 }
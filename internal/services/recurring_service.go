@@ -0,0 +1,464 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	recurringMinOccurrences = 3
+	// amounts within this relative distance belong to the same series
+	recurringAmountTolerance = 0.25
+	// share of intervals that must match the cadence
+	recurringMinRegularity = 0.7
+	recurringLookback      = 2 * 365 * 24 * time.Hour
+)
+
+type cadenceSpec struct {
+	name string
+	days float64 // nominal interval
+	tol  float64 // allowed deviation in days
+}
+
+var cadenceSpecs = []cadenceSpec{
+	{models.CadenceWeekly, 7, 1.5},
+	{models.CadenceBiweekly, 14, 2.5},
+	{models.CadenceMonthly, 30.44, 3.5},
+	{models.CadenceQuarterly, 91.3, 8},
+	{models.CadenceYearly, 365.25, 15},
+}
+
+var ErrSeriesNotFound = errors.New("recurring series not found")
+
+// RecurringService finds periodic expenses in the purchase history and forecasts their next charges.
+type RecurringService struct {
+	DB *gorm.DB
+}
+
+func NewRecurringService(db *gorm.DB) *RecurringService {
+	return &RecurringService{DB: db}
+}
+
+// seriesKey groups purchases by vendor when known, otherwise by normalized title.
+func seriesKey(p *models.Purchase) string {
+	if p.VendorID != nil {
+		return fmt.Sprintf("v:%d", *p.VendorID)
+	}
+	return "t:" + utils.NormalizeText(p.Title)
+}
+
+func purchaseDate(p *models.Purchase) time.Time {
+	if p.PurchaseTime != nil {
+		return p.PurchaseTime.UTC()
+	}
+	return p.CreatedAt.UTC()
+}
+
+func amountClose(a, b int64) bool {
+	if a <= 0 || b <= 0 {
+		return a == b
+	}
+	return math.Abs(float64(a-b))/float64(b) <= recurringAmountTolerance
+}
+
+// advanceCadence moves t forward by n periods of the cadence, keeping the day of month
+// (clamped to the month length).
+func advanceCadence(cadence string, t time.Time, n int) time.Time {
+	addMonths := func(months int) time.Time {
+		first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).AddDate(0, months, 0)
+		last := first.AddDate(0, 1, -1).Day()
+		day := t.Day()
+		if day > last {
+			day = last
+		}
+		return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, t.Location())
+	}
+	switch cadence {
+	case models.CadenceWeekly:
+		return t.AddDate(0, 0, 7*n)
+	case models.CadenceBiweekly:
+		return t.AddDate(0, 0, 14*n)
+	case models.CadenceQuarterly:
+		return addMonths(3 * n)
+	case models.CadenceYearly:
+		return addMonths(12 * n)
+	case models.CadenceJalaliMonthly:
+		jy, jm, jd := utils.ToJalali(t)
+		ny, nm := utils.AddJalaliMonths(jy, jm, n)
+		if l := utils.JalaliMonthLength(ny, nm); jd > l {
+			jd = l
+		}
+		if d, err := utils.FromJalali(ny, nm, jd, t.Location()); err == nil {
+			return d
+		}
+	}
+	return addMonths(n)
+}
+
+// monthlyCost normalizes a series charge to a monthly amount.
+func monthlyCost(s *models.RecurringSeries) models.Money {
+	m := s.Amount.Minor
+	switch s.Cadence {
+	case models.CadenceWeekly:
+		m = m * 52 / 12
+	case models.CadenceBiweekly:
+		m = m * 26 / 12
+	case models.CadenceQuarterly:
+		m = m / 3
+	case models.CadenceYearly:
+		m = m / 12
+	}
+	return models.NewMoney(m, s.Amount.Currency)
+}
+
+// detectSeries checks one group of similar purchases (sorted by date) for a periodic pattern.
+func detectSeries(group []models.Purchase, now time.Time) *models.RecurringSeries {
+	// one charge per day
+	var dates []time.Time
+	var amounts []float64
+	for i := range group {
+		d := purchaseDate(&group[i]).Truncate(24 * time.Hour)
+		if len(dates) > 0 && d.Equal(dates[len(dates)-1]) {
+			continue
+		}
+		dates = append(dates, d)
+		amounts = append(amounts, float64(group[i].Amount.Minor))
+	}
+	if len(dates) < recurringMinOccurrences {
+		return nil
+	}
+
+	intervals := make([]float64, 0, len(dates)-1)
+	for i := 1; i < len(dates); i++ {
+		intervals = append(intervals, dates[i].Sub(dates[i-1]).Hours()/24)
+	}
+	interval := utils.Median(intervals)
+
+	var spec *cadenceSpec
+	for i := range cadenceSpecs {
+		if math.Abs(interval-cadenceSpecs[i].days) <= cadenceSpecs[i].tol {
+			spec = &cadenceSpecs[i]
+			break
+		}
+	}
+	if spec == nil {
+		return nil
+	}
+	regular := 0
+	for _, iv := range intervals {
+		if math.Abs(iv-spec.days) <= spec.tol*1.5 {
+			regular++
+		}
+	}
+	regularity := float64(regular) / float64(len(intervals))
+	if regularity < recurringMinRegularity {
+		return nil
+	}
+
+	last := dates[len(dates)-1]
+	// stopped: no charge for more than two and a half periods
+	if now.Sub(last).Hours()/24 > spec.days*2.5 {
+		return nil
+	}
+
+	cadence := spec.name
+	if cadence == models.CadenceMonthly {
+		// rent & co. are often paid on a Jalali day of month
+		var gDays, jDays []float64
+		for _, d := range dates {
+			_, _, jd := utils.ToJalali(d)
+			gDays = append(gDays, float64(d.Day()))
+			jDays = append(jDays, float64(jd))
+		}
+		if utils.MAD(jDays) < utils.MAD(gDays) {
+			cadence = models.CadenceJalaliMonthly
+		}
+	}
+
+	median := utils.Median(amounts)
+	spread := 0.0
+	if median > 0 {
+		spread = math.Min(1, utils.MAD(amounts)/median/recurringAmountTolerance)
+	}
+	confidence := 0.5*regularity + 0.3*(1-spread) + 0.2*math.Min(1, float64(len(dates))/6)
+
+	p := &group[len(group)-1]
+	return &models.RecurringSeries{
+		UserID:       p.UserID,
+		Key:          seriesKey(p),
+		Title:        p.Title,
+		Vendor:       p.Vendor,
+		VendorID:     p.VendorID,
+		Category:     p.Category,
+		Subcategory:  p.Subcategory,
+		Amount:       models.NewMoney(int64(math.Round(median)), p.Amount.Currency),
+		Cadence:      cadence,
+		IntervalDays: math.Round(interval*10) / 10,
+		Occurrences:  len(dates),
+		FirstSeen:    dates[0],
+		LastSeen:     last,
+		NextExpected: advanceCadence(cadence, last, 1),
+		Confidence:   math.Round(confidence*100) / 100,
+	}
+}
+
+// Detect scans the user's history and proposes recurring series. Existing series are
+// refreshed (dismissed ones are never proposed again). Returns the non-dismissed series found.
+func (s *RecurringService) Detect(userID int) ([]models.RecurringSeries, error) {
+	now := time.Now().UTC()
+	var purchases []models.Purchase
+	// installments have their own plan
+	if err := s.DB.Where("user_id = ? AND status <> ? AND installment_plan_id IS NULL AND purchase_time >= ?", userID, models.StatusRejected, now.Add(-recurringLookback)).
+		Order("purchase_time").Find(&purchases).Error; err != nil {
+		return nil, err
+	}
+
+	// group by vendor/title and currency, then split by amount
+	groups := map[string][]models.Purchase{}
+	var order []string
+	for _, p := range purchases {
+		k := seriesKey(&p) + "|" + p.Amount.Currency
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], p)
+	}
+
+	var found []models.RecurringSeries
+	for _, k := range order {
+		for _, cluster := range amountClusters(groups[k]) {
+			candidate := detectSeries(cluster, now)
+			if candidate == nil {
+				continue
+			}
+			series, err := s.save(candidate, cluster)
+			if err != nil {
+				return nil, err
+			}
+			if series != nil {
+				found = append(found, *series)
+			}
+		}
+	}
+	return found, nil
+}
+
+// amountClusters splits purchases into groups of similar amounts, each sorted by date.
+func amountClusters(ps []models.Purchase) [][]models.Purchase {
+	sorted := append([]models.Purchase(nil), ps...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Amount.Minor < sorted[j].Amount.Minor })
+
+	var clusters [][]models.Purchase
+	for _, p := range sorted {
+		n := len(clusters)
+		if n > 0 && amountClose(p.Amount.Minor, clusters[n-1][0].Amount.Minor) {
+			clusters[n-1] = append(clusters[n-1], p)
+			continue
+		}
+		clusters = append(clusters, []models.Purchase{p})
+	}
+	for _, c := range clusters {
+		sort.SliceStable(c, func(i, j int) bool { return purchaseDate(&c[i]).Before(purchaseDate(&c[j])) })
+	}
+	return clusters
+}
+
+// findSeries returns the user's series for the key whose amount is close to amount.
+func (s *RecurringService) findSeries(userID int, key string, amount models.Money, statuses ...string) (*models.RecurringSeries, error) {
+	q := s.DB.Where("user_id = ? AND series_key = ? AND amount_currency = ?", userID, key, amount.Currency)
+	if len(statuses) > 0 {
+		q = q.Where("status IN ?", statuses)
+	}
+	var items []models.RecurringSeries
+	if err := q.Find(&items).Error; err != nil {
+		return nil, err
+	}
+	for i := range items {
+		if amountClose(amount.Minor, items[i].Amount.Minor) {
+			return &items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// save creates or refreshes a detected series and links its purchases.
+func (s *RecurringService) save(c *models.RecurringSeries, members []models.Purchase) (*models.RecurringSeries, error) {
+	existing, err := s.findSeries(c.UserID, c.Key, c.Amount)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.Status == models.SeriesDismissed {
+			return nil, nil
+		}
+		c.ID, c.Status, c.CreatedAt = existing.ID, existing.Status, existing.CreatedAt
+	} else {
+		c.Status = models.SeriesProposed
+	}
+
+	ids := make([]uint64, 0, len(members))
+	for _, p := range members {
+		ids = append(ids, p.ID)
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(c).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Purchase{}).Where("id IN ?", ids).Update("recurring_series_id", c.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Attach links a new purchase to a matching active series and moves its forecast forward.
+func (s *RecurringService) Attach(p *models.Purchase) error {
+	series, err := s.findSeries(p.UserID, seriesKey(p), p.Amount, models.SeriesActive)
+	if err != nil || series == nil {
+		return err
+	}
+	d := purchaseDate(p).Truncate(24 * time.Hour)
+	if d.After(series.LastSeen) {
+		series.LastSeen = d
+		series.NextExpected = advanceCadence(series.Cadence, d, 1)
+	}
+	series.Occurrences++
+	p.RecurringSeriesID = &series.ID
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(series).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Purchase{}).Where("id = ?", p.ID).Update("recurring_series_id", series.ID).Error
+	})
+}
+
+func (s *RecurringService) List(userID int, status string) ([]models.RecurringSeries, error) {
+	q := s.DB.Where("user_id = ?", userID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var items []models.RecurringSeries
+	err := q.Order("next_expected").Find(&items).Error
+	return items, err
+}
+
+func (s *RecurringService) get(userID int, id uint64) (*models.RecurringSeries, error) {
+	var series models.RecurringSeries
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&series).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSeriesNotFound
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+// Confirm marks a proposed series as a real recurring expense.
+func (s *RecurringService) Confirm(userID int, id uint64) (*models.RecurringSeries, error) {
+	series, err := s.get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	series.Status = models.SeriesActive
+	return series, s.DB.Save(series).Error
+}
+
+// Dismiss rejects a series; its purchases are unlinked and it won't be proposed again.
+func (s *RecurringService) Dismiss(userID int, id uint64) (*models.RecurringSeries, error) {
+	series, err := s.get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	series.Status = models.SeriesDismissed
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(series).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Purchase{}).Where("recurring_series_id = ?", series.ID).Update("recurring_series_id", nil).Error
+	})
+	return series, err
+}
+
+// UpcomingCharge is a forecast charge of an active series.
+type UpcomingCharge struct {
+	SeriesID    uint64       `json:"series_id,omitempty"`
+	ScheduledID uint64       `json:"scheduled_id,omitempty"` // from a scheduled purchase instead of a detected series
+	PlanID      uint64       `json:"plan_id,omitempty"`      // an installment due
+	Title       string       `json:"title"`
+	Vendor      *string      `json:"vendor"`
+	VendorID    *uint64      `json:"vendor_id"`
+	Category    string       `json:"category"`
+	Amount      models.Money `json:"amount"`
+	Date        time.Time    `json:"date"`
+	Overdue     bool         `json:"overdue"` // expected before `from` but not seen yet
+}
+
+// Upcoming forecasts the charges of active series in [from, to).
+func (s *RecurringService) Upcoming(userID int, from, to time.Time) ([]UpcomingCharge, error) {
+	series, err := s.List(userID, models.SeriesActive)
+	if err != nil {
+		return nil, err
+	}
+	var out []UpcomingCharge
+	for i := range series {
+		sr := &series[i]
+		charge := func(d time.Time, overdue bool) UpcomingCharge {
+			return UpcomingCharge{SeriesID: sr.ID, Title: sr.Title, Vendor: sr.Vendor, VendorID: sr.VendorID, Category: sr.Category,
+				Amount: sr.Amount, Date: d, Overdue: overdue}
+		}
+		d, n := sr.NextExpected, 0
+		if d.Before(from) {
+			out = append(out, charge(d, true))
+			for d.Before(from) {
+				n++
+				d = advanceCadence(sr.Cadence, sr.NextExpected, n)
+			}
+		}
+		for d.Before(to) {
+			out = append(out, charge(d, false))
+			n++
+			d = advanceCadence(sr.Cadence, sr.NextExpected, n)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Date.Before(out[j].Date) })
+	return out, nil
+}
+
+// Subscription is an active series with its cost normalized to one month.
+type Subscription struct {
+	models.RecurringSeries
+	MonthlyCost models.Money `json:"monthly_cost"`
+}
+
+// Subscriptions lists active series with their monthly cost, and the monthly total per currency.
+func (s *RecurringService) Subscriptions(userID int) ([]Subscription, []models.Money, error) {
+	series, err := s.List(userID, models.SeriesActive)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make([]Subscription, 0, len(series))
+	totals := map[string]int64{}
+	var currencies []string
+	for _, sr := range series {
+		cost := monthlyCost(&sr)
+		out = append(out, Subscription{RecurringSeries: sr, MonthlyCost: cost})
+		if _, ok := totals[cost.Currency]; !ok {
+			currencies = append(currencies, cost.Currency)
+		}
+		totals[cost.Currency] += cost.Minor
+	}
+	sums := make([]models.Money, 0, len(currencies))
+	for _, c := range currencies {
+		sums = append(sums, models.NewMoney(totals[c], c))
+	}
+	return out, sums, nil
+}
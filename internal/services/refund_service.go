@@ -0,0 +1,235 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// how far back a spoken refund ("پول کفش رو پس دادن") looks for its purchase
+	refundLookback = 120 * 24 * time.Hour
+	// the best candidate must score this much, and clearly more than the second one
+	refundMatchScore  = 0.6
+	refundMatchMargin = 0.15
+)
+
+var ErrRefundNotFound = errors.New("refund not found")
+
+// AmbiguousRefundError is returned when the purchase of a refund can't be picked for sure;
+// the caller asks the user which one (POST /purchases/:id/refunds).
+type AmbiguousRefundError struct {
+	Candidates []models.Purchase
+}
+
+func (e *AmbiguousRefundError) Error() string {
+	if len(e.Candidates) == 0 {
+		return "no purchase found for this refund"
+	}
+	return fmt.Sprintf("refund matches %d purchases", len(e.Candidates))
+}
+
+// RefundService records refunds against their purchases and keeps Purchase.RefundedMinor in sync.
+type RefundService struct {
+	DB       *gorm.DB
+	Purchase *PurchaseService
+}
+
+func NewRefundService(db *gorm.DB, ps *PurchaseService) *RefundService {
+	return &RefundService{DB: db, Purchase: ps}
+}
+
+// Create refunds (part of) a purchase; a zero amount refunds what is left.
+func (s *RefundService) Create(r *models.Refund) error {
+	p, err := s.Purchase.Get(r.UserID, r.PurchaseID)
+	if err != nil {
+		return err
+	}
+	if p.Status == models.StatusRejected {
+		return errors.New("purchase is rejected")
+	}
+	left := p.Net()
+	if r.Amount.Currency == "" {
+		r.Amount.Currency = p.Amount.Currency
+	}
+	if r.Amount.IsZero() {
+		r.Amount = left
+	}
+	if r.Amount.Currency != p.Amount.Currency {
+		return fmt.Errorf("refund is in %s, the purchase in %s", r.Amount.Currency, p.Amount.Currency)
+	}
+	if !r.Amount.IsPositive() {
+		return errors.New("nothing left to refund")
+	}
+	if r.Amount.Minor > left.Minor {
+		return fmt.Errorf("refund is more than what is left of the purchase (%s)", left)
+	}
+	if r.RefundedAt == nil {
+		now := time.Now().UTC()
+		r.RefundedAt = &now
+	}
+	if r.AccountID == nil {
+		r.AccountID = p.AccountID
+	}
+	r.CreatedAt = time.Now().UTC()
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		// re-checked in the update: a concurrent refund may have taken the rest meanwhile
+		res := tx.Model(&models.Purchase{}).Where("id = ? AND refunded_minor + ? <= amount_minor", p.ID, r.Amount.Minor).
+			Update("refunded_minor", gorm.Expr("refunded_minor + ?", r.Amount.Minor))
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("refund is more than what is left of the purchase")
+		}
+		return tx.Create(r).Error
+	})
+}
+
+func (s *RefundService) List(userID int, purchaseID uint64) ([]models.Refund, error) {
+	q := s.DB.Where("user_id = ?", userID)
+	if purchaseID != 0 {
+		q = q.Where("purchase_id = ?", purchaseID)
+	}
+	var items []models.Refund
+	err := q.Order("refunded_at DESC, id DESC").Find(&items).Error
+	return items, err
+}
+
+func (s *RefundService) Delete(userID int, id uint64) error {
+	var r models.Refund
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&r).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRefundNotFound
+		}
+		return err
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&r).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Purchase{}).Where("id = ?", r.PurchaseID).
+			Update("refunded_minor", gorm.Expr("refunded_minor - ?", r.Amount.Minor)).Error
+	})
+}
+
+type refundCandidate struct {
+	purchase models.Purchase
+	score    float64
+}
+
+// FindPurchase ranks the user's recent purchases for a refund described as hint
+// (item/vendor), optionally with the refunded amount and the purchase date.
+func (s *RefundService) FindPurchase(userID int, hint string, amount models.Money, at *time.Time) ([]models.Purchase, []float64, error) {
+	hint = utils.NormalizeText(hint)
+	to := time.Now().UTC()
+	from := to.Add(-refundLookback)
+	if at != nil {
+		from, to = at.Add(-7*24*time.Hour), at.Add(24*time.Hour)
+	}
+	var items []models.Purchase
+	if err := s.DB.Where("user_id = ? AND status <> ? AND duplicate_of IS NULL AND basis <> ? AND purchase_time >= ? AND purchase_time <= ? AND refunded_minor < amount_minor",
+		userID, models.StatusRejected, models.BasisCash, from, to).Order("purchase_time DESC").Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var cands []refundCandidate
+	for _, p := range items {
+		text := 0.0
+		if hint != "" {
+			for _, t := range []string{p.Title, vendorName(p.Vendor)} {
+				t = utils.NormalizeText(t)
+				if t == "" {
+					continue
+				}
+				sc := utils.Similarity(hint, t)
+				if strings.Contains(hint, t) || strings.Contains(t, hint) {
+					sc = 1
+				}
+				text = math.Max(text, sc)
+			}
+		}
+		amountScore := 0.5 // unknown
+		if amount.IsPositive() {
+			left := p.Net()
+			switch {
+			case amount.Currency != "" && amount.Currency != left.Currency, amount.Minor > left.Minor:
+				continue
+			case amount.Minor == left.Minor:
+				amountScore = 1
+			default:
+				amountScore = 0.6 // partial refund
+			}
+		}
+		score := 0.7*text + 0.3*amountScore
+		if hint == "" {
+			score = amountScore * 0.8
+		}
+		if score >= 0.4 {
+			cands = append(cands, refundCandidate{p, round2(score)})
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].score > cands[j].score })
+	if len(cands) > 5 {
+		cands = cands[:5]
+	}
+	out, scores := make([]models.Purchase, len(cands)), make([]float64, len(cands))
+	for i, c := range cands {
+		out[i], scores[i] = c.purchase, c.score
+	}
+	return out, scores, nil
+}
+
+// FromAIData records a refund from the model's "refund" object
+// ({"purchase", "amount", "currency", "purchase_date", "refunded_at", "reason"}; amount 0 = full).
+func (s *RefundService) FromAIData(userID int, data map[string]interface{}, sourceText string) (*models.Refund, *models.Purchase, error) {
+	str := func(k string) string {
+		v, _ := data[k].(string)
+		return strings.TrimSpace(v)
+	}
+	var amount models.Money
+	if v, ok := data["amount"]; ok && v != nil {
+		if m, err := toMoney(v, str("currency")); err == nil && m.IsPositive() {
+			amount = m
+		}
+	}
+	var bought *time.Time
+	if raw := str("purchase_date"); raw != "" {
+		if d, err := utils.ParseDate(raw); err == nil {
+			bought = &d
+		}
+	}
+	cands, scores, err := s.FindPurchase(userID, str("purchase"), amount, bought)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(cands) == 0 || scores[0] < refundMatchScore || (len(cands) > 1 && scores[0]-scores[1] < refundMatchMargin) {
+		return nil, nil, &AmbiguousRefundError{Candidates: cands}
+	}
+	r := &models.Refund{
+		UserID:     userID,
+		PurchaseID: cands[0].ID,
+		Amount:     amount,
+		Reason:     str("reason"),
+		SourceText: sourceText,
+	}
+	if raw := str("refunded_at"); raw != "" {
+		if d, err := utils.ParseDate(raw); err == nil {
+			r.RefundedAt = &d
+		}
+	}
+	if err := s.Create(r); err != nil {
+		return nil, nil, err
+	}
+	p := cands[0]
+	p.RefundedMinor += r.Amount.Minor
+	return r, &p, nil
+}
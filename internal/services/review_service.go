@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"example/AI/internal/models"
+)
+
+const (
+	ReviewApprove = "approve"
+	ReviewReject  = "reject"
+	ReviewEdit    = "edit" // apply edits, keep it in the queue
+)
+
+// ReviewService handles the guessed -> confirmed / rejected workflow.
+type ReviewService struct {
+	Purchase *PurchaseService
+}
+
+func NewReviewService(ps *PurchaseService) *ReviewService {
+	return &ReviewService{Purchase: ps}
+}
+
+type ReviewItem struct {
+	ID     uint64
+	Action string
+	Edit   PurchaseCorrection
+}
+
+type ReviewResult struct {
+	ID       uint64           `json:"id"`
+	Status   string           `json:"status,omitempty"`
+	Purchase *models.Purchase `json:"purchase,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// Pending returns the user's purchases waiting for review, oldest first.
+func (s *ReviewService) Pending(userID int) ([]models.Purchase, error) {
+	var items []models.Purchase
+	err := s.Purchase.DB.Where("user_id = ? AND status = ?", userID, models.StatusGuessed).
+		Order("created_at").
+		Find(&items).Error
+	return items, err
+}
+
+// Apply processes a batch of review decisions; each item succeeds or fails on its own.
+func (s *ReviewService) Apply(userID int, items []ReviewItem) []ReviewResult {
+	results := make([]ReviewResult, 0, len(items))
+	for _, it := range items {
+		p, err := s.apply(userID, it)
+		res := ReviewResult{ID: it.ID, Purchase: p}
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Status = p.Status
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func (s *ReviewService) apply(userID int, it ReviewItem) (*models.Purchase, error) {
+	p, err := s.Purchase.Get(userID, it.ID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Status != models.StatusGuessed {
+		return nil, fmt.Errorf("purchase is %s, not pending review", p.Status)
+	}
+
+	if !it.Edit.IsEmpty() {
+		if p, _, err = s.Purchase.Correct(userID, it.ID, it.Edit); err != nil {
+			return nil, err
+		}
+	}
+
+	switch it.Action {
+	case ReviewApprove:
+		p.Status, p.ReviewReasons = models.StatusConfirmed, ""
+		if p.DuplicateOf != nil {
+			// approving a flagged purchase means it is not a duplicate
+			p.DuplicateOf, p.DuplicateCleared = nil, true
+		}
+	case ReviewReject:
+		p.Status = models.StatusRejected
+	case ReviewEdit:
+		return p, nil
+	default:
+		return nil, errors.New("action must be approve, reject or edit")
+	}
+
+	if err := s.Purchase.DB.Model(p).Select("status", "review_reasons", "duplicate_of", "duplicate_cleared").Updates(p).Error; err != nil {
+		return nil, err
+	}
+	if p.Status == models.StatusConfirmed && s.Purchase.Classifier != nil {
+		s.Purchase.Classifier.Observe(p)
+	}
+	return p, nil
+}
@@ -0,0 +1,469 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/schedule"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const DefaultSchedulerInterval = time.Hour
+
+var (
+	ErrScheduleNotFound = errors.New("scheduled purchase not found")
+	ErrOccurrenceExists = errors.New("purchase for this date was already created")
+)
+
+// ScheduleService keeps scheduled (recurring) purchases and materializes them into purchases.
+type ScheduleService struct {
+	DB       *gorm.DB
+	Purchase *PurchaseService
+
+	mu sync.Mutex // one materialization at a time (ticker vs. API)
+}
+
+func NewScheduleService(db *gorm.DB, ps *PurchaseService) *ScheduleService {
+	return &ScheduleService{DB: db, Purchase: ps}
+}
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// prepare validates a definition and maps category/vendor like normal purchases.
+func (s *ScheduleService) prepare(sp *models.ScheduledPurchase) (*schedule.Rule, error) {
+	if strings.TrimSpace(sp.Title) == "" {
+		return nil, errors.New("title is required")
+	}
+	if !sp.Amount.IsPositive() {
+		return nil, errors.New("amount must be positive")
+	}
+	rule, err := schedule.Parse(sp.Rule)
+	if err != nil {
+		return nil, err
+	}
+	sp.Rule = rule.String()
+	if sp.StartDate.IsZero() {
+		sp.StartDate = today()
+	}
+	sp.StartDate = sp.StartDate.UTC().Truncate(24 * time.Hour)
+	if sp.EndDate != nil && sp.EndDate.Before(sp.StartDate) {
+		return nil, errors.New("end_date is before start_date")
+	}
+	if sp.Status == "" {
+		sp.Status = models.ScheduleActive
+	}
+
+	var vendor *models.Vendor
+	if sp.Vendor != nil && *sp.Vendor != "" && s.Purchase.Vendors != nil {
+		if v, err := s.Purchase.Vendors.Match(sp.UserID, *sp.Vendor); err == nil {
+			sp.Vendor, sp.VendorID, vendor = &v.Name, &v.ID, v
+		}
+	}
+	if s.Purchase.Taxonomy != nil {
+		cat, sub, err := s.Purchase.Taxonomy.Resolve(sp.UserID, sp.Category, sp.Subcategory)
+		if err != nil {
+			return nil, err
+		}
+		sp.Category, sp.Subcategory = cat, sub
+	}
+	if vendor != nil && vendor.DefaultCategory != "" && (sp.Category == "" || sp.Category == models.OtherCategory) {
+		sp.Category, sp.Subcategory = vendor.DefaultCategory, vendor.DefaultSubcategory
+	}
+	return rule, nil
+}
+
+// Create saves the definition and materializes what is already due.
+func (s *ScheduleService) Create(sp *models.ScheduledPurchase) ([]models.Purchase, error) {
+	if _, err := s.prepare(sp); err != nil {
+		return nil, err
+	}
+	if err := s.DB.Create(sp).Error; err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.materialize(sp, time.Now().UTC())
+}
+
+// FromAIData creates a schedule from the model's "schedule" object
+// ({"title", "amount", "currency", "category", "subcategory", "vendor", "rrule", "start_date", "end_date"}).
+func (s *ScheduleService) FromAIData(userID int, data map[string]interface{}, source string) (*models.ScheduledPurchase, []models.Purchase, error) {
+	str := func(k string) string {
+		v, _ := data[k].(string)
+		return strings.TrimSpace(v)
+	}
+	amount, err := toMoney(data["amount"], str("currency"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid amount: %w", err)
+	}
+	sp := &models.ScheduledPurchase{
+		UserID:      userID,
+		Title:       str("title"),
+		Amount:      amount,
+		Category:    str("category"),
+		Subcategory: str("subcategory"),
+		Rule:        str("rrule"),
+		SourceText:  source,
+	}
+	if v := str("vendor"); v != "" {
+		sp.Vendor = &v
+	}
+	if v := str("start_date"); v != "" {
+		if sp.StartDate, err = utils.ParseDate(v); err != nil {
+			return nil, nil, err
+		}
+	}
+	if v := str("end_date"); v != "" {
+		end, err := utils.ParseDate(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		sp.EndDate = &end
+	}
+	created, err := s.Create(sp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sp, created, nil
+}
+
+func (s *ScheduleService) List(userID int) ([]models.ScheduledPurchase, error) {
+	var items []models.ScheduledPurchase
+	err := s.DB.Where("user_id = ?", userID).Order("status, next_run").Find(&items).Error
+	return items, err
+}
+
+func (s *ScheduleService) Get(userID int, id uint64) (*models.ScheduledPurchase, error) {
+	var sp models.ScheduledPurchase
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&sp).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, err
+	}
+	return &sp, nil
+}
+
+func (s *ScheduleService) Occurrences(userID int, id uint64) ([]models.ScheduledOccurrence, error) {
+	if _, err := s.Get(userID, id); err != nil {
+		return nil, err
+	}
+	var items []models.ScheduledOccurrence
+	err := s.DB.Where("scheduled_purchase_id = ?", id).Order("date").Find(&items).Error
+	return items, err
+}
+
+// nextRun is the first future occurrence that isn't materialized or skipped yet.
+func (s *ScheduleService) nextRun(sp *models.ScheduledPurchase, rule *schedule.Rule) (*time.Time, error) {
+	from := sp.StartDate
+	if sp.MaterializedUntil != nil && sp.MaterializedUntil.After(from) {
+		from = *sp.MaterializedUntil
+	}
+	var done []time.Time
+	if err := s.DB.Model(&models.ScheduledOccurrence{}).Where("scheduled_purchase_id = ? AND date >= ?", sp.ID, from).
+		Pluck("date", &done).Error; err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, d := range done {
+		seen[d.UTC().Format("2006-01-02")] = true
+	}
+
+	var next *time.Time
+	rule.Each(sp.StartDate, func(d time.Time) bool {
+		if sp.EndDate != nil && d.After(*sp.EndDate) {
+			return false
+		}
+		if d.Before(from) || seen[d.Format("2006-01-02")] {
+			return true
+		}
+		next = &d
+		return false
+	})
+	return next, nil
+}
+
+// materialize creates the purchases of all due occurrences of one schedule (idempotent:
+// dates that already have an occurrence row are left alone). Caller holds s.mu.
+func (s *ScheduleService) materialize(sp *models.ScheduledPurchase, now time.Time) ([]models.Purchase, error) {
+	if sp.Status != models.ScheduleActive {
+		return nil, nil
+	}
+	rule, err := schedule.Parse(sp.Rule)
+	if err != nil {
+		return nil, err
+	}
+
+	from := sp.StartDate
+	if sp.MaterializedUntil != nil {
+		from = *sp.MaterializedUntil
+	}
+	to := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if sp.EndDate != nil && sp.EndDate.AddDate(0, 0, 1).Before(to) {
+		to = sp.EndDate.AddDate(0, 0, 1)
+	}
+
+	var created []models.Purchase
+	for _, d := range rule.Between(sp.StartDate, from, to) {
+		var p *models.Purchase
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			var n int64
+			if err := tx.Model(&models.ScheduledOccurrence{}).Where("scheduled_purchase_id = ? AND date = ?", sp.ID, d).Count(&n).Error; err != nil {
+				return err
+			}
+			if n > 0 {
+				return nil // already created or skipped
+			}
+			date := d
+			p = &models.Purchase{
+				UserID:       sp.UserID,
+				Title:        sp.Title,
+				Amount:       sp.Amount,
+				Category:     sp.Category,
+				Subcategory:  sp.Subcategory,
+				Vendor:       sp.Vendor,
+				VendorID:     sp.VendorID,
+				PurchaseTime: &date,
+				CreatedAt:    now,
+				Necessity:    "high",
+				ReasonGuess:  "خرید زمان‌بندی‌شده",
+				Confidence:   1,
+				Status:       models.StatusConfirmed,
+				SourceText:   sp.SourceText,
+			}
+			if err := s.Purchase.insert(tx, p, nil); err != nil {
+				return err
+			}
+			return tx.Create(&models.ScheduledOccurrence{
+				ScheduledPurchaseID: sp.ID,
+				Date:                d,
+				PurchaseID:          &p.ID,
+				CreatedAt:           now,
+			}).Error
+		})
+		if err != nil {
+			return created, err
+		}
+		if p != nil {
+			created = append(created, *p)
+			s.Purchase.afterCreate(p)
+			if s.Purchase.Budgets != nil {
+				// nobody is waiting for a reply here; the warnings go to the log
+				if warnings, _ := s.Purchase.Budgets.Evaluate(p); len(warnings) > 0 {
+					log.Printf("scheduler: user %d: %s", p.UserID, strings.Join(warnings, " "))
+				}
+			}
+		}
+	}
+
+	sp.MaterializedUntil = &to
+	if sp.NextRun, err = s.nextRun(sp, rule); err != nil {
+		return created, err
+	}
+	if sp.NextRun == nil {
+		sp.Status = models.ScheduleEnded
+	}
+	return created, s.DB.Save(sp).Error
+}
+
+// MaterializeAll runs every active schedule that has something due; missed runs (downtime)
+// are caught up because each schedule continues from where it stopped.
+func (s *ScheduleService) MaterializeAll(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []models.ScheduledPurchase
+	if err := s.DB.Where("status = ? AND (next_run IS NULL OR next_run <= ?)", models.ScheduleActive, now).
+		Find(&due).Error; err != nil {
+		return 0, err
+	}
+	total := 0
+	var errs []error
+	for i := range due {
+		created, err := s.materialize(&due[i], now)
+		total += len(created)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schedule %d: %w", due[i].ID, err))
+		}
+	}
+	return total, errors.Join(errs...)
+}
+
+// Start runs the scheduler in the background: once right away (catch-up) and then every interval.
+func (s *ScheduleService) Start(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSchedulerInterval
+	}
+	run := func() {
+		n, err := s.MaterializeAll(time.Now().UTC())
+		if err != nil {
+			log.Printf("scheduler error: %v", err)
+		}
+		if n > 0 {
+			log.Printf("scheduler: %d purchases created", n)
+		}
+	}
+	go func() {
+		run()
+		for range time.Tick(interval) {
+			run()
+		}
+	}()
+}
+
+func (s *ScheduleService) Pause(userID int, id uint64) (*models.ScheduledPurchase, error) {
+	sp, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sp.Status != models.ScheduleActive {
+		return nil, fmt.Errorf("schedule is %s", sp.Status)
+	}
+	sp.Status = models.SchedulePaused
+	return sp, s.DB.Save(sp).Error
+}
+
+// Resume re-activates a paused schedule; dates that passed while paused are not created.
+func (s *ScheduleService) Resume(userID int, id uint64) (*models.ScheduledPurchase, []models.Purchase, error) {
+	sp, err := s.Get(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sp.Status != models.SchedulePaused {
+		return nil, nil, fmt.Errorf("schedule is %s", sp.Status)
+	}
+	t := today()
+	sp.Status = models.ScheduleActive
+	if sp.MaterializedUntil == nil || sp.MaterializedUntil.Before(t) {
+		sp.MaterializedUntil = &t
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	created, err := s.materialize(sp, time.Now().UTC())
+	return sp, created, err
+}
+
+// Skip marks one date (default: the next run) so no purchase is created for it.
+func (s *ScheduleService) Skip(userID int, id uint64, date *time.Time) (*models.ScheduledOccurrence, error) {
+	sp, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	rule, err := schedule.Parse(sp.Rule)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var d time.Time
+	if date != nil {
+		d = date.UTC().Truncate(24 * time.Hour)
+		if len(rule.Between(sp.StartDate, d, d.AddDate(0, 0, 1))) == 0 {
+			return nil, fmt.Errorf("%s is not a scheduled date", d.Format("2006-01-02"))
+		}
+	} else if sp.NextRun != nil {
+		d = *sp.NextRun
+	} else {
+		return nil, errors.New("nothing to skip")
+	}
+
+	var occ models.ScheduledOccurrence
+	err = s.DB.Where("scheduled_purchase_id = ? AND date = ?", sp.ID, d).First(&occ).Error
+	if err == nil {
+		if !occ.Skipped {
+			return nil, ErrOccurrenceExists
+		}
+		return &occ, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	occ = models.ScheduledOccurrence{ScheduledPurchaseID: sp.ID, Date: d, Skipped: true, CreatedAt: time.Now().UTC()}
+	if err := s.DB.Create(&occ).Error; err != nil {
+		return nil, err
+	}
+	if sp.NextRun, err = s.nextRun(sp, rule); err != nil {
+		return nil, err
+	}
+	return &occ, s.DB.Save(sp).Error
+}
+
+// End stops the schedule after date (default: today).
+func (s *ScheduleService) End(userID int, id uint64, date *time.Time) (*models.ScheduledPurchase, error) {
+	sp, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	end := today()
+	if date != nil {
+		end = date.UTC().Truncate(24 * time.Hour)
+	}
+	if end.Before(sp.StartDate) {
+		end = sp.StartDate
+	}
+	sp.EndDate = &end
+	rule, err := schedule.Parse(sp.Rule)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sp.NextRun, err = s.nextRun(sp, rule); err != nil {
+		return nil, err
+	}
+	if sp.NextRun == nil {
+		sp.Status = models.ScheduleEnded
+	}
+	return sp, s.DB.Save(sp).Error
+}
+
+// Upcoming lists the not yet materialized (and not skipped) dates of active schedules in [from, to).
+func (s *ScheduleService) Upcoming(userID int, from, to time.Time) ([]UpcomingCharge, error) {
+	var items []models.ScheduledPurchase
+	if err := s.DB.Where("user_id = ? AND status = ?", userID, models.ScheduleActive).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	var out []UpcomingCharge
+	for i := range items {
+		sp := &items[i]
+		rule, err := schedule.Parse(sp.Rule)
+		if err != nil {
+			continue
+		}
+		start := from
+		if sp.MaterializedUntil != nil && sp.MaterializedUntil.After(start) {
+			start = *sp.MaterializedUntil
+		}
+		end := to
+		if sp.EndDate != nil && sp.EndDate.AddDate(0, 0, 1).Before(end) {
+			end = sp.EndDate.AddDate(0, 0, 1)
+		}
+		var taken []time.Time
+		if err := s.DB.Model(&models.ScheduledOccurrence{}).Where("scheduled_purchase_id = ? AND date >= ?", sp.ID, start).
+			Pluck("date", &taken).Error; err != nil {
+			return nil, err
+		}
+		done := map[string]bool{}
+		for _, d := range taken {
+			done[d.UTC().Format("2006-01-02")] = true
+		}
+		for _, d := range rule.Between(sp.StartDate, start, end) {
+			if done[d.Format("2006-01-02")] {
+				continue
+			}
+			out = append(out, UpcomingCharge{ScheduledID: sp.ID, Title: sp.Title, Vendor: sp.Vendor,
+				VendorID: sp.VendorID, Category: sp.Category, Amount: sp.Amount, Date: d})
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,493 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// a statement line and a purchase can be this far apart (posting delays, weekends)
+	DefaultStatementWindow = 72 * time.Hour
+	// auto-match threshold; lines below it only get suggestions
+	StatementMatchThreshold = 0.75
+	statementSuggestScore   = 0.5
+	// what the user typed may be rounded ("حدود ۲۵۰ تومن")
+	statementAmountTolerance = 0.05
+)
+
+var ErrStatementLineNotFound = errors.New("statement line not found")
+
+// StatementService reconciles an imported bank statement (an ImportJob) with the purchases
+// the users logged by chat. The bank is the source of truth for matched amounts.
+type StatementService struct {
+	DB       *gorm.DB
+	Purchase *PurchaseService
+	Window   time.Duration
+}
+
+func NewStatementService(db *gorm.DB, ps *PurchaseService, window time.Duration) *StatementService {
+	if window <= 0 {
+		window = DefaultStatementWindow
+	}
+	return &StatementService{DB: db, Purchase: ps, Window: window}
+}
+
+type StatementSuggestion struct {
+	Purchase models.Purchase `json:"purchase"`
+	Score    float64         `json:"score"`
+}
+
+// StatementReport is the state of a reconciliation: both sides of what is still unmatched.
+type StatementReport struct {
+	Job                *models.ImportJob                `json:"job"`
+	Lines              []models.StatementLine           `json:"lines"`
+	Matched            int                              `json:"matched"`
+	Ignored            int                              `json:"ignored"`
+	UnmatchedLines     []models.StatementLine           `json:"unmatched_lines"`
+	UnmatchedPurchases []models.Purchase                `json:"unmatched_purchases"`
+	Suggestions        map[uint64][]StatementSuggestion `json:"suggestions"` // line id -> candidates
+}
+
+func lineDate(l *models.StatementLine) time.Time {
+	if l.Time != nil {
+		return l.Time.UTC()
+	}
+	return l.CreatedAt.UTC()
+}
+
+// matchScore rates how likely a statement line is the payment of a logged purchase.
+func (s *StatementService) matchScore(l *models.StatementLine, p *models.Purchase) float64 {
+	if l.Amount.Currency != p.Amount.Currency || l.Amount.Minor <= 0 || p.Amount.Minor <= 0 {
+		return 0
+	}
+	if l.AccountID != nil && p.AccountID != nil && *l.AccountID != *p.AccountID {
+		return 0 // paid from another account
+	}
+	diff := math.Abs(float64(l.Amount.Minor - p.Amount.Minor))
+	ratio := diff / math.Max(float64(l.Amount.Minor), float64(p.Amount.Minor))
+	var amountScore float64
+	switch {
+	case diff == 0:
+		amountScore = 1
+	case ratio <= duplicateAmountTolerance:
+		amountScore = 0.8
+	case ratio <= statementAmountTolerance:
+		amountScore = 0.5
+	default:
+		return 0
+	}
+
+	gap := lineDate(l).Sub(purchaseDate(p))
+	if gap < 0 {
+		gap = -gap
+	}
+	if gap > s.Window {
+		return 0
+	}
+	dateScore := 1 - float64(gap)/float64(s.Window+24*time.Hour)
+
+	// bank descriptions are often cryptic: unknown text is neutral, not a mismatch
+	textScore := 0.5
+	for _, t := range []string{vendorName(p.Vendor), p.Title} {
+		if t = utils.NormalizeText(t); t == "" {
+			continue
+		}
+		for _, d := range []string{l.Description, vendorName(l.Vendor)} {
+			if d = utils.NormalizeText(d); d != "" {
+				textScore = math.Max(textScore, utils.Similarity(d, t))
+			}
+		}
+	}
+	return round2(0.5*amountScore + 0.25*dateScore + 0.25*textScore)
+}
+
+// FromImport reads the parsed rows of an import as a statement (instead of committing them as
+// purchases) and auto-matches them.
+func (s *StatementService) FromImport(userID int, jobID uint64, accountID *uint64) (*StatementReport, error) {
+	var job models.ImportJob
+	if err := s.DB.Where("id = ? AND user_id = ?", jobID, userID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImportNotFound
+		}
+		return nil, err
+	}
+	switch job.Status {
+	case models.ImportReconciled:
+		return nil, errors.New("import is already a statement")
+	case models.ImportCommitted:
+		return nil, errors.New("import was committed as purchases")
+	}
+	if accountID != nil && s.Purchase.Accounts != nil {
+		if _, err := s.Purchase.Accounts.Get(userID, *accountID); err != nil {
+			return nil, err
+		}
+	}
+
+	// duplicate rows are exactly the ones already logged, so they are kept too
+	var rows []models.ImportRow
+	if err := s.DB.Where("job_id = ? AND status IN ?", job.ID, []string{models.RowValid, models.RowDuplicate}).
+		Order("row_num").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("import has no valid rows")
+	}
+	now := time.Now().UTC()
+	lines := make([]models.StatementLine, len(rows))
+	for i, r := range rows {
+		lines[i] = models.StatementLine{
+			UserID:      userID,
+			JobID:       job.ID,
+			RowNum:      r.RowNum,
+			AccountID:   accountID,
+			Time:        r.PurchaseTime,
+			Amount:      r.Amount,
+			Description: r.Title,
+			Vendor:      r.Vendor,
+			Status:      models.LineUnmatched,
+			CreatedAt:   now,
+		}
+	}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(lines, DefaultImportBatchSize).Error; err != nil {
+			return err
+		}
+		return tx.Model(&job).Update("status", models.ImportReconciled).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.AutoMatch(userID, job.ID); err != nil {
+		return nil, err
+	}
+	return s.Report(userID, job.ID)
+}
+
+func (s *StatementService) lines(userID int, jobID uint64) ([]models.StatementLine, error) {
+	var lines []models.StatementLine
+	err := s.DB.Where("user_id = ? AND job_id = ?", userID, jobID).Order("row_num, id").Find(&lines).Error
+	return lines, err
+}
+
+// candidates are the countable purchases around the lines that no statement line claims yet.
+func (s *StatementService) candidates(userID int, lines []models.StatementLine) ([]models.Purchase, error) {
+	var from, to *time.Time
+	for i := range lines {
+		d := lineDate(&lines[i])
+		if from == nil || d.Before(*from) {
+			from = &d
+		}
+		if to == nil || d.After(*to) {
+			to = &d
+		}
+	}
+	if from == nil {
+		return nil, nil
+	}
+	var items []models.Purchase
+	err := s.DB.Where("user_id = ? AND status <> ? AND duplicate_of IS NULL AND basis <> ? AND purchase_time >= ? AND purchase_time <= ?",
+		userID, models.StatusRejected, models.BasisAccrual, from.Add(-s.Window), to.Add(s.Window)).
+		Where("id NOT IN (?)", s.DB.Model(&models.StatementLine{}).Select("purchase_id").
+			Where("user_id = ? AND purchase_id IS NOT NULL", userID)).
+		Order("purchase_time, id").Find(&items).Error
+	return items, err
+}
+
+// AutoMatch pairs unmatched lines and purchases, best scores first, each side used once.
+// Returns the number of new matches.
+func (s *StatementService) AutoMatch(userID int, jobID uint64) (int, error) {
+	all, err := s.lines(userID, jobID)
+	if err != nil {
+		return 0, err
+	}
+	var open []models.StatementLine
+	for _, l := range all {
+		if l.Status == models.LineUnmatched {
+			open = append(open, l)
+		}
+	}
+	purchases, err := s.candidates(userID, open)
+	if err != nil || len(purchases) == 0 {
+		return 0, err
+	}
+
+	type pair struct {
+		line, purchase int
+		score          float64
+	}
+	var pairs []pair
+	for i := range open {
+		for j := range purchases {
+			if sc := s.matchScore(&open[i], &purchases[j]); sc >= StatementMatchThreshold {
+				pairs = append(pairs, pair{i, j, sc})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool { return pairs[a].score > pairs[b].score })
+
+	usedLine, usedPurchase := map[int]bool{}, map[int]bool{}
+	n := 0
+	for _, p := range pairs {
+		if usedLine[p.line] || usedPurchase[p.purchase] {
+			continue
+		}
+		usedLine[p.line], usedPurchase[p.purchase] = true, true
+		if err := s.link(&open[p.line], &purchases[p.purchase], p.score, models.MatchAuto); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// link matches a line with a purchase: the purchase is confirmed with the bank's amount.
+func (s *StatementService) link(l *models.StatementLine, p *models.Purchase, score float64, by string) error {
+	l.Status, l.PurchaseID, l.MatchScore, l.MatchedBy = models.LineMatched, &p.ID, score, by
+	l.LoggedAmount, l.LoggedStatus = p.Amount, p.Status
+
+	p.Amount, p.Status = l.Amount, models.StatusConfirmed
+	p.ReviewReasons = removeReason(removeReason(p.ReviewReasons, "bank_sms"), "low_confidence")
+	if p.AccountID == nil {
+		p.AccountID = l.AccountID
+	}
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(p).Updates(map[string]interface{}{
+			"amount_minor":    p.Amount.Minor,
+			"amount_currency": p.Amount.Currency,
+			"status":          p.Status,
+			"review_reasons":  p.ReviewReasons,
+			"account_id":      p.AccountID,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Select("status", "purchase_id", "match_score", "matched_by", "logged_minor", "logged_currency", "logged_status").
+			Updates(l).Error
+	})
+}
+
+func (s *StatementService) getLine(userID int, id uint64) (*models.StatementLine, error) {
+	var l models.StatementLine
+	if err := s.DB.Where("id = ? AND user_id = ?", id, userID).First(&l).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrStatementLineNotFound
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// Match links a line to a purchase chosen by the user.
+func (s *StatementService) Match(userID int, lineID, purchaseID uint64) (*models.StatementLine, error) {
+	l, err := s.getLine(userID, lineID)
+	if err != nil {
+		return nil, err
+	}
+	if l.Status == models.LineMatched {
+		if l.PurchaseID != nil && *l.PurchaseID == purchaseID {
+			return l, nil
+		}
+		if _, err := s.Unmatch(userID, lineID); err != nil {
+			return nil, err
+		}
+		l.Status = models.LineUnmatched
+	}
+	p, err := s.Purchase.Get(userID, purchaseID)
+	if err != nil {
+		return nil, err
+	}
+	if p.Amount.Currency != l.Amount.Currency {
+		return nil, fmt.Errorf("purchase is in %s, the statement line in %s", p.Amount.Currency, l.Amount.Currency)
+	}
+	var taken int64
+	if err := s.DB.Model(&models.StatementLine{}).Where("user_id = ? AND purchase_id = ? AND id <> ?", userID, p.ID, l.ID).
+		Count(&taken).Error; err != nil {
+		return nil, err
+	}
+	if taken > 0 {
+		return nil, errors.New("purchase is already matched to another statement line")
+	}
+	if err := s.link(l, p, s.matchScore(l, p), models.MatchManual); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Unmatch undoes a match; the purchase gets back its logged amount and status.
+func (s *StatementService) Unmatch(userID int, lineID uint64) (*models.StatementLine, error) {
+	l, err := s.getLine(userID, lineID)
+	if err != nil {
+		return nil, err
+	}
+	if l.Status != models.LineMatched || l.PurchaseID == nil {
+		return nil, errors.New("statement line is not matched")
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if l.MatchedBy == models.MatchCreated {
+			// the purchase only existed because of the line
+			if err := tx.Model(&models.Purchase{}).Where("id = ?", *l.PurchaseID).
+				Update("status", models.StatusRejected).Error; err != nil {
+				return err
+			}
+		} else if l.LoggedStatus != "" {
+			if err := tx.Model(&models.Purchase{}).Where("id = ?", *l.PurchaseID).Updates(map[string]interface{}{
+				"amount_minor":    l.LoggedAmount.Minor,
+				"amount_currency": l.LoggedAmount.Currency,
+				"status":          l.LoggedStatus,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(l).Updates(map[string]interface{}{
+			"status": models.LineUnmatched, "purchase_id": nil, "match_score": 0, "matched_by": "",
+			"logged_minor": 0, "logged_status": "",
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.Status, l.PurchaseID, l.MatchScore, l.MatchedBy = models.LineUnmatched, nil, 0, ""
+	l.LoggedAmount, l.LoggedStatus = models.Money{}, ""
+	return l, nil
+}
+
+// Ignore marks a line that has nothing to match (bank fee, own transfer, ...).
+func (s *StatementService) Ignore(userID int, lineID uint64) (*models.StatementLine, error) {
+	l, err := s.getLine(userID, lineID)
+	if err != nil {
+		return nil, err
+	}
+	if l.Status == models.LineMatched {
+		return nil, errors.New("statement line is matched; unmatch it first")
+	}
+	l.Status = models.LineIgnored
+	return l, s.DB.Model(l).Update("status", l.Status).Error
+}
+
+// CreatePurchase records a statement line the user never logged as a new confirmed purchase.
+func (s *StatementService) CreatePurchase(userID int, lineID uint64) (*models.Purchase, error) {
+	l, err := s.getLine(userID, lineID)
+	if err != nil {
+		return nil, err
+	}
+	if l.Status == models.LineMatched {
+		return nil, errors.New("statement line is already matched")
+	}
+	p := &models.Purchase{
+		UserID:       userID,
+		Title:        l.Description,
+		Amount:       l.Amount,
+		Vendor:       l.Vendor,
+		PurchaseTime: l.Time,
+		CreatedAt:    time.Now().UTC(),
+		Confidence:   1,
+		Status:       models.StatusConfirmed,
+		SourceText:   fmt.Sprintf("statement #%d row %d", l.JobID, l.RowNum),
+		AccountID:    l.AccountID,
+	}
+	if p.Title == "" {
+		p.Title = vendorName(l.Vendor)
+	}
+	if err := s.Purchase.Normalize(p); err != nil {
+		return nil, err
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(p).Error; err != nil {
+			return err
+		}
+		l.Status, l.PurchaseID, l.MatchScore, l.MatchedBy = models.LineMatched, &p.ID, 1, models.MatchCreated
+		return tx.Model(l).Select("status", "purchase_id", "match_score", "matched_by").Updates(l).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if s.Purchase.Classifier != nil {
+		s.Purchase.Classifier.Observe(p)
+	}
+	return p, nil
+}
+
+// Report lists the lines, what is unmatched on both sides and suggestions for manual matching.
+func (s *StatementService) Report(userID int, jobID uint64) (*StatementReport, error) {
+	var job models.ImportJob
+	if err := s.DB.Where("id = ? AND user_id = ? AND status = ?", jobID, userID, models.ImportReconciled).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImportNotFound
+		}
+		return nil, err
+	}
+	lines, err := s.lines(userID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	rep := &StatementReport{
+		Job:                &job,
+		Lines:              lines,
+		UnmatchedLines:     []models.StatementLine{},
+		UnmatchedPurchases: []models.Purchase{},
+		Suggestions:        map[uint64][]StatementSuggestion{},
+	}
+	for _, l := range lines {
+		switch l.Status {
+		case models.LineMatched:
+			rep.Matched++
+		case models.LineIgnored:
+			rep.Ignored++
+		default:
+			rep.UnmatchedLines = append(rep.UnmatchedLines, l)
+		}
+	}
+
+	purchases, err := s.candidates(userID, lines)
+	if err != nil {
+		return nil, err
+	}
+	// only purchases inside the statement period (and of its account) are missing from it
+	var first, last time.Time
+	for i := range lines {
+		d := lineDate(&lines[i])
+		if i == 0 || d.Before(first) {
+			first = d
+		}
+		if i == 0 || d.After(last) {
+			last = d
+		}
+	}
+	var account *uint64
+	if len(lines) > 0 {
+		account = lines[0].AccountID
+	}
+	for _, p := range purchases {
+		pd := purchaseDate(&p)
+		if pd.Before(first.Add(-24*time.Hour)) || pd.After(last.Add(24*time.Hour)) {
+			continue
+		}
+		if account == nil || p.AccountID == nil || *p.AccountID == *account {
+			rep.UnmatchedPurchases = append(rep.UnmatchedPurchases, p)
+		}
+	}
+	for i := range rep.UnmatchedLines {
+		l := &rep.UnmatchedLines[i]
+		var sugg []StatementSuggestion
+		for j := range purchases {
+			if sc := s.matchScore(l, &purchases[j]); sc >= statementSuggestScore {
+				sugg = append(sugg, StatementSuggestion{Purchase: purchases[j], Score: sc})
+			}
+		}
+		sort.SliceStable(sugg, func(a, b int) bool { return sugg[a].Score > sugg[b].Score })
+		if len(sugg) > 3 {
+			sugg = sugg[:3]
+		}
+		if len(sugg) > 0 {
+			rep.Suggestions[l.ID] = sugg
+		}
+	}
+	return rep, nil
+}
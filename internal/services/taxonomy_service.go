@@ -0,0 +1,543 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+type TaxonomyService struct {
+	DB *gorm.DB
+
+	mu    sync.Mutex
+	cache map[int]*taxonomyIndex // per user; dropped on every taxonomy write
+	gen   int                    // bumped on every write, so an index built meanwhile isn't cached
+}
+
+func NewTaxonomyService(db *gorm.DB) *TaxonomyService {
+	return &TaxonomyService{DB: db, cache: map[int]*taxonomyIndex{}}
+}
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+// taxonomyIndex is the taxonomy visible to one user (global + custom), indexed for lookups.
+type taxonomyIndex struct {
+	byID   map[uint64]*models.Category
+	byKey  map[string]*models.Category
+	byText map[string]*models.Category // normalized key / label / synonym
+	roots  []*models.Category
+}
+
+// load returns the user's taxonomy index from the cache, building it on first use.
+// The index is shared: callers must not modify it.
+func (s *TaxonomyService) load(userID int) (*taxonomyIndex, error) {
+	s.mu.Lock()
+	idx, ok := s.cache[userID]
+	gen := s.gen
+	s.mu.Unlock()
+	if ok {
+		return idx, nil
+	}
+	idx, err := s.build(userID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if s.gen == gen {
+		s.cache[userID] = idx
+	}
+	s.mu.Unlock()
+	return idx, nil
+}
+
+// invalidate drops the cached index of the owner, or of everyone for a global change.
+func (s *TaxonomyService) invalidate(ownerID *int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gen++
+	if ownerID == nil {
+		s.cache = map[int]*taxonomyIndex{}
+		return
+	}
+	delete(s.cache, *ownerID)
+}
+
+func (s *TaxonomyService) build(userID int) (*taxonomyIndex, error) {
+	var cats []models.Category
+	if err := s.DB.Preload("Labels").
+		Preload("Synonyms", "user_id IS NULL OR user_id = ?", userID).
+		Where("user_id IS NULL OR user_id = ?", userID).
+		Order("id").
+		Find(&cats).Error; err != nil {
+		return nil, err
+	}
+
+	idx := &taxonomyIndex{
+		byID:   map[uint64]*models.Category{},
+		byKey:  map[string]*models.Category{},
+		byText: map[string]*models.Category{},
+	}
+	for i := range cats {
+		c := &cats[i]
+		idx.byID[c.ID] = c
+		// custom categories win over global ones with the same key
+		if prev, ok := idx.byKey[c.Key]; !ok || prev.UserID == nil {
+			idx.byKey[c.Key] = c
+		}
+	}
+
+	// global texts first, then the user's own so they override
+	for _, pass := range []bool{false, true} {
+		for i := range cats {
+			c := &cats[i]
+			if (c.UserID != nil) != pass {
+				continue
+			}
+			idx.byText[utils.NormalizeText(strings.ReplaceAll(c.Key, "-", " "))] = c
+			for _, l := range c.Labels {
+				idx.byText[utils.NormalizeText(l.Label)] = c
+			}
+		}
+		for i := range cats {
+			for _, syn := range cats[i].Synonyms {
+				if (syn.UserID != nil) == pass {
+					idx.byText[syn.Text] = &cats[i]
+				}
+			}
+		}
+	}
+
+	for i := range cats {
+		c := &cats[i]
+		if c.ParentID == nil {
+			idx.roots = append(idx.roots, c)
+		} else if p, ok := idx.byID[*c.ParentID]; ok {
+			p.Children = append(p.Children, *c)
+		}
+	}
+	return idx, nil
+}
+
+// lookup finds a category by exact normalized text, then by its longest matching word run.
+func (idx *taxonomyIndex) lookup(raw string) *models.Category {
+	n := utils.NormalizeText(raw)
+	if n == "" {
+		return nil
+	}
+	if c, ok := idx.byKey[strings.ReplaceAll(n, " ", "-")]; ok {
+		return c
+	}
+	if c, ok := idx.byText[n]; ok {
+		return c
+	}
+	words := strings.Fields(n)
+	for size := len(words) - 1; size > 0; size-- {
+		for i := 0; i+size <= len(words); i++ {
+			if c, ok := idx.byText[strings.Join(words[i:i+size], " ")]; ok {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+func (idx *taxonomyIndex) parent(c *models.Category) *models.Category {
+	if c == nil || c.ParentID == nil {
+		return nil
+	}
+	return idx.byID[*c.ParentID]
+}
+
+// Resolve maps the model's free-text category/subcategory to canonical keys.
+// Unknown categories fall back to "other"; an unknown subcategory is dropped.
+func (s *TaxonomyService) Resolve(userID int, category, subcategory string) (string, string, error) {
+	idx, err := s.load(userID)
+	if err != nil {
+		return "", "", err
+	}
+	cat, sub := idx.resolve(category, subcategory)
+	return cat, sub, nil
+}
+
+func (idx *taxonomyIndex) resolve(category, subcategory string) (string, string) {
+	cat := idx.lookup(category)
+	sub := idx.lookup(subcategory)
+
+	// model put a subcategory into "category"
+	if cat != nil && cat.ParentID != nil {
+		if sub == nil {
+			sub = cat
+		}
+		cat = idx.parent(cat)
+	}
+	// model put a top-level category into "subcategory"
+	if sub != nil && sub.ParentID == nil {
+		if cat == nil {
+			cat = sub
+		}
+		sub = nil
+	}
+	if sub != nil {
+		if cat == nil {
+			cat = idx.parent(sub)
+		} else if *sub.ParentID != cat.ID {
+			sub = nil
+		}
+	}
+
+	catKey, subKey := models.OtherCategory, ""
+	if cat != nil {
+		catKey = cat.Key
+	}
+	if sub != nil {
+		subKey = sub.Key
+	}
+	return catKey, subKey
+}
+
+// CanonicalKeys maps filter values (labels, synonyms, subcategories) to top-level keys.
+func (s *TaxonomyService) CanonicalKeys(userID int, values []string) ([]string, error) {
+	if len(values) == 0 {
+		return values, nil
+	}
+	idx, err := s.load(userID)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var keys []string
+	for _, v := range values {
+		key := v // unknown values are kept as-is so old data can still be filtered
+		if c := idx.lookup(v); c != nil {
+			if p := idx.parent(c); p != nil {
+				c = p
+			}
+			key = c.Key
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Tree returns the categories visible to the user as a tree (with labels and synonyms).
+func (s *TaxonomyService) Tree(userID int) ([]models.Category, error) {
+	idx, err := s.load(userID)
+	if err != nil {
+		return nil, err
+	}
+	tree := make([]models.Category, 0, len(idx.roots))
+	for _, r := range idx.roots {
+		tree = append(tree, *r)
+	}
+	return tree, nil
+}
+
+// PromptContext lists the allowed category keys so the model picks canonical ones.
+func (s *TaxonomyService) PromptContext(userID int, _ string) string {
+	idx, err := s.load(userID)
+	if err != nil || len(idx.roots) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("ALLOWED CATEGORIES (data.category MUST be one of these keys, data.subcategory one of its children or \"\"; use \"other\" if nothing fits):\n")
+	for _, r := range idx.roots {
+		b.WriteString("- " + r.Key)
+		if l := label(r, "fa"); l != "" {
+			b.WriteString(" (" + l + ")")
+		}
+		if len(r.Children) > 0 {
+			children := make([]string, 0, len(r.Children))
+			for _, c := range r.Children {
+				children = append(children, c.Key)
+			}
+			sort.Strings(children)
+			b.WriteString(": " + strings.Join(children, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Label returns the display label of a category key, falling back to the key itself.
+func (s *TaxonomyService) Label(userID int, key, lang string) string {
+	idx, err := s.load(userID)
+	if err != nil {
+		return key
+	}
+	if c, ok := idx.byKey[key]; ok {
+		if l := label(c, lang); l != "" {
+			return l
+		}
+	}
+	return key
+}
+
+func label(c *models.Category, lang string) string {
+	for _, l := range c.Labels {
+		if l.Lang == lang {
+			return l.Label
+		}
+	}
+	return ""
+}
+
+type CustomCategoryInput struct {
+	Key       string
+	Label     string
+	Lang      string
+	ParentKey string
+	Synonyms  []string
+}
+
+// CreateCategory creates a custom category for ownerID, or a global one when ownerID is nil.
+func (s *TaxonomyService) CreateCategory(ownerID *int, in CustomCategoryInput) (*models.Category, error) {
+	key := in.Key
+	if key == "" {
+		key = utils.Slug(in.Label)
+	}
+	if key == "" {
+		return nil, errors.New("category key or label is required")
+	}
+	lang := in.Lang
+	if lang == "" {
+		lang = "fa"
+	}
+
+	scope := 0
+	if ownerID != nil {
+		scope = *ownerID
+	}
+	idx, err := s.load(scope)
+	if err != nil {
+		return nil, err
+	}
+	if existing, ok := idx.byKey[key]; ok && (ownerID == nil) == (existing.UserID == nil) {
+		return nil, fmt.Errorf("category %q already exists", key)
+	}
+
+	cat := models.Category{Key: key, UserID: ownerID}
+	if in.ParentKey != "" {
+		p, ok := idx.byKey[in.ParentKey]
+		if !ok {
+			return nil, ErrCategoryNotFound
+		}
+		if p.ParentID != nil {
+			return nil, errors.New("subcategories can't have children")
+		}
+		cat.ParentID = &p.ID
+	}
+	if in.Label != "" {
+		cat.Labels = []models.CategoryLabel{{Lang: lang, Label: in.Label}}
+	}
+	for _, syn := range in.Synonyms {
+		if n := utils.NormalizeText(syn); n != "" {
+			cat.Synonyms = append(cat.Synonyms, models.CategorySynonym{UserID: ownerID, Text: n})
+		}
+	}
+
+	if err := s.DB.Create(&cat).Error; err != nil {
+		return nil, err
+	}
+	s.invalidate(ownerID)
+	return &cat, nil
+}
+
+// AddSynonym adds a synonym for one user (or globally when userID is nil).
+func (s *TaxonomyService) AddSynonym(categoryID uint64, userID *int, text string) (*models.CategorySynonym, error) {
+	n := utils.NormalizeText(text)
+	if n == "" {
+		return nil, errors.New("synonym text is required")
+	}
+	var cat models.Category
+	if err := s.DB.First(&cat, categoryID).Error; err != nil {
+		return nil, ErrCategoryNotFound
+	}
+	if cat.UserID != nil && (userID == nil || *cat.UserID != *userID) {
+		return nil, ErrCategoryNotFound
+	}
+	syn := models.CategorySynonym{CategoryID: categoryID, UserID: userID, Text: n}
+	if err := s.DB.Create(&syn).Error; err != nil {
+		return nil, err
+	}
+	s.invalidate(userID)
+	return &syn, nil
+}
+
+// MergeCategories folds `fromKey` into `intoKey`: children, labels (as synonyms), synonyms
+// and purchase history move over, then `fromKey` is deleted.
+// With global=true (admin) a global category is merged and everyone's history is updated;
+// otherwise only the user's custom category and the user's purchases are touched.
+func (s *TaxonomyService) MergeCategories(userID int, fromKey, intoKey string, global bool) (int64, error) {
+	idx, err := s.load(userID)
+	if err != nil {
+		return 0, err
+	}
+	from, ok1 := idx.byKey[fromKey]
+	into, ok2 := idx.byKey[intoKey]
+	if !ok1 || !ok2 {
+		return 0, ErrCategoryNotFound
+	}
+	if from.ID == into.ID {
+		return 0, errors.New("can't merge a category into itself")
+	}
+	if !global && from.UserID == nil {
+		return 0, errors.New("only admins can merge global categories")
+	}
+	// a global merge rewrites everyone's history, so it only takes global categories
+	if global && (from.UserID != nil || into.UserID != nil) {
+		return 0, errors.New("global merges only take global categories")
+	}
+	if from.ParentID == nil && into.ParentID != nil && len(from.Children) > 0 {
+		return 0, errors.New("can't merge a category with subcategories into a subcategory")
+	}
+
+	// (category, subcategory) pairs before/after in the purchases table
+	fromCat, fromSub := from.Key, ""
+	if p := idx.parent(from); p != nil {
+		fromCat, fromSub = p.Key, from.Key
+	}
+	intoCat, intoSub := into.Key, ""
+	if p := idx.parent(into); p != nil {
+		intoCat, intoSub = p.Key, into.Key
+	}
+
+	var moved int64
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", from.ID).
+			Update("parent_id", into.ID).Error; err != nil {
+			return err
+		}
+		for _, l := range from.Labels {
+			syn := models.CategorySynonym{CategoryID: into.ID, UserID: from.UserID, Text: utils.NormalizeText(l.Label)}
+			if err := tx.Create(&syn).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&models.CategorySynonym{CategoryID: into.ID, UserID: from.UserID, Text: utils.NormalizeText(strings.ReplaceAll(from.Key, "-", " "))}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.CategorySynonym{}).Where("category_id = ?", from.ID).
+			Update("category_id", into.ID).Error; err != nil {
+			return err
+		}
+
+		q := tx.Model(&models.Purchase{}).Where("category = ?", fromCat)
+		if fromSub != "" {
+			q = q.Where("subcategory = ?", fromSub)
+		}
+		if !global {
+			q = q.Where("user_id = ?", userID)
+		}
+		updates := map[string]interface{}{"category": intoCat}
+		if fromSub != "" || intoSub != "" {
+			updates["subcategory"] = intoSub
+		}
+		res := q.Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		moved = res.RowsAffected
+		// receipt lines count under their own category, so they move too
+		iq := tx.Model(&models.PurchaseItem{}).Where("category = ?", fromCat)
+		if fromSub != "" {
+			iq = iq.Where("subcategory = ?", fromSub)
+		}
+		if !global {
+			iq = iq.Where("user_id = ?", userID)
+		}
+		if err := iq.Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("category_id = ?", from.ID).Delete(&models.CategoryLabel{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Category{}, from.ID).Error
+	})
+	s.invalidate(from.UserID)
+	return moved, err
+}
+
+// Recategorize rewrites history for the given users. With an explicit target it moves
+// (fromCategory[, fromSubcategory]) to (toCategory, toSubcategory); with an empty target
+// every stored category is re-resolved against the current taxonomy.
+func (s *TaxonomyService) Recategorize(userIDs []int, fromCategory, fromSubcategory, toCategory, toSubcategory string) (int64, error) {
+	if len(userIDs) == 0 {
+		return 0, errors.New("no users given")
+	}
+	var total int64
+
+	for _, uid := range userIDs {
+		idx, err := s.load(uid)
+		if err != nil {
+			return total, err
+		}
+
+		// pairs used by purchases or by receipt lines ("" = the purchase's category)
+		type pair struct{ Category, Subcategory string }
+		var pairs []pair
+		seen := map[pair]bool{}
+		for _, model := range []interface{}{&models.Purchase{}, &models.PurchaseItem{}} {
+			q := s.DB.Model(model).Where("user_id = ?", uid)
+			if _, isItem := model.(*models.PurchaseItem); isItem {
+				q = q.Where("category <> ''")
+			}
+			if fromCategory != "" {
+				q = q.Where("category = ?", fromCategory)
+			}
+			if fromSubcategory != "" {
+				q = q.Where("subcategory = ?", fromSubcategory)
+			}
+			var found []pair
+			if err := q.Distinct("category", "subcategory").Scan(&found).Error; err != nil {
+				return total, err
+			}
+			for _, p := range found {
+				if !seen[p] {
+					seen[p] = true
+					pairs = append(pairs, p)
+				}
+			}
+		}
+
+		for _, p := range pairs {
+			cat, sub := toCategory, toSubcategory
+			if cat == "" {
+				cat, sub = idx.resolve(p.Category, p.Subcategory)
+			} else {
+				cat, sub = idx.resolve(cat, sub)
+			}
+			if cat == p.Category && sub == p.Subcategory {
+				continue
+			}
+			updates := map[string]interface{}{"category": cat, "subcategory": sub}
+			err := s.DB.Transaction(func(tx *gorm.DB) error {
+				res := tx.Model(&models.Purchase{}).
+					Where("user_id = ? AND category = ? AND subcategory = ?", uid, p.Category, p.Subcategory).
+					Updates(updates)
+				if res.Error != nil {
+					return res.Error
+				}
+				total += res.RowsAffected
+				return tx.Model(&models.PurchaseItem{}).
+					Where("user_id = ? AND category = ? AND subcategory = ?", uid, p.Category, p.Subcategory).
+					Updates(updates).Error
+			})
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+
+	"gorm.io/gorm"
+)
+
+// VendorMatchThreshold is the minimum similarity for a fuzzy vendor match.
+const VendorMatchThreshold = 0.8
+
+type VendorService struct {
+	DB *gorm.DB
+}
+
+func NewVendorService(db *gorm.DB) *VendorService {
+	return &VendorService{DB: db}
+}
+
+var ErrVendorNotFound = errors.New("vendor not found")
+
+// visible = global vendors + the user's own, with aliases visible to the user
+func (s *VendorService) visible(userID int) ([]models.Vendor, error) {
+	var vendors []models.Vendor
+	err := s.DB.Preload("Aliases", "user_id IS NULL OR user_id = ?", userID).
+		Where("user_id IS NULL OR user_id = ?", userID).
+		Order("id").
+		Find(&vendors).Error
+	return vendors, err
+}
+
+// scoreVendor returns the best similarity between the raw name and the vendor's name/aliases.
+func scoreVendor(v *models.Vendor, normalized string) float64 {
+	best := 0.0
+	names := make([]string, 0, len(v.Aliases)+1)
+	names = append(names, utils.NormalizeText(v.Name))
+	for _, a := range v.Aliases {
+		names = append(names, a.Normalized)
+	}
+	for _, n := range names {
+		if n == normalized {
+			return 1
+		}
+		if sim := utils.Similarity(n, normalized); sim > best {
+			best = sim
+		}
+	}
+	return best
+}
+
+// Match finds the vendor for a free-text name (exact alias, edit distance). Other scripts
+// ("اسنپ" / "Snapp") are matched through aliases. Unknown names create a new vendor owned by
+// the user; fuzzy hits are remembered as a user alias so the next lookup is exact.
+func (s *VendorService) Match(userID int, raw string) (*models.Vendor, error) {
+	normalized := utils.NormalizeText(raw)
+	if normalized == "" {
+		return nil, errors.New("empty vendor name")
+	}
+
+	vendors, err := s.visible(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *models.Vendor
+	bestScore := 0.0
+	for i := range vendors {
+		score := scoreVendor(&vendors[i], normalized)
+		// prefer the user's own vendor on ties
+		if score > bestScore || (score == bestScore && score > 0 && vendors[i].UserID != nil) {
+			best, bestScore = &vendors[i], score
+		}
+	}
+
+	if best != nil && bestScore >= VendorMatchThreshold {
+		if bestScore < 1 {
+			alias := models.VendorAlias{VendorID: best.ID, UserID: &userID, Alias: strings.TrimSpace(raw), Normalized: normalized}
+			_ = s.DB.Create(&alias).Error // learning the alias is best-effort
+		}
+		return best, nil
+	}
+
+	v := models.Vendor{
+		Name:      strings.TrimSpace(raw),
+		UserID:    &userID,
+		CreatedAt: time.Now().UTC(),
+		Aliases:   []models.VendorAlias{{UserID: &userID, Alias: strings.TrimSpace(raw), Normalized: normalized}},
+	}
+	if err := s.DB.Create(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (s *VendorService) List(userID int) ([]models.Vendor, error) {
+	return s.visible(userID)
+}
+
+// get loads a vendor the user is allowed to modify (own vendor, or any when admin).
+func (s *VendorService) get(userID int, id uint64, admin bool) (*models.Vendor, error) {
+	var v models.Vendor
+	if err := s.DB.First(&v, id).Error; err != nil {
+		return nil, ErrVendorNotFound
+	}
+	if !admin && (v.UserID == nil || *v.UserID != userID) {
+		return nil, ErrVendorNotFound
+	}
+	return &v, nil
+}
+
+func (s *VendorService) AddAlias(userID int, id uint64, alias string, admin bool) (*models.VendorAlias, error) {
+	n := utils.NormalizeText(alias)
+	if n == "" {
+		return nil, errors.New("alias is required")
+	}
+	var v models.Vendor
+	if err := s.DB.First(&v, id).Error; err != nil {
+		return nil, ErrVendorNotFound
+	}
+	if v.UserID != nil && *v.UserID != userID && !admin {
+		return nil, ErrVendorNotFound
+	}
+	a := models.VendorAlias{VendorID: id, Alias: alias, Normalized: n}
+	if !admin {
+		a.UserID = &userID
+	}
+	if err := s.DB.Create(&a).Error; err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// SetDefaultCategory sets the category used when the model can't categorize a purchase.
+func (s *VendorService) SetDefaultCategory(userID int, id uint64, category, subcategory string, admin bool) (*models.Vendor, error) {
+	v, err := s.get(userID, id, admin)
+	if err != nil {
+		return nil, err
+	}
+	v.DefaultCategory, v.DefaultSubcategory = category, subcategory
+	if err := s.DB.Model(v).Updates(map[string]interface{}{
+		"default_category":    category,
+		"default_subcategory": subcategory,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Merge folds vendor `fromID` into `intoID`: aliases and purchases move, the old name
+// becomes an alias, then the old vendor is deleted.
+func (s *VendorService) Merge(userID int, fromID, intoID uint64, admin bool) (int64, error) {
+	if fromID == intoID {
+		return 0, errors.New("can't merge a vendor into itself")
+	}
+	from, err := s.get(userID, fromID, admin)
+	if err != nil {
+		return 0, err
+	}
+	var into models.Vendor
+	if err := s.DB.First(&into, intoID).Error; err != nil {
+		return 0, ErrVendorNotFound
+	}
+	if into.UserID != nil && *into.UserID != userID && !admin {
+		return 0, ErrVendorNotFound
+	}
+
+	var moved int64
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.VendorAlias{}).Where("vendor_id = ?", from.ID).
+			Update("vendor_id", into.ID).Error; err != nil {
+			return err
+		}
+		alias := models.VendorAlias{VendorID: into.ID, UserID: from.UserID, Alias: from.Name, Normalized: utils.NormalizeText(from.Name)}
+		if err := tx.Create(&alias).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&models.Purchase{}).Where("vendor_id = ?", from.ID).
+			Updates(map[string]interface{}{"vendor_id": into.ID, "vendor": into.Name})
+		if res.Error != nil {
+			return res.Error
+		}
+		moved = res.RowsAffected
+		return tx.Delete(&models.Vendor{}, from.ID).Error
+	})
+	return moved, err
+}
+
+type VendorReportRow struct {
+	VendorID      uint64       `json:"vendor_id"`
+	Vendor        string       `json:"vendor"`
+	Total         models.Money `json:"total"`
+	PurchaseCount int64        `json:"purchase_count"`
+	LastPurchase  *time.Time   `json:"last_purchase"`
+}
+
+// Report aggregates spending per vendor (and currency) for the filter.
+func (s *VendorService) Report(filter models.PurchaseFilter) ([]VendorReportRow, error) {
+	type row struct {
+		VendorID uint64
+		Vendor   string
+		Currency string
+		Total    int64
+		Cnt      int64
+		Last     *time.Time
+	}
+	var rows []row
+	db := applyFilter(s.DB.Model(&models.Purchase{}), filter).
+		Joins("JOIN vendors ON vendors.id = purchases.vendor_id").
+		Select("purchases.vendor_id, vendors.name AS vendor, purchases.amount_currency AS currency, " +
+			"SUM(purchases.amount_minor - purchases.refunded_minor) AS total, COUNT(*) AS cnt, MAX(purchases.purchase_time) AS last").
+		Group("purchases.vendor_id, vendors.name, purchases.amount_currency").
+		Order("total DESC")
+	if err := db.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]VendorReportRow, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, VendorReportRow{
+			VendorID:      r.VendorID,
+			Vendor:        r.Vendor,
+			Total:         models.NewMoney(r.Total, r.Currency),
+			PurchaseCount: r.Cnt,
+			LastPurchase:  r.Last,
+		})
+	}
+	return out, nil
+}
+
+// BackfillPurchases links old purchases (free-text vendor, no vendor_id) to vendors.
+func (s *VendorService) BackfillPurchases() (int, error) {
+	var rows []models.Purchase
+	if err := s.DB.Select("id, user_id, vendor").
+		Where("vendor_id IS NULL AND vendor IS NOT NULL AND vendor <> ''").
+		Find(&rows).Error; err != nil {
+		return 0, err
+	}
+	linked := 0
+	for _, p := range rows {
+		v, err := s.Match(p.UserID, *p.Vendor)
+		if err != nil {
+			continue
+		}
+		if err := s.DB.Model(&models.Purchase{}).Where("id = ?", p.ID).
+			Updates(map[string]interface{}{"vendor_id": v.ID, "vendor": v.Name}).Error; err != nil {
+			return linked, err
+		}
+		linked++
+	}
+	return linked, nil
+}
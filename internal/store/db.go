@@ -37,10 +37,43 @@ func Connect(dsn, dbName string) error {
 	}
 
 	// automigrate
-	if err := db.AutoMigrate(&models.User{}, &models.Purchase{}); err != nil {
+	if err := db.AutoMigrate(
+		&models.User{},
+		&models.Purchase{},
+		&models.Category{},
+		&models.CategoryLabel{},
+		&models.CategorySynonym{},
+		&models.Vendor{},
+		&models.VendorAlias{},
+		&models.Correction{},
+		&models.UserRule{},
+		&models.PurchaseDraft{},
+		&models.Budget{},
+		&models.RecurringSeries{},
+		&models.ScheduledPurchase{},
+		&models.ScheduledOccurrence{},
+		&models.AnomalyFlag{},
+		&models.ImportJob{},
+		&models.ImportRow{},
+		&models.BankTransaction{},
+		&models.Income{},
+		&models.Account{},
+		&models.Transfer{},
+		&models.Reconciliation{},
+		&models.StatementLine{},
+		&models.Refund{},
+		&models.InstallmentPlan{},
+		&models.InstallmentDue{},
+		&models.PurchaseItem{},
+	); err != nil {
 		return fmt.Errorf("auto migrate failed: %w", err)
 	}
 
+	// data migrations (e.g. legacy float amounts -> minor units)
+	if err := runMigrations(db); err != nil {
+		return err
+	}
+
 	DB = db
 
 	// Seed admin user if not exists
@@ -61,5 +94,13 @@ func Connect(dsn, dbName string) error {
 		fmt.Println("Admin user already exists")
 	}
 
+	// Seed the default category taxonomy
+	if err := seedCategories(DB); err != nil {
+		return fmt.Errorf("failed to seed categories: %w", err)
+	}
+	if err := seedVendors(DB); err != nil {
+		return fmt.Errorf("failed to seed vendors: %w", err)
+	}
+
 	return nil
 }
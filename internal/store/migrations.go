@@ -0,0 +1,164 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"example/AI/internal/models"
+)
+
+// schemaMigration records data migrations that already ran (AutoMigrate only handles the schema).
+type schemaMigration struct {
+	ID        string `gorm:"primaryKey;size:100"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+type migration struct {
+	ID string
+	Up func(tx *gorm.DB) error
+}
+
+// migrations run in order, each one inside its own transaction, exactly once.
+var migrations = []migration{
+	{ID: "0001_purchase_amount_minor_units", Up: migratePurchaseAmountToMinor},
+	{ID: "0002_purchase_item_unit_price_columns", Up: migrateItemUnitPriceColumns},
+	{ID: "0003_scanned_duplicates_to_review", Up: migrateScannedDuplicatesToReview},
+}
+
+func runMigrations(db *gorm.DB) error {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		var count int64
+		if err := db.Model(&schemaMigration{}).Where("id = ?", m.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{ID: m.ID, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.ID, err)
+		}
+		fmt.Println("migration applied:", m.ID)
+	}
+	return nil
+}
+
+// migratePurchaseAmountToMinor moves the legacy float `amount` / free-text `currency`
+// columns into amount_minor / amount_currency. The float is read back into Go as the
+// exact float64 that was written, then rounded to the nearest minor unit of its currency.
+func migratePurchaseAmountToMinor(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if !m.HasColumn("purchases", "amount") {
+		return nil // fresh database, nothing to convert
+	}
+	hasCurrency := m.HasColumn("purchases", "currency")
+
+	type legacyRow struct {
+		ID       uint64
+		Amount   *float64
+		Currency *string
+	}
+
+	cols := "id, amount"
+	if hasCurrency {
+		cols += ", currency"
+	}
+
+	var rows []legacyRow
+	err := tx.Table("purchases").Select(cols).FindInBatches(&rows, 500, func(batch *gorm.DB, _ int) error {
+		for _, r := range rows {
+			cur := ""
+			if r.Currency != nil {
+				cur = *r.Currency
+			}
+			// legacy currencies were free text from the model; one odd value must not stop startup
+			if _, err := models.ParseCurrency(cur); err != nil {
+				fmt.Printf("migration: purchase %d: unknown currency %q, using %s\n", r.ID, cur, models.DefaultCurrency)
+				cur = models.DefaultCurrency
+			}
+			amount := models.NewMoney(0, cur)
+			if r.Amount != nil {
+				var err error
+				if amount, err = models.MoneyFromFloat(*r.Amount, cur); err != nil {
+					return fmt.Errorf("purchase %d: %w", r.ID, err)
+				}
+			}
+			if err := tx.Table("purchases").Where("id = ?", r.ID).Updates(map[string]interface{}{
+				"amount_minor":    amount.Minor,
+				"amount_currency": amount.Currency,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	if err := m.DropColumn("purchases", "amount"); err != nil {
+		return err
+	}
+	if hasCurrency {
+		return m.DropColumn("purchases", "currency")
+	}
+	return nil
+}
+
+// migrateItemUnitPriceColumns moves purchase_items.unit_minor / unit_currency (read like a
+// unit of measure) into unit_price_minor / unit_price_currency, created by AutoMigrate.
+func migrateItemUnitPriceColumns(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if !m.HasColumn("purchase_items", "unit_minor") {
+		return nil
+	}
+	if err := tx.Exec("UPDATE purchase_items SET unit_price_minor = unit_minor, unit_price_currency = unit_currency").Error; err != nil {
+		return err
+	}
+	// SQL Server keeps a default constraint on the old columns; drop it with the column
+	for _, col := range []string{"unit_minor", "unit_currency"} {
+		if err := dropColumnWithDefault(tx, "purchase_items", col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropColumnWithDefault drops a column and the default constraint SQL Server created for it.
+func dropColumnWithDefault(tx *gorm.DB, table, column string) error {
+	var constraint string
+	err := tx.Raw(`SELECT dc.name FROM sys.default_constraints dc
+		JOIN sys.columns c ON c.object_id = dc.parent_object_id AND c.column_id = dc.parent_column_id
+		WHERE dc.parent_object_id = OBJECT_ID(?) AND c.name = ?`, table, column).Scan(&constraint).Error
+	if err != nil {
+		return err
+	}
+	if constraint != "" {
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT [%s]", table, constraint)).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Migrator().DropColumn(table, column)
+}
+
+// migrateScannedDuplicatesToReview moves duplicates flagged by an earlier scan, which stayed
+// confirmed and so counted twice, to review like the ones flagged when added.
+func migrateScannedDuplicatesToReview(tx *gorm.DB) error {
+	return tx.Model(&models.Purchase{}).
+		Where("duplicate_of IS NOT NULL AND duplicate_cleared = ? AND status = ?", false, models.StatusConfirmed).
+		Update("status", models.StatusGuessed).Error
+}
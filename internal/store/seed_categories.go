@@ -0,0 +1,111 @@
+package store
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+)
+
+type seedCategory struct {
+	key      string
+	fa, en   string
+	synonyms []string
+	children []seedCategory
+}
+
+// defaultTaxonomy = درخت پیش‌فرض دسته‌بندی‌ها (فقط اگر هیچ دسته‌ی سراسری وجود نداشته باشد ساخته می‌شود)
+var defaultTaxonomy = []seedCategory{
+	{key: "food", fa: "خوراک", en: "Food", synonyms: []string{"غذا", "خوراکی", "خوراکیها", "خوردنی", "groceries and food"}, children: []seedCategory{
+		{key: "groceries", fa: "خواربار", en: "Groceries", synonyms: []string{"سوپرمارکت", "بقالی", "میوه", "هایپر", "supermarket"}},
+		{key: "restaurant", fa: "رستوران", en: "Restaurant", synonyms: []string{"ناهار", "شام", "dining"}},
+		{key: "fast-food", fa: "فست فود", en: "Fast food", synonyms: []string{"پیتزا", "ساندویچ", "برگر"}},
+		{key: "cafe", fa: "کافه", en: "Cafe", synonyms: []string{"قهوه", "coffee", "کافی شاپ"}},
+		{key: "bakery", fa: "نانوایی", en: "Bakery", synonyms: []string{"نان", "bread", "شیرینی"}},
+	}},
+	{key: "transport", fa: "حمل و نقل", en: "Transport", synonyms: []string{"رفت و آمد", "ایاب و ذهاب", "transportation"}, children: []seedCategory{
+		{key: "taxi", fa: "تاکسی", en: "Taxi", synonyms: []string{"اسنپ", "تپسی", "snapp", "tapsi", "ride"}},
+		{key: "fuel", fa: "سوخت", en: "Fuel", synonyms: []string{"بنزین", "گازوئیل", "gas", "petrol"}},
+		{key: "public-transport", fa: "حمل و نقل عمومی", en: "Public transport", synonyms: []string{"مترو", "اتوبوس", "bus", "metro"}},
+		{key: "parking", fa: "پارکینگ", en: "Parking"},
+		{key: "car-maintenance", fa: "تعمیر خودرو", en: "Car maintenance", synonyms: []string{"مکانیک", "تعویض روغن", "کارواش"}},
+	}},
+	{key: "housing", fa: "مسکن", en: "Housing", synonyms: []string{"خانه", "home"}, children: []seedCategory{
+		{key: "rent", fa: "اجاره", en: "Rent", synonyms: []string{"اجاره خانه", "کرایه خانه"}},
+		{key: "home-maintenance", fa: "تعمیرات منزل", en: "Home maintenance", synonyms: []string{"لوله کش", "برقکار"}},
+		{key: "furniture", fa: "لوازم منزل", en: "Furniture", synonyms: []string{"مبل", "لوازم خانگی"}},
+	}},
+	{key: "bills", fa: "قبوض", en: "Bills", synonyms: []string{"قبض", "utilities"}, children: []seedCategory{
+		{key: "utilities", fa: "آب و برق و گاز", en: "Utilities", synonyms: []string{"قبض برق", "قبض آب", "قبض گاز", "electricity", "water"}},
+		{key: "internet", fa: "اینترنت", en: "Internet", synonyms: []string{"بسته اینترنت", "adsl"}},
+		{key: "phone", fa: "تلفن همراه", en: "Phone", synonyms: []string{"شارژ", "شارژ موبایل", "قبض موبایل", "mobile top up"}},
+	}},
+	{key: "health", fa: "سلامت", en: "Health", synonyms: []string{"پزشکی", "درمان", "medical"}, children: []seedCategory{
+		{key: "pharmacy", fa: "داروخانه", en: "Pharmacy", synonyms: []string{"دارو", "medicine"}},
+		{key: "doctor", fa: "پزشک", en: "Doctor", synonyms: []string{"دکتر", "ویزیت", "دندانپزشک"}},
+		{key: "insurance", fa: "بیمه", en: "Insurance"},
+	}},
+	{key: "shopping", fa: "خرید", en: "Shopping", synonyms: []string{"خرید شخصی"}, children: []seedCategory{
+		{key: "clothing", fa: "پوشاک", en: "Clothing", synonyms: []string{"لباس", "کفش", "clothes", "shoes"}},
+		{key: "electronics", fa: "لوازم الکترونیکی", en: "Electronics", synonyms: []string{"موبایل", "گوشی", "لپ تاپ", "laptop"}},
+		{key: "personal-care", fa: "بهداشتی و آرایشی", en: "Personal care", synonyms: []string{"آرایشگاه", "لوازم آرایشی", "شوینده"}},
+	}},
+	{key: "entertainment", fa: "تفریح", en: "Entertainment", synonyms: []string{"سرگرمی", "fun"}, children: []seedCategory{
+		{key: "subscriptions", fa: "اشتراک", en: "Subscriptions", synonyms: []string{"نتفلیکس", "فیلیمو", "نماوا", "spotify", "subscription"}},
+		{key: "movies", fa: "سینما", en: "Movies", synonyms: []string{"فیلم", "cinema"}},
+		{key: "games", fa: "بازی", en: "Games", synonyms: []string{"گیم"}},
+		{key: "travel", fa: "سفر", en: "Travel", synonyms: []string{"هتل", "بلیط", "مسافرت", "hotel"}},
+	}},
+	{key: "education", fa: "آموزش", en: "Education", synonyms: []string{"تحصیل"}, children: []seedCategory{
+		{key: "courses", fa: "دوره آموزشی", en: "Courses", synonyms: []string{"کلاس", "شهریه", "course"}},
+		{key: "books", fa: "کتاب", en: "Books", synonyms: []string{"book"}},
+	}},
+	{key: "gifts", fa: "هدیه", en: "Gifts", synonyms: []string{"کادو", "gift", "خیریه"}},
+	{key: models.OtherCategory, fa: "سایر", en: "Other", synonyms: []string{"متفرقه", "misc", "unknown"}},
+}
+
+func seedCategories(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.Category{}).Where("user_id IS NULL").Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var create func(sc seedCategory, parentID *uint64) error
+		create = func(sc seedCategory, parentID *uint64) error {
+			cat := models.Category{
+				Key:      sc.key,
+				ParentID: parentID,
+				Labels: []models.CategoryLabel{
+					{Lang: "fa", Label: sc.fa},
+					{Lang: "en", Label: sc.en},
+				},
+			}
+			for _, s := range sc.synonyms {
+				cat.Synonyms = append(cat.Synonyms, models.CategorySynonym{Text: utils.NormalizeText(s)})
+			}
+			if err := tx.Create(&cat).Error; err != nil {
+				return fmt.Errorf("seed category %s: %w", sc.key, err)
+			}
+			for _, child := range sc.children {
+				if err := create(child, &cat.ID); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		for _, sc := range defaultTaxonomy {
+			if err := create(sc, nil); err != nil {
+				return err
+			}
+		}
+		fmt.Println("Default category taxonomy created")
+		return nil
+	})
+}
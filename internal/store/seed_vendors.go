@@ -0,0 +1,58 @@
+package store
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"example/AI/internal/models"
+	"example/AI/internal/utils"
+)
+
+type seedVendor struct {
+	name          string
+	category, sub string
+	aliases       []string
+}
+
+// defaultVendors = فروشنده‌های پرتکرار، با دسته‌ی پیش‌فرض
+var defaultVendors = []seedVendor{
+	{name: "Snapp", category: "transport", sub: "taxi", aliases: []string{"اسنپ", "snap"}},
+	{name: "Tapsi", category: "transport", sub: "taxi", aliases: []string{"تپسی", "tap30"}},
+	{name: "SnappFood", category: "food", sub: "restaurant", aliases: []string{"اسنپ فود", "snapp food"}},
+	{name: "Digikala", category: "shopping", aliases: []string{"دیجی کالا", "دیجیکالا"}},
+	{name: "Okala", category: "food", sub: "groceries", aliases: []string{"اکالا"}},
+	{name: "Snapp Market", category: "food", sub: "groceries", aliases: []string{"اسنپ مارکت"}},
+	{name: "Refah", category: "food", sub: "groceries", aliases: []string{"فروشگاه رفاه", "رفاه"}},
+	{name: "Hyperstar", category: "food", sub: "groceries", aliases: []string{"هایپراستار", "هایپر استار"}},
+	{name: "Irancell", category: "bills", sub: "phone", aliases: []string{"ایرانسل"}},
+	{name: "Hamrah-e Aval", category: "bills", sub: "phone", aliases: []string{"همراه اول", "mci"}},
+	{name: "Filimo", category: "entertainment", sub: "subscriptions", aliases: []string{"فیلیمو"}},
+	{name: "Namava", category: "entertainment", sub: "subscriptions", aliases: []string{"نماوا"}},
+	{name: "Netflix", category: "entertainment", sub: "subscriptions", aliases: []string{"نتفلیکس"}},
+	{name: "Spotify", category: "entertainment", sub: "subscriptions", aliases: []string{"اسپاتیفای"}},
+}
+
+func seedVendors(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.Vendor{}).Where("user_id IS NULL").Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, sv := range defaultVendors {
+			v := models.Vendor{Name: sv.name, DefaultCategory: sv.category, DefaultSubcategory: sv.sub}
+			for _, a := range append([]string{sv.name}, sv.aliases...) {
+				v.Aliases = append(v.Aliases, models.VendorAlias{Alias: a, Normalized: utils.NormalizeText(a)})
+			}
+			if err := tx.Create(&v).Error; err != nil {
+				return fmt.Errorf("seed vendor %s: %w", sv.name, err)
+			}
+		}
+		fmt.Println("Default vendors created")
+		return nil
+	})
+}
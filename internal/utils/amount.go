@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+var amountRe = regexp.MustCompile(`(\d[\d,]*(?:\.\d+)?)(?:\s*(هزار|میلیون|ملیون|میلیارد)|\s?([kmb])\b)?`)
+
+var amountMultipliers = map[string]int64{
+	"هزار":    1_000,
+	"k":       1_000,
+	"میلیون":  1_000_000,
+	"ملیون":   1_000_000,
+	"m":       1_000_000,
+	"میلیارد": 1_000_000_000,
+	"b":       1_000_000_000,
+}
+
+var currencyWords = map[string]string{
+	"تومان": "IRT",
+	"تومن":  "IRT",
+	"toman": "IRT",
+	"ریال":  "IRR",
+	"rial":  "IRR",
+	"دلار":  "USD",
+	"usd":   "USD",
+	"یورو":  "EUR",
+	"eur":   "EUR",
+}
+
+// ParseSpokenAmount extracts the first amount from a short answer like "۲۵۰ هزار تومن"
+// or "1.5m". It returns the exact decimal value as a string and the currency code it
+// mentions ("" if none).
+func ParseSpokenAmount(s string) (value, currency string, ok bool) {
+	s = strings.ToLower(NormalizeDigits(s))
+
+	m := amountRe.FindStringSubmatch(s)
+	if m == nil {
+		return "", "", false
+	}
+	r, ok := new(big.Rat).SetString(strings.ReplaceAll(m[1], ",", ""))
+	if !ok {
+		return "", "", false
+	}
+	if mult, found := amountMultipliers[m[2]+m[3]]; found {
+		r.Mul(r, new(big.Rat).SetInt64(mult))
+	}
+
+	for _, w := range strings.Fields(s) {
+		if code, found := currencyWords[w]; found {
+			currency = code
+			break
+		}
+	}
+
+	if r.IsInt() {
+		return r.Num().String(), currency, true
+	}
+	return strings.TrimRight(strings.TrimRight(r.FloatString(6), "0"), "."), currency, true
+}
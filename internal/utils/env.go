@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// EnvFloat reads a float env var, falling back to def when unset or invalid.
+func EnvFloat(key string, def float64) float64 {
+	raw, ok := os.LookupEnv(key)
+	if !ok || raw == "" {
+		return def
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Printf("invalid %s=%q, using %v", key, raw, def)
+		return def
+	}
+	return v
+}
+
+// EnvDuration reads a duration env var ("30m", "2h"), falling back to def.
+func EnvDuration(key string, def time.Duration) time.Duration {
+	raw, ok := os.LookupEnv(key)
+	if !ok || raw == "" {
+		return def
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Printf("invalid %s=%q, using %v", key, raw, def)
+		return def
+	}
+	return v
+}
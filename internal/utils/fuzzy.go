@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Levenshtein returns the edit distance between a and b (rune based, so Persian works).
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 {
+		return len(rb)
+	}
+	if len(rb) == 0 {
+		return len(ra)
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// Similarity is 1 - normalized edit distance of the normalized, space-less strings (0..1).
+func Similarity(a, b string) float64 {
+	a = strings.ReplaceAll(NormalizeText(a), " ", "")
+	b = strings.ReplaceAll(NormalizeText(b), " ", "")
+	if a == "" || b == "" {
+		return 0
+	}
+	maxLen := max(utf8.RuneCountInString(a), utf8.RuneCountInString(b))
+	return 1 - float64(Levenshtein(a, b))/float64(maxLen)
+}
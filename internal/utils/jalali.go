@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Jalali (Solar Hijri) <-> Gregorian conversion, based on the jalaali-js algorithm
+// (Borkowski), valid for Jalali years -61 .. 3177.
+
+var jalaliBreaks = []int{-61, 9, 38, 199, 426, 686, 756, 818, 1111, 1181, 1210,
+	1635, 2060, 2097, 2192, 2262, 2324, 2394, 2456, 3178}
+
+// jalCal returns the Gregorian year of Farvardin 1st, the March day it falls on, and
+// the number of years since the last leap year (0 = leap).
+func jalCal(jy int) (gy, march, leap int, err error) {
+	bl := len(jalaliBreaks)
+	if jy < jalaliBreaks[0] || jy >= jalaliBreaks[bl-1] {
+		return 0, 0, 0, fmt.Errorf("invalid jalali year %d", jy)
+	}
+	gy = jy + 621
+	leapJ := -14
+	jp := jalaliBreaks[0]
+	jump := 0
+	for i := 1; i < bl; i++ {
+		jm := jalaliBreaks[i]
+		jump = jm - jp
+		if jy < jm {
+			break
+		}
+		leapJ += jump/33*8 + (jump%33)/4
+		jp = jm
+	}
+	n := jy - jp
+	leapJ += n/33*8 + (n%33+3)/4
+	if jump%33 == 4 && jump-n == 4 {
+		leapJ++
+	}
+	leapG := gy/4 - (gy/100+1)*3/4 - 150
+	march = 20 + leapJ - leapG
+
+	if jump-n < 6 {
+		n = n - jump + (jump+4)/33*33
+	}
+	leap = ((n+1)%33 - 1) % 4
+	if leap == -1 {
+		leap = 4
+	}
+	return gy, march, leap, nil
+}
+
+// g2d converts a Gregorian date to a Julian day number.
+func g2d(gy, gm, gd int) int {
+	d := (gy+(gm-8)/6+100100)*1461/4 + (153*((gm+9)%12)+2)/5 + gd - 34840408
+	return d - (gy+100100+(gm-8)/6)/100*3/4 + 752
+}
+
+// d2g converts a Julian day number to a Gregorian date.
+func d2g(jdn int) (gy, gm, gd int) {
+	j := 4*jdn + 139361631
+	j = j + (4*jdn+183187720)/146097*3/4*4 - 3908
+	i := (j%1461)/4*5 + 308
+	gd = (i%153)/5 + 1
+	gm = (i/153)%12 + 1
+	gy = j/1461 - 100100 + (8-gm)/6
+	return
+}
+
+func j2d(jy, jm, jd int) (int, error) {
+	gy, march, _, err := jalCal(jy)
+	if err != nil {
+		return 0, err
+	}
+	return g2d(gy, 3, march) + (jm-1)*31 - jm/7*(jm-7) + jd - 1, nil
+}
+
+func d2j(jdn int) (jy, jm, jd int) {
+	gy, _, _ := d2g(jdn)
+	jy = gy - 621
+	_, march, leap, _ := jalCal(jy)
+	k := jdn - g2d(gy, 3, march)
+	if k >= 0 {
+		if k <= 185 {
+			return jy, 1 + k/31, k%31 + 1
+		}
+		k -= 186
+	} else {
+		jy--
+		k += 179
+		if leap == 1 {
+			k++
+		}
+	}
+	return jy, 7 + k/30, k%30 + 1
+}
+
+// IsJalaliLeap reports whether a Jalali year has 30 days in Esfand.
+func IsJalaliLeap(jy int) bool {
+	_, _, leap, err := jalCal(jy)
+	return err == nil && leap == 0
+}
+
+// JalaliMonthLength returns the number of days of a Jalali month (1..12).
+func JalaliMonthLength(jy, jm int) int {
+	switch {
+	case jm <= 6:
+		return 31
+	case jm <= 11:
+		return 30
+	case IsJalaliLeap(jy):
+		return 30
+	default:
+		return 29
+	}
+}
+
+// ToJalali returns the Jalali date of t (in t's location).
+func ToJalali(t time.Time) (jy, jm, jd int) {
+	return d2j(g2d(t.Year(), int(t.Month()), t.Day()))
+}
+
+// FromJalali returns midnight of a Jalali date in loc.
+func FromJalali(jy, jm, jd int, loc *time.Location) (time.Time, error) {
+	if jm < 1 || jm > 12 || jd < 1 || jd > JalaliMonthLength(jy, jm) {
+		return time.Time{}, fmt.Errorf("invalid jalali date %d/%d/%d", jy, jm, jd)
+	}
+	jdn, err := j2d(jy, jm, jd)
+	if err != nil {
+		return time.Time{}, err
+	}
+	gy, gm, gd := d2g(jdn)
+	return time.Date(gy, time.Month(gm), gd, 0, 0, 0, 0, loc), nil
+}
+
+// AddJalaliMonths moves a Jalali (year, month) by n months.
+func AddJalaliMonths(jy, jm, n int) (int, int) {
+	idx := jy*12 + (jm - 1) + n
+	return idx / 12, idx%12 + 1
+}
+
+var jalaliMonthNames = map[string]int{
+	"فروردین": 1, "اردیبهشت": 2, "خرداد": 3, "تیر": 4, "مرداد": 5, "امرداد": 5, "شهریور": 6,
+	"مهر": 7, "آبان": 8, "ابان": 8, "آذر": 9, "اذر": 9, "دی": 10, "بهمن": 11, "اسفند": 12,
+}
+
+// ParseDate reads "YYYY-MM-DD", "YYYY/MM/DD", "DD/MM/YYYY" and "12 فروردین 1403" dates
+// (Persian digits allowed; a time part after the date is ignored). Years before 1700 are
+// taken as Jalali ("1405/08/01"). The result is midnight UTC.
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(NormalizeDigits(s))
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if fields := strings.Fields(s); len(fields) == 3 {
+		if jm, ok := jalaliMonthNames[fields[1]]; ok {
+			jd, err1 := strconv.Atoi(fields[0])
+			jy, err2 := strconv.Atoi(fields[2])
+			if err1 == nil && err2 == nil {
+				return FromJalali(jy, jm, jd, time.UTC)
+			}
+		}
+	}
+	if i := strings.IndexAny(s, " T"); i > 0 {
+		s = s[:i] // "2025-01-02 14:30"
+	}
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '/' || r == '.' })
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", s)
+		}
+		nums[i] = n
+	}
+	y, m, d := nums[0], nums[1], nums[2]
+	if len(parts[2]) == 4 && len(parts[0]) <= 2 {
+		y, d = nums[2], nums[0] // DD/MM/YYYY
+	}
+	if y < 1700 {
+		return FromJalali(y, m, d, time.UTC)
+	}
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if t.Month() != time.Month(m) || t.Day() != d {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	PeriodDaily         = "daily"
+	PeriodWeekly        = "weekly" // weeks start on Saturday (شنبه)
+	PeriodMonthly       = "monthly"
+	PeriodJalaliMonthly = "jalali_monthly"
+	PeriodYearly        = "yearly"
+)
+
+func ValidPeriod(p string) bool {
+	switch p {
+	case PeriodDaily, PeriodWeekly, PeriodMonthly, PeriodJalaliMonthly, PeriodYearly:
+		return true
+	}
+	return false
+}
+
+// PeriodBounds returns the [start, end) of the period containing t, in t's location.
+func PeriodBounds(period string, t time.Time) (time.Time, time.Time, error) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch period {
+	case PeriodDaily:
+		return day, day.AddDate(0, 0, 1), nil
+	case PeriodWeekly:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 1) % 7))
+		return start, start.AddDate(0, 0, 7), nil
+	case PeriodMonthly:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(0, 1, 0), nil
+	case PeriodJalaliMonthly:
+		jy, jm, _ := ToJalali(day)
+		start, err := FromJalali(jy, jm, 1, t.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		ny, nm := AddJalaliMonths(jy, jm, 1)
+		end, err := FromJalali(ny, nm, 1, t.Location())
+		return start, end, err
+	case PeriodYearly:
+		start := time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+		return start, start.AddDate(1, 0, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown period %q", period)
+}
+
+// PreviousPeriod returns the period right before the one starting at start.
+func PreviousPeriod(period string, start time.Time) (time.Time, time.Time, error) {
+	return PeriodBounds(period, start.Add(-time.Nanosecond))
+}
+
+// PeriodLabel is a short human label of the period containing t ("2025-03", "1404/01", ...).
+func PeriodLabel(period string, t time.Time) string {
+	switch period {
+	case PeriodDaily:
+		return t.Format("2006-01-02")
+	case PeriodWeekly:
+		start, _, _ := PeriodBounds(period, t)
+		return start.Format("2006-01-02")
+	case PeriodJalaliMonthly:
+		jy, jm, _ := ToJalali(t)
+		return fmt.Sprintf("%04d/%02d", jy, jm)
+	case PeriodYearly:
+		return t.Format("2006")
+	}
+	return t.Format("2006-01")
+}
@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"encoding/json"
 	"example/AI/internal/models"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,6 +15,10 @@ func ConvertAIFiltersToPurchaseFilter(aiFilters map[string]interface{}, request
 	if uIds, ok := request["target_users"].([]interface{}); ok {
 		for _, id := range uIds {
 			switch v := id.(type) {
+			case json.Number:
+				if i, err := v.Int64(); err == nil {
+					pf.UserIDs = append(pf.UserIDs, int(i))
+				}
 			case float64:
 				pf.UserIDs = append(pf.UserIDs, int(v))
 			case int:
@@ -60,35 +66,38 @@ func ConvertAIFiltersToPurchaseFilter(aiFilters map[string]interface{}, request
 		pf.ToDate = td
 	}
 
-	// min/max amount
-	parseFloat := func(v interface{}) *float64 {
-		var f float64
+	if inc, ok := aiFilters["include_unconfirmed"].(bool); ok {
+		pf.IncludeUnconfirmed = inc
+	}
+	if basis, ok := aiFilters["basis"].(string); ok && strings.EqualFold(basis, models.BasisCash) {
+		pf.Basis = models.BasisCash
+	}
+
+	// min/max amount (exact, in the filter currency)
+	currency, _ := aiFilters["currency"].(string)
+	parseAmount := func(v interface{}) *models.Money {
+		var raw string
 		switch val := v.(type) {
+		case json.Number:
+			raw = val.String()
 		case float64:
-			f = val
-		case float32:
-			f = float64(val)
+			raw = strconv.FormatFloat(val, 'f', -1, 64)
 		case int:
-			f = float64(val)
-		case int64:
-			f = float64(val)
+			raw = strconv.Itoa(val)
 		case string:
-			if v, err := strconv.ParseFloat(val, 64); err == nil {
-				f = v
-			} else {
-				return nil
-			}
+			raw = val
 		default:
 			return nil
 		}
-		if f == 0 {
+		m, err := models.ParseMoney(raw, currency)
+		if err != nil || m.IsZero() {
 			return nil // اگر 0 بود، نادیده گرفته بشه
 		}
-		return &f
+		return &m
 	}
 
-	pf.MinAmount = parseFloat(aiFilters["min_amount"])
-	pf.MaxAmount = parseFloat(aiFilters["max_amount"])
+	pf.MinAmount = parseAmount(aiFilters["min_amount"])
+	pf.MaxAmount = parseAmount(aiFilters["max_amount"])
 
 	return pf
 }
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"math"
+	"sort"
+)
+
+// Median of xs (0 for an empty slice). xs is not modified.
+func Median(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	s := append([]float64(nil), xs...)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// MAD is the median absolute deviation around the median.
+func MAD(xs []float64) float64 {
+	m := Median(xs)
+	dev := make([]float64, len(xs))
+	for i, x := range xs {
+		dev[i] = math.Abs(x - m)
+	}
+	return Median(dev)
+}
+
+// RobustZ is the modified z-score 0.6745·(x−median)/MAD. With MAD = 0 the mean absolute
+// deviation is used instead; without any spread it returns 0.
+func RobustZ(x float64, xs []float64) float64 {
+	m := Median(xs)
+	if mad := MAD(xs); mad > 0 {
+		return 0.6745 * (x - m) / mad
+	}
+	sum := 0.0
+	for _, v := range xs {
+		sum += math.Abs(v - m)
+	}
+	if len(xs) == 0 || sum == 0 {
+		return 0
+	}
+	return (x - m) / (1.2533 * sum / float64(len(xs)))
+}
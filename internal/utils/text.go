@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// persianReplacer unifies Arabic/Persian letter variants and digits so that
+// "كتاب" / "کتاب" or "۱۲۳" / "123" compare equal.
+var persianReplacer = strings.NewReplacer(
+	"ي", "ی", "ى", "ی", "ئ", "ی",
+	"ك", "ک",
+	"ة", "ه", "ۀ", "ه",
+	"أ", "ا", "إ", "ا", "آ", "ا", "ٱ", "ا",
+	"ؤ", "و",
+	"‌", " ", // ZWNJ (نیم‌فاصله)
+	"‍", "",
+	"ـ", "", // tatweel
+	"۰", "0", "۱", "1", "۲", "2", "۳", "3", "۴", "4",
+	"۵", "5", "۶", "6", "۷", "7", "۸", "8", "۹", "9",
+	"٠", "0", "١", "1", "٢", "2", "٣", "3", "٤", "4",
+	"٥", "5", "٦", "6", "٧", "7", "٨", "8", "٩", "9",
+)
+
+// NormalizeDigits converts Persian/Arabic digits and separators to ASCII.
+func NormalizeDigits(s string) string {
+	return strings.NewReplacer(
+		"۰", "0", "۱", "1", "۲", "2", "۳", "3", "۴", "4",
+		"۵", "5", "۶", "6", "۷", "7", "۸", "8", "۹", "9",
+		"٠", "0", "١", "1", "٢", "2", "٣", "3", "٤", "4",
+		"٥", "5", "٦", "6", "٧", "7", "٨", "8", "٩", "9",
+		"٬", ",", "٫", ".",
+	).Replace(s)
+}
+
+// NormalizeText lower-cases, unifies Persian letters/digits, drops diacritics and
+// punctuation, and collapses whitespace. Used for every name/synonym comparison.
+func NormalizeText(s string) string {
+	s = persianReplacer.Replace(strings.ToLower(s))
+
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.Mn, r): // اعراب (fatha, kasra, ...)
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// Slug turns a label into a category/vendor key ("Fast Food" -> "fast-food").
+func Slug(s string) string {
+	return strings.ReplaceAll(NormalizeText(s), " ", "-")
+}